@@ -9,14 +9,26 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/chdwlch/spark-pool/internal/ark"
+	"github.com/chdwlch/spark-pool/internal/chain"
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/keys"
 	"github.com/chdwlch/spark-pool/internal/miner"
 	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/internal/watchtower"
+	"github.com/chdwlch/spark-pool/pkg/types"
 	"github.com/chdwlch/spark-pool/web"
 )
 
+// keystorePassphraseEnv names the environment variable holding the keystore passphrase
+const keystorePassphraseEnv = "KEYSTORE_PASSPHRASE"
+
+// bitcoindPasswordEnv names the environment variable holding the bitcoind RPC password
+const bitcoindPasswordEnv = "BITCOIND_RPC_PASSWORD"
+
 func main() {
 	// Parse command line flags
 	var (
@@ -24,6 +36,30 @@ func main() {
 		poolName     = flag.String("pool-name", "Ark Virtual Channels Demo Pool", "Mining pool name")
 		operatorAddr = flag.String("operator-addr", "bc1qdemooperatoraddress", "Pool operator address")
 		blockInterval = flag.Duration("block-interval", 30*time.Second, "Block reward interval for demo")
+		payoutScheme  = flag.String("payout-scheme", pool.SchemeHashRate, "Payout scheme (hashrate, pplns, pps, fpps, prop, solo)")
+		pplnsWindow   = flag.Float64("pplns-window", pool.DefaultPPLNSWindow, "PPLNS window size in units of share difficulty")
+		startHeight   = flag.Uint64("start-height", pool.DefaultStartHeight, "Simulated chain height the pool starts at")
+		txFees        = flag.Uint64("tx-fees", 0, "Average transaction fees per block in satoshis")
+		simulateFees  = flag.Bool("simulate-tx-fees", false, "Vary transaction fees randomly around --tx-fees")
+		payoutMin     = flag.Uint64("payout-threshold", 100000, "Default pending balance in sats before a miner is paid through its channel (0 pays every block)")
+		payoutEvery   = flag.Duration("payout-interval", 0, "Pay out all pending balances on this schedule, e.g. 1h or 24h (0 disables)")
+		capBlocks     = flag.Uint64("channel-capacity-blocks", pool.DefaultCapacityBlocks, "Blocks of expected miner earnings each channel is funded for")
+		lowWater      = flag.Uint64("channel-low-water-blocks", pool.DefaultLowWaterBlocks, "Top up a channel once it covers fewer blocks of expected earnings than this")
+		minCapacity   = flag.Uint64("channel-min-capacity", pool.DefaultMinCapacity, "Minimum channel capacity in sats")
+		operatorFee   = flag.Uint("operator-fee-bps", 0, "Operator fee in basis points taken from each miner's share")
+		donationAddr  = flag.String("donation-addr", "", "Address receiving part of the operator fee")
+		donationFee   = flag.Uint("donation-bps", 0, "Share of the operator fee donated, in basis points")
+		closeFeeRate  = flag.Uint64("close-fee-rate", 2, "Fee rate in sat/vB of cooperative channel settlements")
+		roundInterval = flag.Duration("round-interval", 10*time.Second, "Collect cooperative closes for this long before settling them in one round (0 settles each close at once)")
+		refreshMargin = flag.Uint64("vtxo-refresh-margin", channel.DefaultRefreshMargin, "Refresh channel VTXOs into a new round this many blocks before they expire")
+		treasuryFund  = flag.Uint64("treasury-funding", treasury.DefaultRegtestFunding, "Sats of on-chain operator funds in the regtest wallet channels are funded from")
+		bitcoindURL   = flag.String("bitcoind-url", "", "Follow the bitcoind JSON-RPC server at this URL for blocks instead of simulating them")
+		bitcoindUser  = flag.String("bitcoind-user", "", "bitcoind RPC user")
+		chainPoll     = flag.Duration("chain-poll-interval", chain.DefaultPollInterval, "How often to ask bitcoind for a new block")
+		keystorePath  = flag.String("keystore", "data/operator-keystore.json", "Encrypted operator keystore, created on first start")
+		insecureKeys  = flag.Bool("insecure-keystore", false, "Allow an empty "+keystorePassphraseEnv+", leaving the operator keystore unprotected (demos only)")
+		runTower      = flag.Bool("watchtower", true, "Run a watchtower for the simulated miners in this process")
+		towerURL      = flag.String("watchtower-url", "", "Register simulated miners' states with the watchtower at this URL instead")
 	)
 	flag.Parse()
 
@@ -34,21 +70,94 @@ func main() {
 		FullTimestamp: true,
 	})
 
-	// Generate server key (in real implementation, this would come from Ark server)
-	serverPrivKey, err := secp256k1.GeneratePrivateKey()
+	// Load the operator master key, encrypted at rest under the keystore passphrase
+	passphrase := os.Getenv(keystorePassphraseEnv)
+	if passphrase == "" {
+		if !*insecureKeys {
+			logger.Fatalf("%s is not set; set it, or pass --insecure-keystore to run with an unprotected keystore", keystorePassphraseEnv)
+		}
+		logger.Warnf("%s is not set, the operator keystore is protected by an empty passphrase", keystorePassphraseEnv)
+	}
+	keystore, err := keys.Open(*keystorePath, passphrase)
+	if err != nil {
+		logger.Fatalf("Failed to open operator keystore: %v", err)
+	}
+
+	// With a node to follow, the pool starts at its tip
+	var follower *chain.Follower
+	if *bitcoindURL != "" {
+		client := chain.NewClient(*bitcoindURL, *bitcoindUser, os.Getenv(bitcoindPasswordEnv))
+		follower = chain.NewFollower(client, *operatorAddr)
+		if err := follower.Sync(context.Background()); err != nil {
+			logger.Fatalf("Failed to reach bitcoind: %v", err)
+		}
+		*startHeight = follower.Tip().Height
+	}
+
+	// Run an in-process mock Ark server, keyed from the keystore so channel scripts
+	// survive a restart
+	serverPrivKey, err := keystore.ServerKey()
 	if err != nil {
-		logger.Fatalf("Failed to generate server key: %v", err)
+		logger.Fatalf("Failed to derive server key: %v", err)
+	}
+	asp := ark.NewMockASP(serverPrivKey)
+
+	// Fund the operator's regtest wallet, whose UTXOs are boarded into the Ark
+	// server as channels need them
+	wallet := treasury.NewRegtestWallet(*operatorAddr)
+	if _, err := wallet.Fund(*treasuryFund, *startHeight); err != nil {
+		logger.Fatalf("Failed to fund operator wallet: %v", err)
 	}
-	serverPubKey := serverPrivKey.PubKey()
 
 	// Create pool manager
-	poolManager := pool.NewManager(*poolName, *operatorAddr, serverPubKey)
+	poolManager, err := pool.NewManager(pool.Config{
+		PoolName:        *poolName,
+		OperatorAddress: *operatorAddr,
+		PayoutScheme:    *payoutScheme,
+		PPLNSWindow:     *pplnsWindow,
+		BlockInterval:   *blockInterval,
+		StartHeight:     *startHeight,
+		PayoutThreshold: *payoutMin,
+		CloseFeeRate:    *closeFeeRate,
+		RoundInterval:   *roundInterval,
+		Capacity: pool.CapacityConfig{
+			Blocks:         *capBlocks,
+			LowWaterBlocks: *lowWater,
+			MinCapacity:    *minCapacity,
+		},
+		FeeModel: pool.FeeModel{
+			MeanFees: *txFees,
+			Simulate: *simulateFees,
+		},
+
+		FeeBasisPoints:      uint32(*operatorFee),
+		DonationAddress:     *donationAddr,
+		DonationBasisPoints: uint32(*donationFee),
+		Logger:              logger,
+	}, keystore, asp, wallet)
+	if err != nil {
+		logger.Fatalf("Failed to create pool manager: %v", err)
+	}
+	asp.SetHeight(poolManager.BlockHeight())
+	poolManager.OnChainEvent(asp.HandleChainEvent)
 
 	// Create miner manager
 	minerManager := miner.NewManager(poolManager)
 
+	// Let a watchtower answer stale exits for simulated miners
+	switch {
+	case *towerURL != "":
+		minerManager.SetWatchtower(watchtower.NewClient(*towerURL))
+		logger.Infof("Simulated miners register with the watchtower at %s", *towerURL)
+	case *runTower:
+		minerManager.SetWatchtower(watchtower.New(poolManager))
+	}
+
 	// Create API server
 	api := web.NewAPI(poolManager, minerManager)
+	if follower != nil {
+		api.SetNode(follower)
+	}
 
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
@@ -61,8 +170,88 @@ func main() {
 	// Start WebSocket broadcaster
 	api.StartBroadcaster()
 
-	// Start block reward simulator
-	go startBlockRewardSimulator(context.Background(), poolManager, *blockInterval, logger)
+	// Pay out the blocks the pool finds on the node, or simulate them
+	if follower != nil {
+		follower.OnBlock = func(block *types.Block, found bool) {
+			if !found {
+				if err := poolManager.AdvanceChain(block.Height); err != nil {
+					logger.Errorf("Failed to advance to block %d: %v", block.Height, err)
+				}
+				return
+			}
+
+			logger.Infof("Pool found block %d (%s)", block.Height, block.Hash)
+			blockReward, err := poolManager.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{
+				BlockHeight: block.Height,
+				Reward:      chain.CoinbaseValue(block),
+			})
+			if err != nil {
+				// The block still moves the chain on, even when nothing can be paid
+				logger.Errorf("Failed to process block reward: %v", err)
+				if err := poolManager.AdvanceChain(block.Height); err != nil {
+					logger.Errorf("Failed to advance to block %d: %v", block.Height, err)
+				}
+				return
+			}
+			logBlockReward(blockReward, poolManager, logger)
+		}
+		follower.OnDisconnect = func(header *types.BlockHeader) {
+			orphaned, err := poolManager.DisconnectBlock(header.Height)
+			if err != nil {
+				logger.Errorf("Failed to disconnect block %d: %v", header.Height, err)
+				return
+			}
+			if orphaned != nil {
+				logger.Warnf("Pool block %d (%s) was orphaned by a reorganisation", header.Height, header.Hash)
+			}
+		}
+		follower.OnError = func(err error) {
+			logger.Errorf("Failed to follow bitcoind: %v", err)
+		}
+		go follower.Run(context.Background(), *chainPoll)
+	} else {
+		go startBlockRewardSimulator(context.Background(), poolManager, *blockInterval, logger)
+	}
+
+	// Start scheduled payouts
+	if *payoutEvery > 0 {
+		scheduler := pool.NewPayoutScheduler(poolManager, *payoutEvery)
+		scheduler.OnPayout = func(payouts map[string]uint64, err error) {
+			for minerID, amount := range payouts {
+				logger.Infof("Scheduled payout to miner %s: %d sats", minerID, amount)
+			}
+			if err != nil {
+				logger.Errorf("Scheduled payout failed: %v", err)
+			}
+		}
+		go scheduler.Run(context.Background())
+	}
+
+	// Start round scheduling
+	if *roundInterval > 0 {
+		scheduler := pool.NewRoundScheduler(poolManager, *roundInterval)
+		scheduler.OnRound = func(sealed *types.Round, err error) {
+			if sealed != nil {
+				logger.Infof("Sealed round %s: %d intents, %d sats in fees", sealed.ID, len(sealed.Intents), sealed.Fee)
+			}
+			if err != nil {
+				logger.Errorf("Round failed: %v", err)
+			}
+		}
+		go scheduler.Run(context.Background())
+	}
+
+	// Keep channel VTXOs from expiring
+	refresher := channel.NewRefresher(poolManager, *refreshMargin)
+	refresher.OnRefresh = func(height uint64, intents []*types.RoundIntent, err error) {
+		for _, intent := range intents {
+			logger.Infof("Queued VTXO refresh of channel %s at height %d", intent.ChannelID, height)
+		}
+		if err != nil {
+			logger.Errorf("VTXO refresh failed: %v", err)
+		}
+	}
+	refresher.Start()
 
 	// Create HTTP server
 	server := &http.Server{
@@ -75,7 +264,14 @@ func main() {
 		logger.Infof("Starting mining pool demo server on port %s", *port)
 		logger.Infof("Pool name: %s", *poolName)
 		logger.Infof("Operator address: %s", *operatorAddr)
-		logger.Infof("Block interval: %v", *blockInterval)
+		logger.Infof("Operator keystore: %s", *keystorePath)
+		if *bitcoindURL != "" {
+			logger.Infof("Following bitcoind at %s from height %d", *bitcoindURL, *startHeight)
+		} else {
+			logger.Infof("Block interval: %v", *blockInterval)
+		}
+		logger.Infof("Payout scheme: %s", *payoutScheme)
+		logger.Infof("Operator fee: %d bps", *operatorFee)
 		logger.Infof("Dashboard available at: http://localhost:%s", *port)
 		
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -97,6 +293,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
+	poolManager.Close()
 
 	logger.Info("Server exited")
 }
@@ -106,33 +303,37 @@ func startBlockRewardSimulator(ctx context.Context, poolManager *pool.Manager, i
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	blockHeight := uint64(100000)
-
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			blockHeight++
-			
-			logger.Infof("Processing block reward for height %d", blockHeight)
+			// The pool manager owns the chain height, so let it pick the next block
+			height := poolManager.BlockHeight() + 1
+			logger.Infof("Processing block reward for height %d", height)
 			
-			blockReward, err := poolManager.ProcessBlockReward(ctx)
+			blockReward, err := poolManager.ProcessBlockReward(ctx, types.ProcessBlockRequest{})
 			if err != nil {
+				// The block still moves the chain on, even when nothing can be paid
 				logger.Errorf("Failed to process block reward: %v", err)
+				if err := poolManager.AdvanceChain(height); err != nil {
+					logger.Errorf("Failed to advance to block %d: %v", height, err)
+				}
 				continue
 			}
-
-			// Log block reward details
-			logger.Infof("Block %d reward distributed:", blockHeight)
-			for minerID, amount := range blockReward.Distributions {
-				logger.Infof("  Miner %s: %d sats", minerID, amount)
-			}
-
-			// Log pool stats
-			stats := poolManager.GetPoolStats()
-			logger.Infof("Pool stats - Total miners: %d, Active: %d, Hash rate: %.2f TH/s, Total earned: %d sats",
-				stats.TotalMiners, stats.ActiveMiners, stats.TotalHashRate/1e12, stats.TotalEarned)
+			logBlockReward(blockReward, poolManager, logger)
 		}
 	}
-} 
\ No newline at end of file
+} 
+
+// logBlockReward logs how a block reward was distributed and the pool stats after it
+func logBlockReward(blockReward *types.BlockReward, poolManager *pool.Manager, logger *logrus.Logger) {
+	logger.Infof("Block %d reward distributed:", blockReward.BlockHeight)
+	for minerID, amount := range blockReward.Distributions {
+		logger.Infof("  Miner %s: %d sats", minerID, amount)
+	}
+
+	stats := poolManager.GetPoolStats()
+	logger.Infof("Pool stats - Total miners: %d, Active: %d, Hash rate: %.2f TH/s, Total earned: %d sats",
+		stats.TotalMiners, stats.ActiveMiners, stats.TotalHashRate/1e12, stats.TotalEarned)
+}
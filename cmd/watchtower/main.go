@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/watchtower"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	// Parse command line flags
+	var (
+		port    = flag.String("port", "8090", "Server port")
+		poolURL = flag.String("pool-url", "http://localhost:8080", "Pool operator API to watch")
+	)
+	flag.Parse()
+
+	// Setup logging
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	// Follow the pool's chain and answer stale exits through its API
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	poolClient := watchtower.NewPoolClient(*poolURL)
+	tower := watchtower.New(poolClient)
+	go poolClient.Run(ctx, func(err error) {
+		logger.Warnf("Watching pool: %v", err)
+	})
+
+	// Setup Gin router
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Logger(), gin.Recovery())
+	tower.SetupRoutes(router)
+
+	// Create HTTP server
+	server := &http.Server{
+		Addr:    ":" + *port,
+		Handler: router,
+	}
+
+	// Start server in goroutine
+	go func() {
+		logger.Infof("Starting watchtower on port %s", *port)
+		logger.Infof("Watching pool at %s", *poolURL)
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down watchtower...")
+	stop()
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	logger.Info("Watchtower exited")
+}
@@ -0,0 +1,398 @@
+package ark
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// DefaultVTXOLifetime is how many blocks the mock's batches live, about a week
+const DefaultVTXOLifetime = 1008
+
+// Round statuses
+const (
+	RoundSigning   = "signing"
+	RoundFinalized = "finalized"
+	RoundFailed    = "failed"
+)
+
+// ErrVTXOSpent is returned when an intent spends a VTXO that is already spent or
+// registered in another intent
+var ErrVTXOSpent = errors.New("VTXO already spent")
+
+// ErrUnknownVTXO is returned when an intent spends a VTXO the mock never issued
+var ErrUnknownVTXO = errors.New("unknown VTXO")
+
+// mockTag is the BIP-340 tag the mock derives its round IDs and transaction IDs
+// under
+const mockTag = "SparkPool/MockASP"
+
+// MockASP is an in-memory Ark service provider. It batches registered intents into
+// rounds, issues a VTXO for every output once the spent VTXOs are forfeited, and
+// co-signs settlements with its key. IDs follow a counter and heights follow
+// SetHeight, so tests can drive it deterministically.
+type MockASP struct {
+	key      *secp256k1.PrivateKey
+	lifetime uint64
+	height   uint64
+	counter  uint64
+	intents  map[string]*types.ArkIntent
+	rounds   map[string]*types.ArkRound
+	pending  map[string]string
+	spent    map[string]bool
+	vtxos    map[string][]*types.VTXO
+	issued   map[string]*types.VTXO
+	boarded  map[string]bool
+
+	// addresses lists the output addresses of each round waiting for forfeits, in
+	// VTXO order
+	addresses map[string][]string
+
+	mu sync.Mutex
+}
+
+// NewMockASP creates a mock Ark service provider signing with key, whose VTXOs
+// expire DefaultVTXOLifetime blocks after their round
+func NewMockASP(key *secp256k1.PrivateKey) *MockASP {
+	return &MockASP{
+		key:       key,
+		lifetime:  DefaultVTXOLifetime,
+		intents:   make(map[string]*types.ArkIntent),
+		rounds:    make(map[string]*types.ArkRound),
+		pending:   make(map[string]string),
+		spent:     make(map[string]bool),
+		vtxos:     make(map[string][]*types.VTXO),
+		issued:    make(map[string]*types.VTXO),
+		boarded:   make(map[string]bool),
+		addresses: make(map[string][]string),
+	}
+}
+
+// SetHeight moves the mock's chain tip. It never moves backwards.
+func (m *MockASP) SetHeight(height uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if height > m.height {
+		m.height = height
+	}
+}
+
+// HandleChainEvent follows a pool's simulated chain
+func (m *MockASP) HandleChainEvent(event *types.ChainEvent) {
+	m.SetHeight(event.Height)
+}
+
+// GetInfo returns the mock's public key and VTXO lifetime
+func (m *MockASP) GetInfo(ctx context.Context) (*types.ArkInfo, error) {
+	return &types.ArkInfo{
+		PubKey:       hex.EncodeToString(schnorr.SerializePubKey(m.key.PubKey())),
+		VTXOLifetime: m.lifetime,
+	}, nil
+}
+
+// RegisterIntent queues an intent for the next round. Its inputs must be VTXOs the
+// mock issued that are not spent or registered in another intent, and together
+// they must cover its outputs; anything left over pays the round's fee.
+func (m *MockASP) RegisterIntent(ctx context.Context, intent *types.ArkIntent) (string, error) {
+	if len(intent.Outputs) == 0 {
+		return "", fmt.Errorf("intent has no outputs")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inputs := uint64(0)
+	for _, input := range intent.Inputs {
+		vtxo, exists := m.issued[input]
+		if !exists {
+			return "", fmt.Errorf("%w: %s", ErrUnknownVTXO, input)
+		}
+		if m.spent[input] {
+			return "", fmt.Errorf("%w: %s", ErrVTXOSpent, input)
+		}
+		if other, registered := m.pending[input]; registered {
+			return "", fmt.Errorf("%w: %s is registered in intent %s", ErrVTXOSpent, input, other)
+		}
+		inputs += vtxo.Amount
+	}
+	outputs := uint64(0)
+	for _, output := range intent.Outputs {
+		outputs += output.Amount
+	}
+	if outputs > inputs {
+		return "", fmt.Errorf("intent spends %d sats into %d sats of outputs", inputs, outputs)
+	}
+
+	registered := &types.ArkIntent{
+		ID:      m.nextID("intent"),
+		Inputs:  intent.Inputs,
+		Outputs: intent.Outputs,
+	}
+	for _, input := range registered.Inputs {
+		m.pending[input] = registered.ID
+	}
+	m.intents[registered.ID] = registered
+
+	return registered.ID, nil
+}
+
+// DeleteIntent withdraws a registered intent that has not joined a round, freeing
+// its inputs
+func (m *MockASP) DeleteIntent(ctx context.Context, intentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	intent, exists := m.intents[intentID]
+	if !exists {
+		return fmt.Errorf("intent %s not found", intentID)
+	}
+	for _, input := range intent.Inputs {
+		delete(m.pending, input)
+	}
+	delete(m.intents, intentID)
+
+	return nil
+}
+
+// Board settles on-chain UTXOs into a single VTXO held at address, in a round of
+// its own. A UTXO can only be boarded once.
+func (m *MockASP) Board(ctx context.Context, utxos []*types.UTXO, address string) (*types.VTXO, error) {
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("boarding has no inputs")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	amount := uint64(0)
+	for _, utxo := range utxos {
+		if m.boarded[utxo.Outpoint] {
+			return nil, fmt.Errorf("UTXO %s is already boarded", utxo.Outpoint)
+		}
+		amount += utxo.Amount
+	}
+
+	round := &types.ArkRound{
+		ID:             m.nextID("round"),
+		CommitmentTxID: m.nextID("commitment"),
+		Height:         m.height,
+		Intents:        make([]string, 0),
+		Inputs:         make([]string, 0, len(utxos)),
+		Status:         RoundFinalized,
+		CreatedAt:      time.Now(),
+	}
+	vtxo := &types.VTXO{
+		Outpoint:      round.CommitmentTxID + ":0",
+		Amount:        amount,
+		BatchID:       round.ID,
+		CreatedHeight: m.height,
+		ExpiryHeight:  m.height + m.lifetime,
+		CreatedAt:     round.CreatedAt,
+	}
+	for _, utxo := range utxos {
+		m.boarded[utxo.Outpoint] = true
+		round.Inputs = append(round.Inputs, utxo.Outpoint)
+	}
+	round.VTXOs = []*types.VTXO{vtxo}
+	m.rounds[round.ID] = round
+	m.vtxos[address] = append(m.vtxos[address], vtxo)
+	m.issued[vtxo.Outpoint] = vtxo
+
+	return vtxo, nil
+}
+
+// JoinRound batches registered intents into a new round, which waits for the
+// forfeits of every input it spends
+func (m *MockASP) JoinRound(ctx context.Context, intentIDs []string) (*types.ArkRound, error) {
+	if len(intentIDs) == 0 {
+		return nil, fmt.Errorf("round has no intents")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	round := &types.ArkRound{
+		ID:             m.nextID("round"),
+		CommitmentTxID: m.nextID("commitment"),
+		Height:         m.height,
+		Intents:        intentIDs,
+		Inputs:         make([]string, 0),
+		VTXOs:          make([]*types.VTXO, 0),
+		Status:         RoundSigning,
+		CreatedAt:      time.Now(),
+	}
+	addresses := make([]string, 0)
+	for _, intentID := range intentIDs {
+		if _, exists := m.intents[intentID]; !exists {
+			return nil, fmt.Errorf("intent %s not found", intentID)
+		}
+	}
+	for _, intentID := range intentIDs {
+		intent := m.intents[intentID]
+		delete(m.intents, intentID)
+
+		round.Inputs = append(round.Inputs, intent.Inputs...)
+		for _, output := range intent.Outputs {
+			round.VTXOs = append(round.VTXOs, &types.VTXO{
+				Outpoint:      fmt.Sprintf("%s:%d", round.CommitmentTxID, len(round.VTXOs)),
+				Amount:        output.Amount,
+				BatchID:       round.ID,
+				CreatedHeight: m.height,
+				ExpiryHeight:  m.height + m.lifetime,
+				CreatedAt:     round.CreatedAt,
+			})
+			addresses = append(addresses, output.Address)
+		}
+	}
+	m.rounds[round.ID] = round
+	m.addresses[round.ID] = addresses
+
+	return round, nil
+}
+
+// SubmitForfeits finalizes a round once every input it spends is forfeited with a
+// valid signature. The inputs are spent and the round's VTXOs issued to their
+// outputs' addresses. A round with a missing or invalid forfeit fails and its
+// inputs are freed.
+func (m *MockASP) SubmitForfeits(ctx context.Context, roundID string, forfeits []*types.ArkForfeit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	round, exists := m.rounds[roundID]
+	if !exists {
+		return fmt.Errorf("round %s not found", roundID)
+	}
+	if round.Status != RoundSigning {
+		return fmt.Errorf("round %s is %s", roundID, round.Status)
+	}
+
+	if err := checkForfeits(round, forfeits); err != nil {
+		m.failRound(round)
+		return err
+	}
+
+	for _, input := range round.Inputs {
+		delete(m.pending, input)
+		m.spent[input] = true
+	}
+	for i, vtxo := range round.VTXOs {
+		address := m.addresses[roundID][i]
+		m.vtxos[address] = append(m.vtxos[address], vtxo)
+		m.issued[vtxo.Outpoint] = vtxo
+	}
+	delete(m.addresses, roundID)
+	round.Status = RoundFinalized
+
+	return nil
+}
+
+// AbortRound fails a round still waiting for its forfeits, freeing its inputs
+func (m *MockASP) AbortRound(ctx context.Context, roundID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	round, exists := m.rounds[roundID]
+	if !exists {
+		return fmt.Errorf("round %s not found", roundID)
+	}
+	if round.Status != RoundSigning {
+		return fmt.Errorf("round %s is %s", roundID, round.Status)
+	}
+	m.failRound(round)
+	return nil
+}
+
+// failRound fails a round waiting for forfeits and frees its inputs
+func (m *MockASP) failRound(round *types.ArkRound) {
+	for _, input := range round.Inputs {
+		delete(m.pending, input)
+	}
+	delete(m.addresses, round.ID)
+	round.Status = RoundFailed
+}
+
+// GetVTXOs returns the unspent VTXOs the mock issued to address
+func (m *MockASP) GetVTXOs(ctx context.Context, address string) ([]*types.VTXO, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vtxos := make([]*types.VTXO, 0)
+	for _, vtxo := range m.vtxos[address] {
+		if !m.spent[vtxo.Outpoint] {
+			vtxos = append(vtxos, vtxo)
+		}
+	}
+	return vtxos, nil
+}
+
+// CosignSettlement signs a settlement digest with the mock's key
+func (m *MockASP) CosignSettlement(ctx context.Context, settlement *types.Settlement, digest [32]byte) ([]byte, error) {
+	sig, err := schnorr.Sign(m.key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign settlement as server: %w", err)
+	}
+	return sig, nil
+}
+
+// GetRound returns a round by ID
+func (m *MockASP) GetRound(roundID string) (*types.ArkRound, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	round, exists := m.rounds[roundID]
+	return round, exists
+}
+
+// nextID returns the next deterministic ID of a kind
+func (m *MockASP) nextID(kind string) string {
+	m.counter++
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], m.counter)
+	id := schnorr.TaggedHash(mockTag, []byte(kind), n[:])
+	return hex.EncodeToString(id[:])
+}
+
+// checkForfeits checks that every input of a round is forfeited with a valid
+// signature
+func checkForfeits(round *types.ArkRound, forfeits []*types.ArkForfeit) error {
+	forfeited := make(map[string]bool, len(forfeits))
+	for _, forfeit := range forfeits {
+		if err := verifyForfeit(forfeit, round.ID); err != nil {
+			return err
+		}
+		forfeited[forfeit.Outpoint] = true
+	}
+	for _, input := range round.Inputs {
+		if !forfeited[input] {
+			return fmt.Errorf("round %s is missing the forfeit of %s", round.ID, input)
+		}
+	}
+	return nil
+}
+
+// verifyForfeit checks a forfeit's signature over its outpoint and round
+func verifyForfeit(forfeit *types.ArkForfeit, roundID string) error {
+	pubKey, err := hex.DecodeString(forfeit.PubKey)
+	if err != nil {
+		return fmt.Errorf("invalid forfeit public key: %w", err)
+	}
+	sig, err := hex.DecodeString(forfeit.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid forfeit signature: %w", err)
+	}
+
+	digest := channel.ForfeitDigest(forfeit.Outpoint, roundID)
+	if err := schnorr.Verify(pubKey, digest[:], sig); err != nil {
+		return fmt.Errorf("invalid forfeit of %s: %w", forfeit.Outpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package ark
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func newTestKey(t *testing.T) *secp256k1.PrivateKey {
+	t.Helper()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+// forfeit signs the forfeit of an outpoint in a round
+func forfeit(t *testing.T, key *secp256k1.PrivateKey, outpoint, roundID string) *types.ArkForfeit {
+	t.Helper()
+
+	digest := channel.ForfeitDigest(outpoint, roundID)
+	sig, err := schnorr.Sign(key, digest[:])
+	if err != nil {
+		t.Fatalf("sign forfeit: %v", err)
+	}
+	return &types.ArkForfeit{
+		Outpoint:  outpoint,
+		PubKey:    hex.EncodeToString(schnorr.SerializePubKey(key.PubKey())),
+		Signature: hex.EncodeToString(sig),
+	}
+}
+
+// board boards a single UTXO to address
+func board(t *testing.T, m *MockASP, outpoint string, amount uint64, address string) *types.VTXO {
+	t.Helper()
+
+	vtxo, err := m.Board(context.Background(), []*types.UTXO{{Outpoint: outpoint, Amount: amount}}, address)
+	if err != nil {
+		t.Fatalf("Board: %v", err)
+	}
+	return vtxo
+}
+
+func TestMockRound(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockASP(newTestKey(t))
+	m.SetHeight(100)
+	owner := newTestKey(t)
+
+	funding := board(t, m, "utxo:0", 100000, "alice")
+	if funding.ExpiryHeight != 100+DefaultVTXOLifetime {
+		t.Errorf("boarded VTXO expires at %d", funding.ExpiryHeight)
+	}
+
+	intentID, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs: []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{
+			{Address: "alice", Amount: 60000},
+			{Address: "bob", Amount: 39000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterIntent: %v", err)
+	}
+
+	// An input can only be in one intent at a time
+	_, err = m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "carol", Amount: 1000}},
+	})
+	if !errors.Is(err, ErrVTXOSpent) {
+		t.Errorf("double registration: got %v, want ErrVTXOSpent", err)
+	}
+
+	m.SetHeight(101)
+	round, err := m.JoinRound(ctx, []string{intentID})
+	if err != nil {
+		t.Fatalf("JoinRound: %v", err)
+	}
+	if round.Status != RoundSigning || len(round.VTXOs) != 2 || round.Height != 101 {
+		t.Fatalf("round is %s with %d VTXOs at height %d", round.Status, len(round.VTXOs), round.Height)
+	}
+
+	// Outputs are only issued once the inputs are forfeited
+	if vtxos, _ := m.GetVTXOs(ctx, "bob"); len(vtxos) != 0 {
+		t.Errorf("bob holds %d VTXOs before the round finalized", len(vtxos))
+	}
+	if err := m.SubmitForfeits(ctx, round.ID, []*types.ArkForfeit{forfeit(t, owner, funding.Outpoint, round.ID)}); err != nil {
+		t.Fatalf("SubmitForfeits: %v", err)
+	}
+
+	finalized, _ := m.GetRound(round.ID)
+	if finalized.Status != RoundFinalized {
+		t.Errorf("round is %s, want %s", finalized.Status, RoundFinalized)
+	}
+	alice, _ := m.GetVTXOs(ctx, "alice")
+	bob, _ := m.GetVTXOs(ctx, "bob")
+	if len(alice) != 1 || alice[0].Amount != 60000 || alice[0].ExpiryHeight != 101+DefaultVTXOLifetime {
+		t.Errorf("alice holds %+v, want only the 60000 sat output", alice)
+	}
+	if len(bob) != 1 || bob[0].Amount != 39000 {
+		t.Errorf("bob holds %+v", bob)
+	}
+
+	// The forfeited VTXO is spent for good
+	_, err = m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "carol", Amount: 1000}},
+	})
+	if !errors.Is(err, ErrVTXOSpent) {
+		t.Errorf("spending a forfeited VTXO: got %v, want ErrVTXOSpent", err)
+	}
+}
+
+func TestMockRoundFailsWithoutForfeits(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockASP(newTestKey(t))
+	owner := newTestKey(t)
+	first := board(t, m, "utxo:0", 50000, "alice")
+	second := board(t, m, "utxo:1", 50000, "alice")
+
+	intentID, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{first.Outpoint, second.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 99000}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterIntent: %v", err)
+	}
+	round, err := m.JoinRound(ctx, []string{intentID})
+	if err != nil {
+		t.Fatalf("JoinRound: %v", err)
+	}
+
+	// One input left unforfeited fails the round and frees both inputs
+	if err := m.SubmitForfeits(ctx, round.ID, []*types.ArkForfeit{forfeit(t, owner, first.Outpoint, round.ID)}); err == nil {
+		t.Fatal("round finalized with a missing forfeit")
+	}
+	failed, _ := m.GetRound(round.ID)
+	if failed.Status != RoundFailed {
+		t.Errorf("round is %s, want %s", failed.Status, RoundFailed)
+	}
+	if vtxos, _ := m.GetVTXOs(ctx, "bob"); len(vtxos) != 0 {
+		t.Errorf("failed round issued %d VTXOs", len(vtxos))
+	}
+	if vtxos, _ := m.GetVTXOs(ctx, "alice"); len(vtxos) != 2 {
+		t.Errorf("alice holds %d VTXOs after the failed round, want 2", len(vtxos))
+	}
+	if _, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{first.Outpoint, second.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 99000}},
+	}); err != nil {
+		t.Errorf("inputs of a failed round are still locked: %v", err)
+	}
+}
+
+func TestMockRejectsForgedForfeits(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockASP(newTestKey(t))
+	funding := board(t, m, "utxo:0", 100000, "alice")
+
+	intentID, _ := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 99000}},
+	})
+	round, err := m.JoinRound(ctx, []string{intentID})
+	if err != nil {
+		t.Fatalf("JoinRound: %v", err)
+	}
+
+	// A forfeit signed for another round does not count
+	forged := forfeit(t, newTestKey(t), funding.Outpoint, "another-round")
+	if err := m.SubmitForfeits(ctx, round.ID, []*types.ArkForfeit{forged}); err == nil {
+		t.Error("round finalized with a forfeit signed for another round")
+	}
+}
+
+func TestMockBoardsAUTXOOnce(t *testing.T) {
+	m := NewMockASP(newTestKey(t))
+	board(t, m, "utxo:0", 1000, "alice")
+	if _, err := m.Board(context.Background(), []*types.UTXO{{Outpoint: "utxo:0", Amount: 1000}}, "alice"); err == nil {
+		t.Error("boarded the same UTXO twice")
+	}
+}
+
+func TestMockCosignsSettlements(t *testing.T) {
+	key := newTestKey(t)
+	m := NewMockASP(key)
+
+	info, err := m.GetInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	digest := schnorr.TaggedHash("SparkPool/Test", []byte("settlement"))
+	sig, err := m.CosignSettlement(context.Background(), &types.Settlement{}, digest)
+	if err != nil {
+		t.Fatalf("CosignSettlement: %v", err)
+	}
+	pubKey, _ := hex.DecodeString(info.PubKey)
+	if err := schnorr.Verify(pubKey, digest[:], sig); err != nil {
+		t.Errorf("co-signature does not verify under the advertised key: %v", err)
+	}
+}
+
+func TestMockAbortRoundFreesItsInputs(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockASP(newTestKey(t))
+	funding := board(t, m, "utxo:0", 50000, "alice")
+	intent := &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 49000}},
+	}
+
+	intentID, err := m.RegisterIntent(ctx, intent)
+	if err != nil {
+		t.Fatalf("RegisterIntent: %v", err)
+	}
+	round, err := m.JoinRound(ctx, []string{intentID})
+	if err != nil {
+		t.Fatalf("JoinRound: %v", err)
+	}
+	if err := m.AbortRound(ctx, round.ID); err != nil {
+		t.Fatalf("AbortRound: %v", err)
+	}
+	if err := m.AbortRound(ctx, round.ID); err == nil {
+		t.Error("aborted a failed round again")
+	}
+	if err := m.SubmitForfeits(ctx, round.ID, nil); err == nil {
+		t.Error("forfeits accepted for an aborted round")
+	}
+	if _, err := m.RegisterIntent(ctx, intent); err != nil {
+		t.Errorf("inputs of an aborted round are still locked: %v", err)
+	}
+}
+
+func TestMockRejectsUnknownAndOverspentInputs(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockASP(newTestKey(t))
+	funding := board(t, m, "utxo:0", 50000, "alice")
+
+	_, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{"forged:0"},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 1000}},
+	})
+	if !errors.Is(err, ErrUnknownVTXO) {
+		t.Errorf("intent spending a forged VTXO: got %v, want ErrUnknownVTXO", err)
+	}
+
+	if _, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 50001}},
+	}); err == nil {
+		t.Error("intent creating more than it spends was registered")
+	}
+
+	if _, err := m.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{funding.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bob", Amount: 50000}},
+	}); err != nil {
+		t.Errorf("intent spending exactly its inputs: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package chain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// headerSize is the size in bytes of a serialized block header
+const headerSize = 80
+
+// ErrBlockRejected is returned when a node refuses a submitted block
+var ErrBlockRejected = errors.New("block rejected")
+
+// ErrInvalidBlock is returned for a submitted block that cannot be decoded
+var ErrInvalidBlock = errors.New("invalid block")
+
+// Backend is a Bitcoin node the pool mines on: bitcoind over JSON-RPC, or a fake
+// node standing in for one
+type Backend interface {
+	// GetBlockTemplate returns a template for the next block
+	GetBlockTemplate(ctx context.Context) (*types.BlockTemplate, error)
+
+	// SubmitBlock submits a serialized block, hex encoded
+	SubmitBlock(ctx context.Context, blockHex string) error
+
+	// GetBestBlockHash returns the hash of the tip of the best chain
+	GetBestBlockHash(ctx context.Context) (string, error)
+
+	// GetBlock returns a block with its transactions decoded
+	GetBlock(ctx context.Context, hash string) (*types.Block, error)
+
+	// GetBlockHeader returns a block's header
+	GetBlockHeader(ctx context.Context, hash string) (*types.BlockHeader, error)
+}
+
+// BlockHash returns the hash of a serialized block or block header, in the byte
+// order bitcoind displays it
+func BlockHash(block []byte) (string, error) {
+	if len(block) < headerSize {
+		return "", fmt.Errorf("block is %d bytes, shorter than a header", len(block))
+	}
+	return displayHash(doubleSHA256(block[:headerSize])), nil
+}
+
+// CoinbaseValue returns what a block's coinbase transaction pays in sats
+func CoinbaseValue(block *types.Block) uint64 {
+	if len(block.Tx) == 0 {
+		return 0
+	}
+	total := uint64(0)
+	for _, output := range block.Tx[0].Vout {
+		total += uint64(math.Round(output.Value * 1e8))
+	}
+	return total
+}
+
+// PaysAddress reports whether a block's coinbase transaction pays address
+func PaysAddress(block *types.Block, address string) bool {
+	if len(block.Tx) == 0 || address == "" {
+		return false
+	}
+	for _, output := range block.Tx[0].Vout {
+		if output.ScriptPubKey.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleSHA256 returns SHA-256 applied twice to data
+func doubleSHA256(data []byte) [32]byte {
+	first := sha256.Sum256(data)
+	return sha256.Sum256(first[:])
+}
+
+// displayHash hex encodes a hash in reverse byte order, as bitcoind displays it
+func displayHash(hash [32]byte) string {
+	return hex.EncodeToString(reverse(hash[:]))
+}
+
+// reverse returns a copy of b in reverse order
+func reverse(b []byte) []byte {
+	reversed := make([]byte, len(b))
+	for i, v := range b {
+		reversed[len(b)-1-i] = v
+	}
+	return reversed
+}
@@ -0,0 +1,394 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+const (
+	// fakeBits is the compact difficulty target of every fake block
+	fakeBits = "207fffff"
+
+	// fakeSubsidy is the most a fake block's coinbase may pay in sats. The fake
+	// node has no halving schedule; the pool keeps the real one.
+	fakeSubsidy = 312500000
+)
+
+// FakeNode is an in-memory stand-in for bitcoind that serves the same JSON-RPC
+// calls, so a Client can be pointed at it in tests. Blocks are mined to an
+// address with Mine or submitted like to bitcoind; proof of work is not checked.
+type FakeNode struct {
+	blocks []*types.Block
+	mined  uint64
+	byHash map[string]*types.Block
+	mu     sync.RWMutex
+}
+
+// NewFakeNode creates a fake node whose chain starts with a block at height
+func NewFakeNode(height uint64) *FakeNode {
+	n := &FakeNode{
+		byHash: make(map[string]*types.Block),
+	}
+	n.appendBlock(height, "", &types.Transaction{
+		TxID: displayHash(doubleSHA256([]byte("genesis"))),
+		Vin:  []*types.TxInput{{Coinbase: "00"}},
+		Vout: []*types.TxOutput{},
+	})
+	return n
+}
+
+// Mine adds a block to the tip whose coinbase pays value to address
+func (n *FakeNode) Mine(address string, value uint64) *types.Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := n.blocks[len(n.blocks)-1]
+	height := tip.Height + 1
+
+	// The seed counts every block mined, so a block mined on a fork never has the
+	// hash of the one it replaces
+	n.mined++
+	var seed [16]byte
+	binary.BigEndian.PutUint64(seed[:8], height)
+	binary.BigEndian.PutUint64(seed[8:], n.mined)
+	coinbase := &types.Transaction{
+		TxID: displayHash(doubleSHA256(append(seed[:], address...))),
+		Vin:  []*types.TxInput{{Coinbase: hex.EncodeToString(seed[:])}},
+		Vout: []*types.TxOutput{{
+			Value:        float64(value) / 1e8,
+			ScriptPubKey: types.ScriptPubKey{Address: address},
+		}},
+	}
+	return n.appendBlock(height, tip.Hash, coinbase)
+}
+
+// Rewind drops the blocks above height from the best chain, so the next blocks
+// mined fork from the block at height
+func (n *FakeNode) Rewind(height uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for len(n.blocks) > 1 && n.blocks[len(n.blocks)-1].Height > height {
+		n.blocks = n.blocks[:len(n.blocks)-1]
+	}
+}
+
+// appendBlock adds a block with a coinbase transaction on top of prevHash
+func (n *FakeNode) appendBlock(height uint64, prevHash string, coinbase *types.Transaction) *types.Block {
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[0:4], 0x20000000)
+	if prev, err := hex.DecodeString(prevHash); err == nil && len(prev) == 32 {
+		copy(header[4:36], reverse(prev))
+	}
+	merkle, _ := hex.DecodeString(coinbase.TxID)
+	copy(header[36:68], reverse(merkle))
+	binary.LittleEndian.PutUint32(header[68:72], uint32(time.Now().Unix()))
+	binary.LittleEndian.PutUint32(header[76:80], uint32(height))
+
+	return n.addBlock(header, height, coinbase)
+}
+
+// addBlock records a block from its serialized header
+func (n *FakeNode) addBlock(header []byte, height uint64, coinbase *types.Transaction) *types.Block {
+	hash, _ := BlockHash(header)
+	block := &types.Block{
+		BlockHeader: types.BlockHeader{
+			Hash:       hash,
+			Height:     height,
+			Version:    int32(binary.LittleEndian.Uint32(header[0:4])),
+			MerkleRoot: displayHash([32]byte(header[36:68])),
+			Time:       int64(binary.LittleEndian.Uint32(header[68:72])),
+			Bits:       fakeBits,
+			Nonce:      binary.LittleEndian.Uint32(header[76:80]),
+		},
+		Tx: []*types.Transaction{coinbase},
+	}
+	if len(n.blocks) > 0 {
+		block.PreviousBlockHash = n.blocks[len(n.blocks)-1].Hash
+	}
+
+	n.blocks = append(n.blocks, block)
+	n.byHash[block.Hash] = block
+	return block
+}
+
+// ServeHTTP answers a JSON-RPC call like bitcoind
+func (n *FakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, rpcErr := n.handle(req.Method, req.Params)
+
+	status := http.StatusOK
+	if rpcErr != nil {
+		status = http.StatusInternalServerError
+		result = nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"result": result,
+		"error":  rpcErr,
+		"id":     req.ID,
+	})
+}
+
+// handle runs one JSON-RPC call
+func (n *FakeNode) handle(method string, params []json.RawMessage) (any, *RPCError) {
+	switch method {
+	case "getblocktemplate":
+		return n.template(), nil
+	case "submitblock":
+		var blockHex string
+		if err := decodeParam(params, 0, &blockHex); err != nil {
+			return nil, err
+		}
+		return n.submit(blockHex)
+	case "getbestblockhash":
+		n.mu.RLock()
+		defer n.mu.RUnlock()
+		return n.blocks[len(n.blocks)-1].Hash, nil
+	case "getblock", "getblockheader":
+		var hash string
+		if err := decodeParam(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		block, err := n.block(hash)
+		if err != nil {
+			return nil, err
+		}
+		if method == "getblockheader" {
+			return block.BlockHeader, nil
+		}
+		return block, nil
+	default:
+		return nil, &RPCError{Code: -32601, Message: "Method not found"}
+	}
+}
+
+// template returns a template for the block after the tip, without transactions
+func (n *FakeNode) template() *types.BlockTemplate {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	tip := n.blocks[len(n.blocks)-1]
+	return &types.BlockTemplate{
+		Version:           0x20000000,
+		PreviousBlockHash: tip.Hash,
+		Height:            tip.Height + 1,
+		CoinbaseValue:     fakeSubsidy,
+		Bits:              fakeBits,
+		Target:            "7fffff0000000000000000000000000000000000000000000000000000000000",
+		CurTime:           time.Now().Unix(),
+		Transactions:      make([]*types.TemplateTransaction, 0),
+	}
+}
+
+// submit adds a serialized block to the tip. Like bitcoind, a refused block is
+// answered with the reason rather than an error.
+func (n *FakeNode) submit(blockHex string) (any, *RPCError) {
+	raw, err := hex.DecodeString(blockHex)
+	if err != nil || len(raw) < headerSize {
+		return nil, &RPCError{Code: -22, Message: "Block decode failed"}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hash, _ := BlockHash(raw)
+	if _, exists := n.byHash[hash]; exists {
+		return "duplicate", nil
+	}
+	tip := n.blocks[len(n.blocks)-1]
+	if displayHash([32]byte(raw[4:36])) != tip.Hash {
+		return "bad-prevblk", nil
+	}
+
+	r := bytes.NewReader(raw[headerSize:])
+	count, err := readVarInt(r)
+	if err != nil || count == 0 {
+		return "bad-blk-length", nil
+	}
+	coinbase, err := readCoinbase(r)
+	if err != nil {
+		return "bad-cb-missing", nil
+	}
+	if CoinbaseValue(&types.Block{Tx: []*types.Transaction{coinbase}}) > fakeSubsidy {
+		return "bad-cb-amount", nil
+	}
+
+	n.addBlock(raw[:headerSize], tip.Height+1, coinbase)
+	return nil, nil
+}
+
+// block returns a block by hash, with its confirmations
+func (n *FakeNode) block(hash string) (*types.Block, *RPCError) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	block, exists := n.byHash[hash]
+	if !exists {
+		return nil, &RPCError{Code: -5, Message: "Block not found"}
+	}
+	found := *block
+	found.Confirmations = int64(n.blocks[len(n.blocks)-1].Height-block.Height) + 1
+	return &found, nil
+}
+
+// readCoinbase decodes a serialized coinbase transaction, keeping its input script
+// and outputs
+func readCoinbase(r *bytes.Reader) (*types.Transaction, error) {
+	var stripped bytes.Buffer
+	version := make([]byte, 4)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, err
+	}
+	stripped.Write(version)
+
+	// A segwit transaction has a marker and a flag before its inputs
+	segwit := false
+	if marker, err := r.ReadByte(); err != nil {
+		return nil, err
+	} else if marker == 0 {
+		if flag, err := r.ReadByte(); err != nil || flag != 1 {
+			return nil, fmt.Errorf("invalid segwit flag")
+		}
+		segwit = true
+	} else {
+		r.UnreadByte()
+	}
+
+	start := r.Size() - int64(r.Len())
+	inputs, err := readVarInt(r)
+	if err != nil || inputs != 1 {
+		return nil, fmt.Errorf("coinbase must have one input")
+	}
+	prevout := make([]byte, 36)
+	if _, err := io.ReadFull(r, prevout); err != nil {
+		return nil, err
+	}
+	script, err := readVarBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	tx := &types.Transaction{
+		Vin:  []*types.TxInput{{Coinbase: hex.EncodeToString(script)}},
+		Vout: make([]*types.TxOutput, 0),
+	}
+	outputs, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < outputs; i++ {
+		value := make([]byte, 8)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+		pkScript, err := readVarBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		tx.Vout = append(tx.Vout, &types.TxOutput{
+			Value:        float64(binary.LittleEndian.Uint64(value)) / 1e8,
+			N:            uint32(i),
+			ScriptPubKey: types.ScriptPubKey{Hex: hex.EncodeToString(pkScript)},
+		})
+	}
+	end := r.Size() - int64(r.Len())
+
+	body := make([]byte, end-start)
+	if _, err := r.ReadAt(body, start); err != nil {
+		return nil, err
+	}
+	stripped.Write(body)
+
+	if segwit {
+		witnesses, err := readVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < witnesses; i++ {
+			if _, err := readVarBytes(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	locktime := make([]byte, 4)
+	if _, err := io.ReadFull(r, locktime); err != nil {
+		return nil, err
+	}
+	stripped.Write(locktime)
+
+	// The transaction ID leaves out the witness
+	tx.TxID = displayHash(doubleSHA256(stripped.Bytes()))
+	return tx, nil
+}
+
+// readVarInt reads a Bitcoin compact size integer
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+// readVarBytes reads a byte string prefixed with its compact size length
+func readVarBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// decodeParam decodes the JSON-RPC parameter at index into v
+func decodeParam(params []json.RawMessage, index int, v any) *RPCError {
+	if index >= len(params) {
+		return &RPCError{Code: -1, Message: fmt.Sprintf("missing parameter %d", index)}
+	}
+	if err := json.Unmarshal(params[index], v); err != nil {
+		return &RPCError{Code: -8, Message: fmt.Sprintf("invalid parameter %d: %v", index, err)}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DefaultPollInterval is how often a follower asks its node for a new tip
+const DefaultPollInterval = 5 * time.Second
+
+// MaxReorgDepth is how many of the latest blocks a follower remembers to find
+// where a reorganisation forks from the chain it has seen
+const MaxReorgDepth = 100
+
+// Follower follows a node's best chain and reports every new block, telling the
+// blocks the pool found from the rest. A block is the pool's if the pool submitted
+// it or its coinbase pays the pool's address.
+type Follower struct {
+	backend   Backend
+	address   string
+	submitted map[string]bool
+	seen      []*types.BlockHeader
+	mu        sync.Mutex
+
+	// OnBlock is called for every new block, oldest first. It must not call back
+	// into the follower.
+	OnBlock func(block *types.Block, found bool)
+
+	// OnDisconnect is called for every block a reorganisation takes off the best
+	// chain, newest first, before the blocks replacing them are reported. It must
+	// not call back into the follower.
+	OnDisconnect func(header *types.BlockHeader)
+
+	// OnError is called when the node cannot be followed
+	OnError func(err error)
+}
+
+// NewFollower creates a follower of backend for a pool paid at address
+func NewFollower(backend Backend, address string) *Follower {
+	return &Follower{
+		backend:   backend,
+		address:   address,
+		submitted: make(map[string]bool),
+	}
+}
+
+// Tip returns the header of the latest block the follower has seen, or nil before
+// its first sync
+func (f *Follower) Tip() *types.BlockHeader {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tip()
+}
+
+// tip returns the latest block seen, or nil
+func (f *Follower) tip() *types.BlockHeader {
+	if len(f.seen) == 0 {
+		return nil
+	}
+	return f.seen[len(f.seen)-1]
+}
+
+// GetBlockTemplate returns a template from the node for the pool's next block
+func (f *Follower) GetBlockTemplate(ctx context.Context) (*types.BlockTemplate, error) {
+	return f.backend.GetBlockTemplate(ctx)
+}
+
+// SubmitBlock submits a block the pool found and remembers it as the pool's
+func (f *Follower) SubmitBlock(ctx context.Context, blockHex string) error {
+	raw, err := hex.DecodeString(blockHex)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+	}
+	hash, err := BlockHash(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+	}
+
+	if err := f.backend.SubmitBlock(ctx, blockHex); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.submitted[hash] = true
+	return nil
+}
+
+// Sync reports the blocks added to the best chain since the last sync. The first
+// sync only records the tip. When the node has reorganised, the blocks seen since
+// the fork are disconnected before the new branch is reported; a fork deeper than
+// MaxReorgDepth blocks is an error.
+func (f *Follower) Sync(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bestHash, err := f.backend.GetBestBlockHash(ctx)
+	if err != nil {
+		return err
+	}
+	tip := f.tip()
+	if tip != nil && bestHash == tip.Hash {
+		return nil
+	}
+	best, err := f.backend.GetBlockHeader(ctx, bestHash)
+	if err != nil {
+		return err
+	}
+	if tip == nil {
+		f.seen = append(f.seen, best)
+		return nil
+	}
+
+	// Walk back from the new tip to the height already seen, then further until
+	// the new chain meets a block seen before
+	headers := make([]*types.BlockHeader, 0)
+	header := best
+	fork := len(f.seen) - 1
+	for {
+		for fork >= 0 && f.seen[fork].Height > header.Height {
+			fork--
+		}
+		if fork >= 0 && f.seen[fork].Hash == header.Hash {
+			break
+		}
+		if fork < 0 {
+			return fmt.Errorf("chain reorganised deeper than %d blocks", MaxReorgDepth)
+		}
+		if header.PreviousBlockHash == "" {
+			return fmt.Errorf("block %s does not connect to the chain seen", header.Hash)
+		}
+		headers = append(headers, header)
+		if header, err = f.backend.GetBlockHeader(ctx, header.PreviousBlockHash); err != nil {
+			return err
+		}
+	}
+
+	for i := len(f.seen) - 1; i > fork; i-- {
+		disconnected := f.seen[i]
+		f.seen = f.seen[:i]
+		if f.OnDisconnect != nil {
+			f.OnDisconnect(disconnected)
+		}
+	}
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		block, err := f.backend.GetBlock(ctx, headers[i].Hash)
+		if err != nil {
+			return err
+		}
+		found := f.submitted[block.Hash] || PaysAddress(block, f.address)
+		delete(f.submitted, block.Hash)
+		f.seen = append(f.seen, headers[i])
+		if len(f.seen) > MaxReorgDepth {
+			f.seen = f.seen[len(f.seen)-MaxReorgDepth:]
+		}
+
+		if f.OnBlock != nil {
+			f.OnBlock(block, found)
+		}
+	}
+	return nil
+}
+
+// Run syncs with the node every interval until ctx is cancelled
+func (f *Follower) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Sync(ctx); err != nil && f.OnError != nil {
+			f.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
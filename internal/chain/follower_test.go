@@ -0,0 +1,96 @@
+package chain
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// followed records what a follower reports, in order
+type followed struct {
+	events []string
+}
+
+// follow creates a synced follower of a fake node that records its blocks
+func follow(t *testing.T, client *Client) (*Follower, *followed) {
+	t.Helper()
+
+	f := &followed{}
+	follower := NewFollower(client, poolAddress)
+	follower.OnBlock = func(block *types.Block, found bool) {
+		f.events = append(f.events, "connect "+block.Hash)
+	}
+	follower.OnDisconnect = func(header *types.BlockHeader) {
+		f.events = append(f.events, "disconnect "+header.Hash)
+	}
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	return follower, f
+}
+
+// sync syncs a follower and returns what it reported
+func (f *followed) sync(t *testing.T, follower *Follower) []string {
+	t.Helper()
+
+	f.events = nil
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	return f.events
+}
+
+func TestFollowerDisconnectsReorganisedBlocks(t *testing.T) {
+	node, client := newTestNode(t, 100)
+	follower, f := follow(t, client)
+
+	node.Mine(poolAddress, fakeSubsidy)
+	stale := node.Mine(poolAddress, fakeSubsidy)
+	f.sync(t, follower)
+
+	// A longer branch replaces the block at 102
+	node.Rewind(101)
+	first := node.Mine("bcrt1qother", fakeSubsidy)
+	second := node.Mine("bcrt1qother", fakeSubsidy)
+	want := []string{"disconnect " + stale.Hash, "connect " + first.Hash, "connect " + second.Hash}
+	if got := f.sync(t, follower); !reflect.DeepEqual(got, want) {
+		t.Errorf("longer branch: reported %q, want %q", got, want)
+	}
+
+	// A branch of the same height replaces the tip
+	node.Rewind(102)
+	replacement := node.Mine(poolAddress, fakeSubsidy)
+	want = []string{"disconnect " + second.Hash, "connect " + replacement.Hash}
+	if got := f.sync(t, follower); !reflect.DeepEqual(got, want) {
+		t.Errorf("same height branch: reported %q, want %q", got, want)
+	}
+	if tip := follower.Tip(); tip.Hash != replacement.Hash {
+		t.Errorf("follower tip %s, want %s", tip.Hash, replacement.Hash)
+	}
+}
+
+func TestFollowerRefusesAReorgDeeperThanItRemembers(t *testing.T) {
+	node, client := newTestNode(t, 100)
+	follower, f := follow(t, client)
+
+	for i := 0; i < MaxReorgDepth+10; i++ {
+		node.Mine(poolAddress, fakeSubsidy)
+	}
+	f.sync(t, follower)
+	tip := follower.Tip()
+
+	node.Rewind(105)
+	for i := 0; i < MaxReorgDepth+20; i++ {
+		node.Mine("bcrt1qother", fakeSubsidy)
+	}
+	err := follower.Sync(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "reorganised deeper") {
+		t.Fatalf("deep reorg: got %v", err)
+	}
+	if follower.Tip() != tip {
+		t.Errorf("follower moved to %s on a failed sync", follower.Tip().Hash)
+	}
+}
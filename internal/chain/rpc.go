@@ -0,0 +1,162 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DefaultRPCTimeout bounds every call to bitcoind
+const DefaultRPCTimeout = 30 * time.Second
+
+// RPCError is an error bitcoind returned for a call
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// rpcRequest is a JSON-RPC 1.0 request as bitcoind takes it
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC response from bitcoind
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     uint64          `json:"id"`
+}
+
+// Client talks to bitcoind over JSON-RPC
+type Client struct {
+	url      string
+	user     string
+	password string
+	http     *http.Client
+	nextID   uint64
+	mu       sync.Mutex
+}
+
+// NewClient creates a client for the bitcoind RPC server at url, authenticating
+// with user and password
+func NewClient(url, user, password string) *Client {
+	return &Client{
+		url:      url,
+		user:     user,
+		password: password,
+		http:     &http.Client{Timeout: DefaultRPCTimeout},
+	}
+}
+
+// GetBlockTemplate returns a template for the next block, with segwit rules
+func (c *Client) GetBlockTemplate(ctx context.Context) (*types.BlockTemplate, error) {
+	var template types.BlockTemplate
+	params := map[string]any{"rules": []string{"segwit"}}
+	if err := c.call(ctx, "getblocktemplate", []any{params}, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// SubmitBlock submits a serialized block, hex encoded. bitcoind answers null for
+// an accepted block and the reason otherwise.
+func (c *Client) SubmitBlock(ctx context.Context, blockHex string) error {
+	var reason *string
+	if err := c.call(ctx, "submitblock", []any{blockHex}, &reason); err != nil {
+		return err
+	}
+	if reason != nil {
+		return fmt.Errorf("%w: %s", ErrBlockRejected, *reason)
+	}
+	return nil
+}
+
+// GetBestBlockHash returns the hash of the tip of the best chain
+func (c *Client) GetBestBlockHash(ctx context.Context) (string, error) {
+	var hash string
+	if err := c.call(ctx, "getbestblockhash", []any{}, &hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// GetBlock returns a block with its transactions decoded
+func (c *Client) GetBlock(ctx context.Context, hash string) (*types.Block, error) {
+	var block types.Block
+	if err := c.call(ctx, "getblock", []any{hash, 2}, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetBlockHeader returns a block's header
+func (c *Client) GetBlockHeader(ctx context.Context, hash string) (*types.BlockHeader, error) {
+	var header types.BlockHeader
+	if err := c.call(ctx, "getblockheader", []any{hash, true}, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// call makes one JSON-RPC call and decodes its result into result
+func (c *Client) call(ctx context.Context, method string, params []any, result any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	body, err := json.Marshal(rpcRequest{JSONRPC: "1.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%s failed: bitcoind rejected the RPC credentials", method)
+	}
+
+	// bitcoind reports RPC errors with a non-200 status and a JSON body
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s failed with status %d: %w", method, resp.StatusCode, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s failed: %w", method, rpcResp.Error)
+	}
+	if rpcResp.ID != id {
+		return fmt.Errorf("%s answered request %d instead of %d", method, rpcResp.ID, id)
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
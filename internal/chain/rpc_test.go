@@ -0,0 +1,176 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// poolAddress is the address the test pool is paid at
+const poolAddress = "bcrt1qpool"
+
+// newTestNode serves a fake node over HTTP and returns a client for it
+func newTestNode(t *testing.T, height uint64) (*FakeNode, *Client) {
+	t.Helper()
+
+	node := NewFakeNode(height)
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+	return node, NewClient(server.URL, "user", "password")
+}
+
+// buildBlock serializes a block on a template with a coinbase paying value. The
+// header commits to nothing but the previous block and a nonce, since the fake
+// node does not check proof of work or the merkle root.
+func buildBlock(t *testing.T, template *types.BlockTemplate, value uint64, nonce uint32) string {
+	t.Helper()
+
+	var block bytes.Buffer
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(template.Version))
+	prev, err := hex.DecodeString(template.PreviousBlockHash)
+	if err != nil || len(prev) != 32 {
+		t.Fatalf("invalid previous block hash %q", template.PreviousBlockHash)
+	}
+	copy(header[4:36], reverse(prev))
+	binary.LittleEndian.PutUint32(header[68:72], uint32(template.CurTime))
+	binary.LittleEndian.PutUint32(header[76:80], nonce)
+	block.Write(header)
+
+	// One transaction: a coinbase with the height in its script and one output
+	block.WriteByte(1)
+	block.Write([]byte{1, 0, 0, 0})
+	block.WriteByte(1)
+	block.Write(make([]byte, 32))
+	block.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	script := binary.LittleEndian.AppendUint32([]byte{4}, uint32(template.Height))
+	block.WriteByte(byte(len(script)))
+	block.Write(script)
+	block.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	block.WriteByte(1)
+	block.Write(binary.LittleEndian.AppendUint64(nil, value))
+	pkScript := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	block.WriteByte(byte(len(pkScript)))
+	block.Write(pkScript)
+	block.Write([]byte{0, 0, 0, 0})
+
+	return hex.EncodeToString(block.Bytes())
+}
+
+func TestMineThroughTemplateAndSubmit(t *testing.T) {
+	ctx := context.Background()
+	node, client := newTestNode(t, 100)
+
+	follower := NewFollower(client, poolAddress)
+	type seen struct {
+		height uint64
+		found  bool
+		value  uint64
+	}
+	blocks := make([]seen, 0)
+	follower.OnBlock = func(block *types.Block, found bool) {
+		blocks = append(blocks, seen{block.Height, found, CoinbaseValue(block)})
+	}
+	if err := follower.Sync(ctx); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if tip := follower.Tip(); tip == nil || tip.Height != 100 {
+		t.Fatalf("follower starts at %+v, want height 100", tip)
+	}
+
+	template, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+	if template.Height != 101 || template.PreviousBlockHash != follower.Tip().Hash || template.CoinbaseValue != fakeSubsidy {
+		t.Fatalf("template %+v does not build on the tip", template)
+	}
+
+	// The pool submits its block, then another miner and the pool's own address
+	// each mine one
+	found := buildBlock(t, template, template.CoinbaseValue, 1)
+	if err := follower.SubmitBlock(ctx, found); err != nil {
+		t.Fatalf("SubmitBlock: %v", err)
+	}
+	node.Mine("bcrt1qother", fakeSubsidy)
+	node.Mine(poolAddress, fakeSubsidy+2500)
+
+	if err := follower.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	want := []seen{
+		{101, true, fakeSubsidy},
+		{102, false, fakeSubsidy},
+		{103, true, fakeSubsidy + 2500},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("follower reported %+v, want %+v", blocks, want)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("block %d reported as %+v, want %+v", i, blocks[i], want[i])
+		}
+	}
+
+	raw, _ := hex.DecodeString(found)
+	hash, _ := BlockHash(raw)
+	header, err := client.GetBlockHeader(ctx, hash)
+	if err != nil {
+		t.Fatalf("GetBlockHeader: %v", err)
+	}
+	if header.Height != 101 || header.Confirmations != 3 {
+		t.Errorf("submitted block at height %d with %d confirmations", header.Height, header.Confirmations)
+	}
+}
+
+func TestSubmitBlockRejections(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestNode(t, 100)
+
+	template, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+	block := buildBlock(t, template, template.CoinbaseValue, 1)
+	if err := client.SubmitBlock(ctx, block); err != nil {
+		t.Fatalf("SubmitBlock: %v", err)
+	}
+
+	next, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		block  string
+		reason string
+	}{
+		{"duplicate", block, "duplicate"},
+		{"stale template", buildBlock(t, template, template.CoinbaseValue, 2), "bad-prevblk"},
+		{"coinbase pays too much", buildBlock(t, next, next.CoinbaseValue+1, 1), "bad-cb-amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.SubmitBlock(ctx, tt.block)
+			if !errors.Is(err, ErrBlockRejected) {
+				t.Fatalf("got %v, want ErrBlockRejected", err)
+			}
+			if want := ErrBlockRejected.Error() + ": " + tt.reason; err.Error() != want {
+				t.Errorf("rejected with %q, want %q", err, want)
+			}
+		})
+	}
+
+	// Garbage is a decode error, not a rejection
+	if err := client.SubmitBlock(ctx, "00"); err == nil || errors.Is(err, ErrBlockRejected) {
+		t.Errorf("undecodable block: got %v", err)
+	}
+}
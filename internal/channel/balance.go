@@ -0,0 +1,84 @@
+package channel
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// GetChannelBalance returns the balance sheet of a channel
+func (cm *Manager) GetChannelBalance(channelID string) (*types.ChannelBalance, error) {
+	channel, exists := cm.GetChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel %s not found", channelID)
+	}
+	return BalanceOf(channel), nil
+}
+
+// GetChannelStats returns a channel's statistics, built from its balance sheet
+func (cm *Manager) GetChannelStats(channelID string) (*types.ChannelStats, error) {
+	channel, exists := cm.GetChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel %s not found", channelID)
+	}
+
+	balance := BalanceOf(channel)
+	return &types.ChannelStats{
+		ChannelID:       channel.ID,
+		MinerID:         channel.MinerID,
+		InitialFunding:  channel.InitialFunding,
+		CurrentBalance:  channel.CurrentBalance,
+		OperatorBalance: balance.OperatorBalance,
+		MinerBalance:    balance.MinerBalance,
+		InFlight:        balance.InFlight,
+		Status:          channel.Status,
+		PaymentCount:    len(channel.PaymentHistory),
+		CreatedAt:       channel.CreatedAt.Format(time.RFC3339),
+		LastUpdated:     channel.LastUpdated.Format(time.RFC3339),
+	}, nil
+}
+
+// BalanceOf splits a channel's capacity between the operator and the miner. The
+// sides follow the latest signed state until the channel settles, and the
+// settlement after. While an exit is pending on a state that pays the miner
+// something else, only the amount both agree on is counted on each side and the
+// difference is in flight.
+func BalanceOf(channel *types.Channel) *types.ChannelBalance {
+	capacity := channel.InitialFunding
+	latest := capacity - channel.CurrentBalance
+
+	balance := &types.ChannelBalance{
+		ChannelID:       channel.ID,
+		Status:          channel.Status,
+		Capacity:        capacity,
+		OperatorBalance: channel.CurrentBalance,
+		MinerBalance:    latest,
+	}
+	if n := len(channel.PaymentHistory); n > 0 {
+		balance.SequenceNum = channel.PaymentHistory[n-1].SequenceNum
+	}
+
+	switch {
+	case channel.Settlement != nil:
+		// The operator pays the settlement fee from its side
+		balance.MinerBalance = channel.Settlement.MinerAmount
+		balance.OperatorBalance = capacity - channel.Settlement.MinerAmount
+
+	case channel.Exit != nil:
+		claimed := stateBalance(channel.Exit.State)
+		if channel.Exit.Justice != nil {
+			claimed = channel.Exit.Justice.MinerAmount
+		}
+
+		low, high := latest, claimed
+		if low > high {
+			low, high = high, low
+		}
+		balance.MinerBalance = low
+		balance.OperatorBalance = capacity - high
+		balance.InFlight = high - low
+	}
+
+	return balance
+}
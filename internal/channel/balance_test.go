@@ -0,0 +1,73 @@
+package channel
+
+import (
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestBalanceOf(t *testing.T) {
+	paid := func() *types.Channel {
+		return &types.Channel{
+			ID:             "ch",
+			Status:         types.ChannelOpen,
+			InitialFunding: 100000,
+			CurrentBalance: 70000,
+			PaymentHistory: []*types.PaymentUpdate{{SequenceNum: 3, MinerBalance: 30000}},
+		}
+	}
+
+	settled := paid()
+	settled.Status = types.ChannelClosed
+	settled.Settlement = &types.Settlement{MinerAmount: 30000, Fee: 400}
+
+	// The miner exits on an older state that paid it more than its latest
+	stale := paid()
+	stale.Status = types.ChannelClosingUnilateral
+	stale.Exit = &types.ChannelExit{State: &types.PaymentUpdate{SequenceNum: 2, MinerBalance: 40000}}
+
+	// A dispute proved the exit stale; the justice settlement pays the latest state
+	disputed := paid()
+	disputed.Status = types.ChannelDisputed
+	disputed.Exit = &types.ChannelExit{
+		State:   &types.PaymentUpdate{SequenceNum: 2, MinerBalance: 40000},
+		Justice: &types.Settlement{MinerAmount: 30000},
+	}
+
+	// The operator exits before the miner was ever paid
+	unpaid := &types.Channel{ID: "ch", Status: types.ChannelClosingUnilateral, InitialFunding: 100000, CurrentBalance: 100000,
+		Exit: &types.ChannelExit{}}
+
+	tests := []struct {
+		name    string
+		channel *types.Channel
+		want    types.ChannelBalance
+	}{
+		{"open", paid(), types.ChannelBalance{Capacity: 100000, OperatorBalance: 70000, MinerBalance: 30000, SequenceNum: 3}},
+		{"settled", settled, types.ChannelBalance{Capacity: 100000, OperatorBalance: 70000, MinerBalance: 30000, SequenceNum: 3}},
+		{"stale exit", stale, types.ChannelBalance{Capacity: 100000, OperatorBalance: 60000, MinerBalance: 30000, InFlight: 10000, SequenceNum: 3}},
+		{"disputed exit", disputed, types.ChannelBalance{Capacity: 100000, OperatorBalance: 70000, MinerBalance: 30000, SequenceNum: 3}},
+		{"unpaid exit", unpaid, types.ChannelBalance{Capacity: 100000, OperatorBalance: 100000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := *BalanceOf(tt.channel)
+			tt.want.ChannelID = tt.channel.ID
+			tt.want.Status = tt.channel.Status
+			if got != tt.want {
+				t.Errorf("balance %+v, want %+v", got, tt.want)
+			}
+			if got.OperatorBalance+got.MinerBalance+got.InFlight != got.Capacity {
+				t.Errorf("sides add up to %d of %d", got.OperatorBalance+got.MinerBalance+got.InFlight, got.Capacity)
+			}
+		})
+	}
+}
+
+func TestGetChannelBalanceOfAnUnknownChannel(t *testing.T) {
+	cm, _ := newTestChannel(t, 1000)
+	if _, err := cm.GetChannelBalance("unknown"); err == nil {
+		t.Error("balance of an unknown channel")
+	}
+}
@@ -4,60 +4,122 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/chdwlch/spark-pool/pkg/types"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
-// Manager handles Virtual Channel operations for the mining pool
+// OperatorKeySource derives the pool operator's per-channel signing keys
+type OperatorKeySource interface {
+	OperatorKey(index uint32) (*secp256k1.PrivateKey, error)
+}
+
+// Manager handles Virtual Channel operations for the mining pool and keeps every
+// channel it has created. Callers serialize changes to any one channel.
 type Manager struct {
 	serverPubKey *secp256k1.PublicKey
+	server       Cosigner
+	operatorKeys OperatorKeySource
+	channels     map[string]*types.Channel
+	mu           sync.RWMutex
 }
 
-// NewManager creates a new channel manager
-func NewManager(serverPubKey *secp256k1.PublicKey) *Manager {
+// NewManager creates a new channel manager. The server cosigner signs settlements
+// on behalf of the Ark server holding serverPubKey.
+func NewManager(serverPubKey *secp256k1.PublicKey, server Cosigner, operatorKeys OperatorKeySource) *Manager {
 	return &Manager{
 		serverPubKey: serverPubKey,
+		server:       server,
+		operatorKeys: operatorKeys,
+		channels:     make(map[string]*types.Channel),
 	}
 }
 
-// CreateMiningPoolChannel creates a new Virtual Channel for a miner
+// CreateMiningPoolChannel creates a new Virtual Channel for a miner, locked to the
+// operator key at operatorKeyIndex. The channel waits for initialFunding to land
+// in its VTXO and opens with OpenChannel.
 func (cm *Manager) CreateMiningPoolChannel(
-	poolOperatorKey *secp256k1.PublicKey,
+	operatorKeyIndex uint32,
 	minerKey *secp256k1.PublicKey,
 	initialFunding uint64,
 ) (*types.Channel, error) {
+	poolOperatorKey, err := cm.operatorKeys.OperatorKey(operatorKeyIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive operator key %d: %w", operatorKeyIndex, err)
+	}
+
+	scriptTree, address, err := BuildScriptTree(poolOperatorKey.PubKey(), minerKey, cm.serverPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build channel script tree: %w", err)
+	}
+
 	channel := &types.Channel{
-		ID:              generateChannelID(),
-		PoolOperatorKey: poolOperatorKey,
-		MinerKey:        minerKey,
-		InitialFunding:  initialFunding,
-		CurrentBalance:  initialFunding,
-		Status:          "active",
-		CreatedAt:       time.Now(),
-		LastUpdated:     time.Now(),
-		PaymentHistory:  make([]*types.PaymentUpdate, 0),
+		ID:               generateChannelID(),
+		PoolOperatorKey:  poolOperatorKey.PubKey(),
+		OperatorKeyIndex: operatorKeyIndex,
+		MinerKey:         minerKey,
+		InitialFunding:   initialFunding,
+		CurrentBalance:   initialFunding,
+		Status:           types.ChannelPendingFunding,
+		History:          make([]*types.ChannelTransition, 0),
+		Disputes:         make([]*types.DisputeEvent, 0),
+		CreatedAt:        time.Now(),
+		LastUpdated:      time.Now(),
+		PaymentHistory:   make([]*types.PaymentUpdate, 0),
+		TopUps:           make([]*types.ChannelTopUp, 0),
+		ScriptTree:       scriptTree,
+		TaprootAddress:   address,
+		VTXOHistory:      make([]*types.VTXO, 0),
 	}
 
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.channels[channel.ID] = channel
+
 	return channel, nil
 }
 
+// OpenChannel opens a channel once the Ark server issued the VTXO funding it
+func (cm *Manager) OpenChannel(channel *types.Channel, vtxo *types.VTXO) error {
+	if vtxo.Amount != channel.InitialFunding {
+		return fmt.Errorf("VTXO of %d sats cannot fund channel %s with %d sats", vtxo.Amount, channel.ID, channel.InitialFunding)
+	}
+	if err := Transition(channel, types.ChannelOpen, "funded in batch "+vtxo.BatchID); err != nil {
+		return err
+	}
+	channel.VTXO = vtxo
+	channel.FundingOutpoint = vtxo.Outpoint
+	return nil
+}
+
+// DiscardChannel forgets a channel that was created but never funded
+func (cm *Manager) DiscardChannel(channelID string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	delete(cm.channels, channelID)
+}
+
 // CreatePaymentUpdate creates a new payment update for a channel
 func (cm *Manager) CreatePaymentUpdate(
 	channel *types.Channel,
 	amount uint64,
 	fromParty string,
 ) (*types.PaymentUpdate, error) {
-	if channel.Status != "active" {
-		return nil, fmt.Errorf("channel is not active")
+	if err := cm.ValidatePayment(channel, amount); err != nil {
+		return nil, err
 	}
 
-	if amount > channel.CurrentBalance {
-		return nil, fmt.Errorf("insufficient balance in channel")
+	// The operator key is derived again for every state, so channels stay
+	// signable after a restart
+	operatorKey, err := cm.operatorKeys.OperatorKey(channel.OperatorKeyIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive operator key for channel %s: %w", channel.ID, err)
 	}
 
-	// Create payment update
+	// Create payment update. The miner's side of the channel is everything the
+	// operator has funded minus what it still holds.
 	paymentUpdate := &types.PaymentUpdate{
 		ID:           generatePaymentID(),
 		ChannelID:    channel.ID,
@@ -65,8 +127,14 @@ func (cm *Manager) CreatePaymentUpdate(
 		FromParty:    fromParty,
 		ToParty:      "miner",
 		Timestamp:    time.Now(),
-		Status:       "pending",
-		SequenceNum:  uint64(len(channel.PaymentHistory) + 1),
+		Status:       "signed",
+		SequenceNum:  nextSequence(channel),
+		MinerBalance: channel.InitialFunding - channel.CurrentBalance + amount,
+	}
+
+	// Sign the new state as pool operator
+	if err := signState(paymentUpdate, operatorKey); err != nil {
+		return nil, err
 	}
 
 	// Update channel balance
@@ -77,30 +145,70 @@ func (cm *Manager) CreatePaymentUpdate(
 	return paymentUpdate, nil
 }
 
-// GetChannelBalance returns the current balance of a channel
-func (cm *Manager) GetChannelBalance(channelID string) (uint64, error) {
-	// In a real implementation, this would query the channel state
-	// For now, we'll return a placeholder
-	return 0, nil
-}
+// TopUpChannel adds operator funding to a channel so it can keep paying its miner.
+// The Ark server moved the channel's VTXO and the top-up into vtxo, which later
+// settlements spend.
+func (cm *Manager) TopUpChannel(channel *types.Channel, amount uint64, reason string, vtxo *types.VTXO) error {
+	if channel.Status != types.ChannelOpen {
+		return ErrChannelNotOpen
+	}
 
-// CloseChannel closes a Virtual Channel
-func (cm *Manager) CloseChannel(channel *types.Channel) error {
-	if channel.Status != "active" {
-		return fmt.Errorf("channel is not active")
+	if amount == 0 {
+		return fmt.Errorf("top-up amount must be positive")
+	}
+	if vtxo.Amount != channel.InitialFunding+amount {
+		return fmt.Errorf("VTXO of %d sats cannot hold channel %s after a %d sat top-up", vtxo.Amount, channel.ID, amount)
 	}
 
-	channel.Status = "closing"
+	channel.VTXOHistory = append(channel.VTXOHistory, channel.VTXO)
+	channel.VTXO = vtxo
+	channel.FundingOutpoint = vtxo.Outpoint
+	channel.InitialFunding += amount
+	channel.CurrentBalance += amount
 	channel.LastUpdated = time.Now()
+	channel.TopUps = append(channel.TopUps, &types.ChannelTopUp{
+		Amount:    amount,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
 
-	// In a real implementation, this would:
-	// 1. Create a closing transaction
-	// 2. Sign it with both parties
-	// 3. Broadcast to the network
+// ValidatePayment checks that a channel can accept a payment of amount without changing it
+func (cm *Manager) ValidatePayment(channel *types.Channel, amount uint64) error {
+	if channel.Status != types.ChannelOpen {
+		return ErrChannelNotOpen
+	}
+
+	if amount > channel.CurrentBalance {
+		return fmt.Errorf("insufficient balance in channel")
+	}
 
 	return nil
 }
 
+// GetChannel returns a channel by ID, whatever its state
+func (cm *Manager) GetChannel(channelID string) (*types.Channel, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	channel, exists := cm.channels[channelID]
+	return channel, exists
+}
+
+// GetChannels returns every channel the manager has created
+func (cm *Manager) GetChannels() map[string]*types.Channel {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	result := make(map[string]*types.Channel, len(cm.channels))
+	for id, channel := range cm.channels {
+		result[id] = channel
+	}
+	return result
+}
+
 // generateChannelID generates a unique channel ID
 func generateChannelID() string {
 	bytes := make([]byte, 16)
@@ -113,4 +221,4 @@ func generatePaymentID() string {
 	bytes := make([]byte, 8)
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package channel
+
+import (
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// staticKeys derives the same operator key for every channel
+type staticKeys struct {
+	key *secp256k1.PrivateKey
+}
+
+func (k staticKeys) OperatorKey(index uint32) (*secp256k1.PrivateKey, error) {
+	return k.key, nil
+}
+
+// newTestChannel opens a channel funded with funding sats
+func newTestChannel(t *testing.T, funding uint64) (*Manager, *types.Channel) {
+	t.Helper()
+
+	operatorKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate operator key: %v", err)
+	}
+	minerKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate miner key: %v", err)
+	}
+	serverKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate server key: %v", err)
+	}
+
+	cm := NewManager(serverKey.PubKey(), nil, staticKeys{operatorKey})
+	ch, err := cm.CreateMiningPoolChannel(0, minerKey.PubKey(), funding)
+	if err != nil {
+		t.Fatalf("CreateMiningPoolChannel: %v", err)
+	}
+	if err := cm.OpenChannel(ch, &types.VTXO{Outpoint: "funding:0", Amount: funding, ExpiryHeight: 1000}); err != nil {
+		t.Fatalf("OpenChannel: %v", err)
+	}
+	return cm, ch
+}
+
+func TestPaymentSequenceFollowsTheLatestState(t *testing.T) {
+	cm, ch := newTestChannel(t, 100000)
+
+	first, err := cm.CreatePaymentUpdate(ch, 1000, "pool_operator")
+	if err != nil {
+		t.Fatalf("CreatePaymentUpdate: %v", err)
+	}
+	if first.SequenceNum != 1 {
+		t.Errorf("first state has sequence %d, want 1", first.SequenceNum)
+	}
+
+	// The history need not start at the first state; the next one follows the
+	// latest whatever the history's length
+	first.SequenceNum = 7
+	next, err := cm.CreatePaymentUpdate(ch, 1000, "pool_operator")
+	if err != nil {
+		t.Fatalf("CreatePaymentUpdate: %v", err)
+	}
+	if next.SequenceNum != 8 {
+		t.Errorf("state after sequence 7 has sequence %d, want 8", next.SequenceNum)
+	}
+	if next.MinerBalance != 2000 || ch.CurrentBalance != 98000 {
+		t.Errorf("miner holds %d, operator %d", next.MinerBalance, ch.CurrentBalance)
+	}
+}
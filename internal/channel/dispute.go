@@ -0,0 +1,136 @@
+package channel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// CheckClaim compares the state a party exited with against the latest signed
+// state and returns nil when the claim is current. A stale claim is fraud when it
+// pays the claimant more than the latest state; the difference is the penalty.
+func CheckClaim(channel *types.Channel, claimedBy string, claimed, latest *types.PaymentUpdate) *types.DisputeEvent {
+	if latest == nil || (claimed != nil && claimed.SequenceNum >= latest.SequenceNum) {
+		return nil
+	}
+
+	event := &types.DisputeEvent{
+		ID:                 generateDisputeID(),
+		ChannelID:          channel.ID,
+		MinerID:            channel.MinerID,
+		ClaimedBy:          claimedBy,
+		LatestSequence:     latest.SequenceNum,
+		LatestMinerBalance: latest.MinerBalance,
+		Timestamp:          time.Now(),
+	}
+	if claimed != nil {
+		event.ClaimedSequence = claimed.SequenceNum
+		event.ClaimedMinerBalance = claimed.MinerBalance
+	}
+
+	// The operator gains from an old state that owes the miner less, the miner
+	// from one that owes it more
+	switch claimedBy {
+	case PartyOperator:
+		if event.ClaimedMinerBalance < event.LatestMinerBalance {
+			event.Penalty = event.LatestMinerBalance - event.ClaimedMinerBalance
+		}
+	case PartyMiner:
+		if event.ClaimedMinerBalance > event.LatestMinerBalance {
+			event.Penalty = event.ClaimedMinerBalance - event.LatestMinerBalance
+		}
+	}
+	event.Fraud = event.Penalty > 0
+
+	return event
+}
+
+// ChallengeExit presents a later signed state during a channel's exit window. The
+// exit is overridden by a justice settlement paying out the later state, and a
+// claimant caught with a state in its own favour forfeits what it tried to take.
+// The dispute is recorded on the channel and returned.
+func (cm *Manager) ChallengeExit(
+	channel *types.Channel,
+	party string,
+	state *types.PaymentUpdate,
+	height uint64,
+	operatorAddress string,
+	feeRate uint64,
+) (*types.DisputeEvent, error) {
+	exit := channel.Exit
+	if exit == nil || (channel.Status != types.ChannelClosingUnilateral && channel.Status != types.ChannelDisputed) {
+		return nil, fmt.Errorf("channel %s is not in a unilateral exit", channel.ID)
+	}
+	if height >= exit.MaturityHeight {
+		return nil, fmt.Errorf("exit window of channel %s closed at height %d", channel.ID, exit.MaturityHeight)
+	}
+	if state == nil {
+		return nil, fmt.Errorf("a challenge needs a signed state")
+	}
+
+	if err := verifyChannelState(channel, state); err != nil {
+		return nil, err
+	}
+	event := CheckClaim(channel, exit.Party, exit.State, state)
+	if event == nil {
+		return nil, fmt.Errorf("state %d is not later than the exiting state %d", state.SequenceNum, exit.State.SequenceNum)
+	}
+	event.ReportedBy = party
+	event.Height = height
+
+	// The penalty moves to the other side, capped by what the claimant holds
+	minerAmount := state.MinerBalance
+	switch {
+	case event.Fraud && exit.Party == PartyOperator:
+		if event.Penalty > channel.InitialFunding-minerAmount {
+			event.Penalty = channel.InitialFunding - minerAmount
+		}
+		minerAmount += event.Penalty
+	case event.Fraud && exit.Party == PartyMiner:
+		if event.Penalty > minerAmount {
+			event.Penalty = minerAmount
+		}
+		minerAmount -= event.Penalty
+	}
+
+	justice, err := cm.buildSettlement(channel, exit.Leaf, state, minerAmount, operatorAddress, SettlementFee(exit.Leaf, feeRate))
+	if err != nil {
+		return nil, err
+	}
+	justice.Type = SettlementJustice
+
+	if channel.Status == types.ChannelClosingUnilateral {
+		reason := fmt.Sprintf("%s presented state %d at height %d", party, state.SequenceNum, height)
+		if event.Fraud {
+			reason = fmt.Sprintf("%s proved %s fraud with state %d at height %d", party, exit.Party, state.SequenceNum, height)
+		}
+		if err := Transition(channel, types.ChannelDisputed, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	exit.State = state
+	exit.Justice = justice
+	exit.Challenges = append(exit.Challenges, &types.ExitChallenge{
+		Party:        party,
+		SequenceNum:  state.SequenceNum,
+		MinerBalance: state.MinerBalance,
+		Height:       height,
+		Timestamp:    event.Timestamp,
+	})
+
+	event.Justice = justice
+	channel.Disputes = append(channel.Disputes, event)
+
+	return event, nil
+}
+
+// generateDisputeID generates a unique dispute ID
+func generateDisputeID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,126 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// ErrTimelockActive is matched by every TimelockError
+var ErrTimelockActive = errors.New("exit timelock has not expired")
+
+// TimelockError is returned when an exit is finalized before its CSV delay has passed
+type TimelockError struct {
+	ChannelID      string
+	Height         uint64
+	MaturityHeight uint64
+}
+
+// Error implements the error interface
+func (e *TimelockError) Error() string {
+	return fmt.Sprintf("channel %s exit matures at height %d, chain is at %d (%d blocks left)",
+		e.ChannelID, e.MaturityHeight, e.Height, e.MaturityHeight-e.Height)
+}
+
+// Is makes every TimelockError match ErrTimelockActive
+func (e *TimelockError) Is(target error) bool {
+	return target == ErrTimelockActive
+}
+
+// exitLeaves maps each party to the leaf it exits through on its own
+var exitLeaves = map[string]string{
+	PartyOperator: LeafOperatorExit,
+	PartyMiner:    LeafMinerExit,
+}
+
+// StartUnilateralExit broadcasts a channel's state on behalf of one party at the
+// given chain height. The exit settles on state (nil if the miner was never paid)
+// once the CSV delay of the party's exit leaf has passed.
+func (cm *Manager) StartUnilateralExit(channel *types.Channel, party string, state *types.PaymentUpdate, height uint64) error {
+	leafName, exists := exitLeaves[party]
+	if !exists {
+		return fmt.Errorf("unknown exit party %q", party)
+	}
+	leaf, err := findLeaf(channel, leafName)
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		if err := verifyChannelState(channel, state); err != nil {
+			return err
+		}
+	}
+
+	reason := fmt.Sprintf("%s exit broadcast at height %d", party, height)
+	if err := Transition(channel, types.ChannelClosingUnilateral, reason); err != nil {
+		return err
+	}
+
+	channel.Exit = &types.ChannelExit{
+		Party:           party,
+		Leaf:            leaf.Name,
+		State:           state,
+		BroadcastHeight: height,
+		MaturityHeight:  height + uint64(leaf.Locktime),
+		Challenges:      make([]*types.ExitChallenge, 0),
+	}
+
+	return nil
+}
+
+// FinalizeUnilateralExit settles a channel through the exiting party's leaf once
+// the chain has reached the exit's maturity height. The miner cosigner is only
+// needed when the miner is the one exiting.
+func (cm *Manager) FinalizeUnilateralExit(
+	channel *types.Channel,
+	operatorAddress string,
+	feeRate uint64,
+	height uint64,
+	miner Cosigner,
+) (*types.Settlement, error) {
+	exit := channel.Exit
+	if exit == nil || (channel.Status != types.ChannelClosingUnilateral && channel.Status != types.ChannelDisputed) {
+		return nil, fmt.Errorf("channel %s is not in a unilateral exit", channel.ID)
+	}
+	if height < exit.MaturityHeight {
+		return nil, &TimelockError{
+			ChannelID:      channel.ID,
+			Height:         height,
+			MaturityHeight: exit.MaturityHeight,
+		}
+	}
+
+	// A dispute has already prepared the settlement overriding the exit
+	settlement := exit.Justice
+	if settlement == nil {
+		var err error
+		fee := SettlementFee(exit.Leaf, feeRate)
+		settlement, err = cm.buildSettlement(channel, exit.Leaf, exit.State, stateBalance(exit.State), operatorAddress, fee)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := cm.signSettlement(channel, settlement, map[string]Cosigner{PartyMiner: miner}); err != nil {
+		return nil, err
+	}
+
+	channel.Settlement = settlement
+	reason := fmt.Sprintf("%s exit matured at height %d", exit.Party, height)
+	if err := Transition(channel, types.ChannelClosed, reason); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// verifyChannelState checks that a state belongs to a channel and is signed by its
+// operator key
+func verifyChannelState(channel *types.Channel, state *types.PaymentUpdate) error {
+	if state.ChannelID != channel.ID {
+		return fmt.Errorf("state is for channel %s, not %s", state.ChannelID, channel.ID)
+	}
+	return VerifyPaymentUpdate(state, schnorr.SerializePubKey(channel.PoolOperatorKey))
+}
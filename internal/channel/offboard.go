@@ -0,0 +1,86 @@
+package channel
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DustLimit is the smallest on-chain output in sats an off-boarding may create
+const DustLimit = 330
+
+// PartyOnchain receives the funds a state moves out of the channel
+const PartyOnchain = "onchain"
+
+// PrepareOffboard checks that amount can be off-boarded from the miner's side of an
+// open channel, the miner paying fee from it, and returns the on-chain amount. The
+// channel is left unchanged until CompleteOffboard.
+func (cm *Manager) PrepareOffboard(channel *types.Channel, amount, fee uint64) (uint64, error) {
+	if channel.Status != types.ChannelOpen {
+		return 0, ErrChannelNotOpen
+	}
+	if channel.VTXO == nil {
+		return 0, fmt.Errorf("channel %s has no VTXO", channel.ID)
+	}
+
+	minerBalance := channel.InitialFunding - channel.CurrentBalance
+	if amount > minerBalance {
+		return 0, fmt.Errorf("off-boarding %d sats exceeds the miner's %d sat channel balance", amount, minerBalance)
+	}
+	if amount < fee+DustLimit {
+		return 0, fmt.Errorf("off-boarding %d sats leaves less than %d sats after a %d sat fee", amount, DustLimit, fee)
+	}
+	if n := len(channel.PaymentHistory); n > 0 {
+		if channel.PaymentHistory[n-1].MinerBalance != minerBalance {
+			return 0, fmt.Errorf("channel %s balance does not match its latest signed state", channel.ID)
+		}
+	}
+
+	return amount - fee, nil
+}
+
+// CompleteOffboard moves amount off the miner's side of a channel and into the VTXO
+// the Ark server issued for what remains. The channel shrinks by amount, and a new
+// state signed by the operator records the miner's smaller side.
+func (cm *Manager) CompleteOffboard(channel *types.Channel, amount uint64, vtxo *types.VTXO) (*types.PaymentUpdate, error) {
+	if channel.Status != types.ChannelOpen {
+		return nil, ErrChannelNotOpen
+	}
+	minerBalance := channel.InitialFunding - channel.CurrentBalance
+	if amount > minerBalance {
+		return nil, fmt.Errorf("off-boarding %d sats exceeds the miner's %d sat channel balance", amount, minerBalance)
+	}
+	if vtxo.Amount != channel.InitialFunding-amount {
+		return nil, fmt.Errorf("VTXO of %d sats cannot hold channel %s after off-boarding", vtxo.Amount, channel.ID)
+	}
+
+	operatorKey, err := cm.operatorKeys.OperatorKey(channel.OperatorKeyIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive operator key for channel %s: %w", channel.ID, err)
+	}
+
+	update := &types.PaymentUpdate{
+		ID:           generatePaymentID(),
+		ChannelID:    channel.ID,
+		Amount:       amount,
+		FromParty:    PartyMiner,
+		ToParty:      PartyOnchain,
+		Timestamp:    time.Now(),
+		Status:       "signed",
+		SequenceNum:  nextSequence(channel),
+		MinerBalance: minerBalance - amount,
+	}
+	if err := signState(update, operatorKey); err != nil {
+		return nil, err
+	}
+
+	channel.VTXOHistory = append(channel.VTXOHistory, channel.VTXO)
+	channel.VTXO = vtxo
+	channel.FundingOutpoint = vtxo.Outpoint
+	channel.InitialFunding -= amount
+	channel.LastUpdated = time.Now()
+	channel.PaymentHistory = append(channel.PaymentHistory, update)
+
+	return update, nil
+}
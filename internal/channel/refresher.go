@@ -0,0 +1,71 @@
+package channel
+
+import (
+	"sync"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DefaultRefreshMargin is how many blocks before its expiry a channel VTXO is
+// refreshed by default
+const DefaultRefreshMargin = 144
+
+// RefreshQueue is the part of the pool a Refresher follows the chain of and queues
+// refreshes in
+type RefreshQueue interface {
+	OnChainEvent(observer func(event *types.ChainEvent))
+	QueueRefreshes(deadline uint64) ([]*types.RoundIntent, error)
+}
+
+// Refresher keeps channel VTXOs from expiring. On every new block it queues a
+// refresh into the next round for each open channel whose VTXO expires within the
+// margin; the channel keeps its latest signed state in the new VTXO.
+type Refresher struct {
+	queue  RefreshQueue
+	margin uint64
+	height uint64
+	mu     sync.Mutex
+
+	// OnRefresh is called after every new block with the refreshes queued and any
+	// error
+	OnRefresh func(height uint64, intents []*types.RoundIntent, err error)
+}
+
+// NewRefresher creates a refresher queueing refreshes margin blocks before expiry.
+// A zero margin uses DefaultRefreshMargin.
+func NewRefresher(queue RefreshQueue, margin uint64) *Refresher {
+	if margin == 0 {
+		margin = DefaultRefreshMargin
+	}
+	return &Refresher{
+		queue:  queue,
+		margin: margin,
+	}
+}
+
+// Margin returns how many blocks before expiry VTXOs are refreshed
+func (r *Refresher) Margin() uint64 {
+	return r.margin
+}
+
+// Start follows the queue's chain until the process exits
+func (r *Refresher) Start() {
+	r.queue.OnChainEvent(r.HandleChainEvent)
+}
+
+// HandleChainEvent queues refreshes for the VTXOs expiring within the margin of a
+// new chain tip
+func (r *Refresher) HandleChainEvent(event *types.ChainEvent) {
+	r.mu.Lock()
+	if event.Height <= r.height {
+		r.mu.Unlock()
+		return
+	}
+	r.height = event.Height
+	r.mu.Unlock()
+
+	intents, err := r.queue.QueueRefreshes(event.Height + r.margin)
+	if r.OnRefresh != nil {
+		r.OnRefresh(event.Height, intents, err)
+	}
+}
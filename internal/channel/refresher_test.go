@@ -0,0 +1,52 @@
+package channel
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// recordingQueue records the deadlines a refresher queues refreshes for
+type recordingQueue struct {
+	deadlines []uint64
+	err       error
+}
+
+func (q *recordingQueue) OnChainEvent(observer func(event *types.ChainEvent)) {}
+
+func (q *recordingQueue) QueueRefreshes(deadline uint64) ([]*types.RoundIntent, error) {
+	q.deadlines = append(q.deadlines, deadline)
+	return nil, q.err
+}
+
+func TestRefresherQueuesOncePerNewBlock(t *testing.T) {
+	queue := &recordingQueue{}
+	refresher := NewRefresher(queue, 10)
+
+	for _, height := range []uint64{100, 101, 101, 99, 103} {
+		refresher.HandleChainEvent(&types.ChainEvent{Type: "block", Height: height})
+	}
+
+	if want := []uint64{110, 111, 113}; !reflect.DeepEqual(queue.deadlines, want) {
+		t.Errorf("queued refreshes for deadlines %v, want %v", queue.deadlines, want)
+	}
+}
+
+func TestRefresherReportsFailures(t *testing.T) {
+	queue := &recordingQueue{err: errors.New("round failed")}
+	refresher := NewRefresher(queue, 0)
+	if refresher.Margin() != DefaultRefreshMargin {
+		t.Errorf("margin %d, want %d", refresher.Margin(), DefaultRefreshMargin)
+	}
+
+	var reported error
+	refresher.OnRefresh = func(height uint64, intents []*types.RoundIntent, err error) {
+		reported = err
+	}
+	refresher.HandleChainEvent(&types.ChainEvent{Type: "block", Height: 1})
+	if !errors.Is(reported, queue.err) {
+		t.Errorf("reported %v, want %v", reported, queue.err)
+	}
+}
@@ -0,0 +1,414 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Settlement types
+const (
+	SettlementCooperative = "cooperative"
+	SettlementUnilateral  = "unilateral"
+	SettlementJustice     = "justice"
+)
+
+// Parties signing a settlement
+const (
+	PartyOperator = "operator"
+	PartyMiner    = "miner"
+	PartyServer   = "server"
+)
+
+// settlementTag is the BIP-340 tag settlement digests are computed under
+const settlementTag = "SparkPool/Settlement"
+
+// settlementVSize is the estimated virtual size in vbytes of a settlement spending
+// each leaf into two P2TR outputs
+var settlementVSize = map[string]uint64{
+	LeafCooperative:  230, // three signatures
+	LeafOperatorExit: 213, // two signatures behind a CSV delay
+	LeafMinerExit:    213,
+}
+
+// finalSequence is the input sequence of a settlement without a relative timelock
+const finalSequence = 0xffffffff
+
+// leafParties lists the parties whose signatures a leaf checks, in script order
+var leafParties = map[string][]string{
+	LeafCooperative:  {PartyOperator, PartyMiner, PartyServer},
+	LeafOperatorExit: {PartyOperator, PartyServer},
+	LeafMinerExit:    {PartyMiner, PartyServer},
+}
+
+// Cosigner returns a party's BIP-340 signature of a settlement digest
+type Cosigner func(settlement *types.Settlement, digest [32]byte) ([]byte, error)
+
+// BeginClose starts the cooperative close of a Virtual Channel and returns the
+// latest signed state, which the settlement will pay out (nil if the miner was never
+// paid). A channel in a unilateral exit window can still be closed cooperatively,
+// which settles it before the exit matures.
+func (cm *Manager) BeginClose(channel *types.Channel) (*types.PaymentUpdate, error) {
+	switch channel.Status {
+	case types.ChannelClosingUnilateral, types.ChannelDisputed:
+	default:
+		if err := Transition(channel, types.ChannelClosingCooperative, "close requested"); err != nil {
+			return nil, err
+		}
+	}
+
+	// The miner is owed its side of the latest signed state
+	var finalState *types.PaymentUpdate
+	if n := len(channel.PaymentHistory); n > 0 {
+		finalState = channel.PaymentHistory[n-1]
+		if finalState.MinerBalance != channel.InitialFunding-channel.CurrentBalance {
+			return nil, fmt.Errorf("channel %s balance does not match its latest signed state", channel.ID)
+		}
+	}
+
+	return finalState, nil
+}
+
+// AbortClose reopens a channel whose cooperative close left its round without
+// settling. A channel that was already in a unilateral exit window stays there.
+func (cm *Manager) AbortClose(channel *types.Channel, reason string) error {
+	if channel.Status != types.ChannelClosingCooperative {
+		return nil
+	}
+	return Transition(channel, types.ChannelOpen, reason)
+}
+
+// SignClose builds the cooperative settlement of a closing channel as part of a
+// round, paying the miner its side of finalState and the operator its change minus
+// fee, and collects the operator, miner and server signatures for the 3-of-3 leaf.
+// The channel is left unchanged until CompleteClose.
+func (cm *Manager) SignClose(
+	channel *types.Channel,
+	finalState *types.PaymentUpdate,
+	operatorAddress string,
+	fee uint64,
+	roundID string,
+	miner Cosigner,
+) (*types.Settlement, error) {
+	switch channel.Status {
+	case types.ChannelClosingCooperative, types.ChannelClosingUnilateral, types.ChannelDisputed:
+	default:
+		return nil, fmt.Errorf("channel %s is not closing", channel.ID)
+	}
+
+	settlement, err := cm.buildSettlement(channel, LeafCooperative, finalState, stateBalance(finalState), operatorAddress, fee)
+	if err != nil {
+		return nil, err
+	}
+	settlement.RoundID = roundID
+	if err := cm.signSettlement(channel, settlement, map[string]Cosigner{PartyMiner: miner}); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// CompleteClose settles a channel with its signed cooperative settlement once the
+// round it is part of is final
+func (cm *Manager) CompleteClose(channel *types.Channel, settlement *types.Settlement) error {
+	if err := Transition(channel, types.ChannelClosed, "cooperative close settled in round "+settlement.RoundID); err != nil {
+		return err
+	}
+	channel.Settlement = settlement
+	return nil
+}
+
+// SettlementVSize returns the estimated virtual size in vbytes of a settlement
+// spending a leaf in a transaction of its own
+func SettlementVSize(leafName string) uint64 {
+	return settlementVSize[leafName]
+}
+
+// SettlementFee returns the fee at feeRate sat/vB of a settlement spending a leaf in
+// a transaction of its own
+func SettlementFee(leafName string, feeRate uint64) uint64 {
+	return feeRate * settlementVSize[leafName]
+}
+
+// buildSettlement builds the unsigned settlement of a channel through a leaf,
+// paying minerAmount to the miner and the rest to the operator, minus fee
+func (cm *Manager) buildSettlement(
+	channel *types.Channel,
+	leafName string,
+	finalState *types.PaymentUpdate,
+	minerAmount uint64,
+	operatorAddress string,
+	fee uint64,
+) (*types.Settlement, error) {
+	leaf, err := findLeaf(channel, leafName)
+	if err != nil {
+		return nil, err
+	}
+
+	if minerAmount > channel.InitialFunding {
+		return nil, fmt.Errorf("state pays the miner %d sats from a %d sat channel", minerAmount, channel.InitialFunding)
+	}
+
+	// The operator pays the fee out of its change
+	change := channel.InitialFunding - minerAmount
+	if fee > change {
+		fee = change
+	}
+	operatorChange := change - fee
+
+	outputs := make([]*types.SettlementOutput, 0, 2)
+	if minerAmount > 0 {
+		outputs = append(outputs, &types.SettlementOutput{
+			Party:   PartyMiner,
+			Address: channel.MinerAddress,
+			Amount:  minerAmount,
+		})
+	}
+	if operatorChange > 0 {
+		outputs = append(outputs, &types.SettlementOutput{
+			Party:   PartyOperator,
+			Address: operatorAddress,
+			Amount:  operatorChange,
+		})
+	}
+
+	// Exit leaves can only be spent once their CSV delay has passed
+	sequence := uint32(finalSequence)
+	settlementType := SettlementCooperative
+	if leaf.Locktime > 0 {
+		sequence = leaf.Locktime
+		settlementType = SettlementUnilateral
+	}
+
+	tx := &types.SettlementTx{
+		Input: &types.SettlementInput{
+			Outpoint:     channel.FundingOutpoint,
+			Amount:       channel.InitialFunding,
+			Leaf:         leaf.Name,
+			LeafHash:     leaf.LeafHash,
+			Script:       leaf.Script,
+			ControlBlock: leaf.ControlBlock,
+			Sequence:     sequence,
+		},
+		Outputs: outputs,
+	}
+	digest, err := SettlementDigest(tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.Digest = hex.EncodeToString(digest[:])
+
+	return &types.Settlement{
+		ID:             generateSettlementID(),
+		ChannelID:      channel.ID,
+		MinerID:        channel.MinerID,
+		Type:           settlementType,
+		ChannelAmount:  channel.InitialFunding,
+		MinerAmount:    minerAmount,
+		OperatorChange: operatorChange,
+		Fee:            fee,
+		FinalState:     finalState,
+		Transaction:    tx,
+		Signatures:     make([]*types.SettlementSignature, 0, 3),
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// signSettlement collects the signature of every party the settlement's leaf
+// requires and verifies the result. The operator signs with the channel's operator
+// key and the server through the server cosigner; other parties must be given.
+func (cm *Manager) signSettlement(channel *types.Channel, settlement *types.Settlement, cosigners map[string]Cosigner) error {
+	digest, err := SettlementDigest(settlement.Transaction)
+	if err != nil {
+		return err
+	}
+
+	// Start over if an earlier attempt collected only some of the signatures
+	settlement.Signatures = settlement.Signatures[:0]
+	for _, party := range leafParties[settlement.Transaction.Input.Leaf] {
+		var sig []byte
+		var pubKey *secp256k1.PublicKey
+
+		switch party {
+		case PartyOperator:
+			operatorKey, err := cm.operatorKeys.OperatorKey(channel.OperatorKeyIndex)
+			if err != nil {
+				return fmt.Errorf("failed to derive operator key for channel %s: %w", channel.ID, err)
+			}
+			if sig, err = schnorr.Sign(operatorKey, digest[:]); err != nil {
+				return fmt.Errorf("failed to sign settlement: %w", err)
+			}
+			pubKey = operatorKey.PubKey()
+		case PartyServer:
+			if cm.server == nil {
+				return fmt.Errorf("no server cosigner configured")
+			}
+			if sig, err = cm.server(settlement, digest); err != nil {
+				return fmt.Errorf("server did not sign settlement: %w", err)
+			}
+			pubKey = cm.serverPubKey
+		default:
+			cosigner := cosigners[party]
+			if cosigner == nil {
+				return fmt.Errorf("%s cannot sign the settlement of channel %s", party, channel.ID)
+			}
+			if sig, err = cosigner(settlement, digest); err != nil {
+				return fmt.Errorf("%s did not sign settlement: %w", party, err)
+			}
+			pubKey = channel.MinerKey
+		}
+
+		addSignature(settlement, party, schnorr.SerializePubKey(pubKey), sig)
+	}
+
+	return VerifySettlement(settlement, channel)
+}
+
+// stateBalance returns the miner's side of a state, which is nothing without one
+func stateBalance(state *types.PaymentUpdate) uint64 {
+	if state == nil {
+		return 0
+	}
+	return state.MinerBalance
+}
+
+// addSignature records a party's signature on a settlement
+func addSignature(settlement *types.Settlement, party string, pubKey, sig []byte) {
+	settlement.Signatures = append(settlement.Signatures, &types.SettlementSignature{
+		Party:     party,
+		PubKey:    hex.EncodeToString(pubKey),
+		Signature: hex.EncodeToString(sig),
+	})
+}
+
+// SerializeSettlement encodes a settlement transaction template as
+// len(outpoint) (1 byte) || outpoint || amount (8 bytes BE) || leaf hash (32 bytes) ||
+// sequence (4 bytes BE) || output count (1 byte) || per output: len(address) (1 byte) ||
+// address || amount (8 bytes BE)
+func SerializeSettlement(tx *types.SettlementTx) ([]byte, error) {
+	leafHash, err := hex.DecodeString(tx.Input.LeafHash)
+	if err != nil || len(leafHash) != 32 {
+		return nil, fmt.Errorf("invalid settlement leaf hash")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(byte(len(tx.Input.Outpoint)))
+	buf.WriteString(tx.Input.Outpoint)
+	binary.Write(&buf, binary.BigEndian, tx.Input.Amount)
+	buf.Write(leafHash)
+	binary.Write(&buf, binary.BigEndian, tx.Input.Sequence)
+	buf.WriteByte(byte(len(tx.Outputs)))
+	for _, output := range tx.Outputs {
+		buf.WriteByte(byte(len(output.Address)))
+		buf.WriteString(output.Address)
+		binary.Write(&buf, binary.BigEndian, output.Amount)
+	}
+	return buf.Bytes(), nil
+}
+
+// SettlementDigest returns the 32-byte message every party signs for a settlement
+func SettlementDigest(tx *types.SettlementTx) ([32]byte, error) {
+	data, err := SerializeSettlement(tx)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return schnorr.TaggedHash(settlementTag, data), nil
+}
+
+// VerifySettlement checks that a settlement spends the channel through one of its
+// leaves, that its outputs and fee add up to the channel amount and that it carries
+// a valid signature from every key the leaf requires
+func VerifySettlement(settlement *types.Settlement, channel *types.Channel) error {
+	tx := settlement.Transaction
+	if tx == nil || tx.Input == nil {
+		return fmt.Errorf("settlement has no transaction")
+	}
+
+	leaf, err := findLeaf(channel, tx.Input.Leaf)
+	if err != nil {
+		return err
+	}
+	if tx.Input.LeafHash != leaf.LeafHash {
+		return fmt.Errorf("settlement spends an unknown leaf")
+	}
+	if tx.Input.Outpoint != channel.FundingOutpoint {
+		return fmt.Errorf("settlement spends the wrong outpoint")
+	}
+
+	total := settlement.Fee
+	for _, output := range tx.Outputs {
+		total += output.Amount
+	}
+	if total != tx.Input.Amount {
+		return fmt.Errorf("settlement pays out %d sats but spends %d", total, tx.Input.Amount)
+	}
+
+	digest, err := SettlementDigest(tx)
+	if err != nil {
+		return err
+	}
+	if tx.Digest != hex.EncodeToString(digest[:]) {
+		return fmt.Errorf("settlement digest does not match its transaction")
+	}
+
+	for i, party := range leafParties[leaf.Name] {
+		sig := findSignature(settlement, party)
+		if sig == nil {
+			return fmt.Errorf("settlement is missing the %s signature", party)
+		}
+		if sig.PubKey != leaf.PubKeys[i] {
+			return fmt.Errorf("settlement signed by unexpected %s key", party)
+		}
+
+		pubKey, err := hex.DecodeString(sig.PubKey)
+		if err != nil {
+			return fmt.Errorf("invalid %s key encoding: %w", party, err)
+		}
+		sigBytes, err := hex.DecodeString(sig.Signature)
+		if err != nil {
+			return fmt.Errorf("invalid %s signature encoding: %w", party, err)
+		}
+		if err := schnorr.Verify(pubKey, digest[:], sigBytes); err != nil {
+			return fmt.Errorf("%s settlement signature: %w", party, err)
+		}
+	}
+
+	return nil
+}
+
+// findLeaf returns the named leaf of a channel's script tree
+func findLeaf(channel *types.Channel, name string) (*types.TapLeaf, error) {
+	if channel.ScriptTree == nil {
+		return nil, fmt.Errorf("channel %s has no script tree", channel.ID)
+	}
+	for _, leaf := range channel.ScriptTree.Leaves {
+		if leaf.Name == name {
+			return leaf, nil
+		}
+	}
+	return nil, fmt.Errorf("channel %s has no %s leaf", channel.ID, name)
+}
+
+// findSignature returns a party's signature on a settlement
+func findSignature(settlement *types.Settlement, party string) *types.SettlementSignature {
+	for _, sig := range settlement.Signatures {
+		if sig.Party == party {
+			return sig
+		}
+	}
+	return nil
+}
+
+// generateSettlementID generates a unique settlement ID
+func generateSettlementID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
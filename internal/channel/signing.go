@@ -0,0 +1,87 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// channelStateTag is the BIP-340 tag the operator signs channel states under
+const channelStateTag = "SparkPool/ChannelState"
+
+// SerializeState encodes a channel state as
+// len(channelID) (1 byte) || channelID || sequence (8 bytes BE) || miner balance (8 bytes BE)
+func SerializeState(channelID string, sequenceNum, minerBalance uint64) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(len(channelID)))
+	buf.WriteString(channelID)
+	binary.Write(&buf, binary.BigEndian, sequenceNum)
+	binary.Write(&buf, binary.BigEndian, minerBalance)
+	return buf.Bytes()
+}
+
+// StateDigest returns the 32-byte message the operator signs for a serialized state
+func StateDigest(state []byte) [32]byte {
+	return schnorr.TaggedHash(channelStateTag, state)
+}
+
+// nextSequence returns the sequence number of a channel's next state, one past its
+// latest signed state
+func nextSequence(channel *types.Channel) uint64 {
+	if n := len(channel.PaymentHistory); n > 0 {
+		return channel.PaymentHistory[n-1].SequenceNum + 1
+	}
+	return 1
+}
+
+// signState fills in the serialized state and operator signature of an update
+func signState(update *types.PaymentUpdate, operatorKey *secp256k1.PrivateKey) error {
+	state := SerializeState(update.ChannelID, update.SequenceNum, update.MinerBalance)
+	digest := StateDigest(state)
+
+	sig, err := schnorr.Sign(operatorKey, digest[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign channel state: %w", err)
+	}
+
+	update.State = hex.EncodeToString(state)
+	update.Signature = hex.EncodeToString(sig)
+	update.OperatorKey = hex.EncodeToString(schnorr.SerializePubKey(operatorKey.PubKey()))
+	return nil
+}
+
+// VerifyPaymentUpdate checks that an update's serialized state matches its fields and
+// carries a valid signature from operatorKey (x-only). Miners use it to prove what
+// they are owed without trusting the pool's records.
+func VerifyPaymentUpdate(update *types.PaymentUpdate, operatorKey []byte) error {
+	state, err := hex.DecodeString(update.State)
+	if err != nil {
+		return fmt.Errorf("invalid state encoding: %w", err)
+	}
+
+	expected := SerializeState(update.ChannelID, update.SequenceNum, update.MinerBalance)
+	if !bytes.Equal(state, expected) {
+		return fmt.Errorf("serialized state does not match update fields")
+	}
+
+	if update.OperatorKey != hex.EncodeToString(operatorKey) {
+		return fmt.Errorf("update signed by unexpected operator key")
+	}
+
+	sig, err := hex.DecodeString(update.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	digest := StateDigest(state)
+	if err := schnorr.Verify(operatorKey, digest[:], sig); err != nil {
+		return fmt.Errorf("channel state signature: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// ErrIllegalTransition is matched by every TransitionError
+var ErrIllegalTransition = errors.New("illegal channel state transition")
+
+// ErrChannelNotOpen is returned when an operation needs an open channel
+var ErrChannelNotOpen = errors.New("channel is not open")
+
+// TransitionError is returned when a channel is asked to move to a state its
+// current state cannot reach
+type TransitionError struct {
+	ChannelID string
+	From      types.ChannelStatus
+	To        types.ChannelStatus
+}
+
+// Error implements the error interface
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("channel %s cannot move from %s to %s", e.ChannelID, e.From, e.To)
+}
+
+// Is makes every TransitionError match ErrIllegalTransition
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// transitions lists the states each state may move to
+var transitions = map[types.ChannelStatus][]types.ChannelStatus{
+	types.ChannelPendingFunding: {
+		types.ChannelOpen,
+		types.ChannelClosed, // funding abandoned
+	},
+	types.ChannelOpen: {
+		types.ChannelClosingCooperative,
+		types.ChannelClosingUnilateral,
+		types.ChannelExpired,
+	},
+	types.ChannelClosingCooperative: {
+		types.ChannelOpen, // close abandoned before its round settled
+		types.ChannelClosed,
+		types.ChannelClosingUnilateral, // the counterparty stopped cooperating
+		types.ChannelDisputed,
+	},
+	types.ChannelClosingUnilateral: {
+		types.ChannelClosed,
+		types.ChannelDisputed,
+	},
+	types.ChannelDisputed: {
+		types.ChannelClosed,
+	},
+	types.ChannelClosed:  {},
+	types.ChannelExpired: {},
+}
+
+// CanTransition reports whether a channel in state from may move to state to
+func CanTransition(from, to types.ChannelStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether a channel in the given state can never change again
+func IsTerminal(status types.ChannelStatus) bool {
+	next, known := transitions[status]
+	return known && len(next) == 0
+}
+
+// Transition moves a channel to a new state and records the change in its history
+func Transition(channel *types.Channel, to types.ChannelStatus, reason string) error {
+	if !CanTransition(channel.Status, to) {
+		return &TransitionError{
+			ChannelID: channel.ID,
+			From:      channel.Status,
+			To:        to,
+		}
+	}
+
+	now := time.Now()
+	channel.History = append(channel.History, &types.ChannelTransition{
+		From:      channel.Status,
+		To:        to,
+		Reason:    reason,
+		Timestamp: now,
+	})
+	channel.Status = to
+	channel.LastUpdated = now
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package channel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestEveryTransition(t *testing.T) {
+	states := []types.ChannelStatus{
+		types.ChannelPendingFunding,
+		types.ChannelOpen,
+		types.ChannelClosingCooperative,
+		types.ChannelClosingUnilateral,
+		types.ChannelDisputed,
+		types.ChannelClosed,
+		types.ChannelExpired,
+	}
+	type edge struct{ from, to types.ChannelStatus }
+	legal := map[edge]bool{
+		{types.ChannelPendingFunding, types.ChannelOpen}:                  true,
+		{types.ChannelPendingFunding, types.ChannelClosed}:                true,
+		{types.ChannelOpen, types.ChannelClosingCooperative}:              true,
+		{types.ChannelOpen, types.ChannelClosingUnilateral}:               true,
+		{types.ChannelOpen, types.ChannelExpired}:                         true,
+		{types.ChannelClosingCooperative, types.ChannelOpen}:              true,
+		{types.ChannelClosingCooperative, types.ChannelClosed}:            true,
+		{types.ChannelClosingCooperative, types.ChannelClosingUnilateral}: true,
+		{types.ChannelClosingCooperative, types.ChannelDisputed}:          true,
+		{types.ChannelClosingUnilateral, types.ChannelClosed}:             true,
+		{types.ChannelClosingUnilateral, types.ChannelDisputed}:           true,
+		{types.ChannelDisputed, types.ChannelClosed}:                      true,
+	}
+
+	for _, from := range states {
+		for _, to := range states {
+			want := legal[edge{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+
+			ch := &types.Channel{ID: "ch", Status: from}
+			err := Transition(ch, to, "test")
+			switch {
+			case want && err != nil:
+				t.Errorf("%s -> %s: %v", from, to, err)
+			case want && (ch.Status != to || len(ch.History) != 1 || ch.History[0].From != from):
+				t.Errorf("%s -> %s left status %s with history %+v", from, to, ch.Status, ch.History)
+			case !want && !errors.Is(err, ErrIllegalTransition):
+				t.Errorf("%s -> %s: got %v, want ErrIllegalTransition", from, to, err)
+			case !want && (ch.Status != from || len(ch.History) != 0):
+				t.Errorf("refused %s -> %s still moved the channel to %s", from, to, ch.Status)
+			}
+		}
+	}
+
+	for _, state := range states {
+		terminal := state == types.ChannelClosed || state == types.ChannelExpired
+		if IsTerminal(state) != terminal {
+			t.Errorf("IsTerminal(%s) = %v, want %v", state, IsTerminal(state), terminal)
+		}
+	}
+	if IsTerminal("unknown") || CanTransition("unknown", types.ChannelOpen) {
+		t.Error("an unknown state is treated as known")
+	}
+}
@@ -0,0 +1,346 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Unilateral exit delays (in blocks) of the channel's CSV closures
+const (
+	OperatorExitDelay = 144 // ~24 hours
+	MinerExitDelay    = 288 // ~48 hours
+)
+
+// Tapscript leaf names
+const (
+	LeafCooperative  = "cooperative"
+	LeafOperatorExit = "operator_exit"
+	LeafMinerExit    = "miner_exit"
+)
+
+// Script opcodes used by the channel tapscripts
+const (
+	opDrop                = 0x75
+	opChecksig            = 0xac
+	opChecksigverify      = 0xad
+	opChecksequenceverify = 0xb2
+)
+
+// tapscriptLeafVersion is the BIP-342 leaf version
+const tapscriptLeafVersion = 0xc0
+
+// addressHRP is the bech32 human readable part of channel addresses
+const addressHRP = "bc"
+
+// unspendableKeyX is the BIP-341 NUMS point H used as internal key, so the
+// channel output can only be spent through one of its script leaves
+var unspendableKeyX, _ = hex.DecodeString("50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0")
+
+// tapLeaf is a script leaf before it is placed in the tree
+type tapLeaf struct {
+	name     string
+	script   []byte
+	locktime uint32
+	pubKeys  []*secp256k1.PublicKey
+	hash     [32]byte
+}
+
+// BuildScriptTree builds the taproot output for a channel: a 3-of-3 cooperative
+// leaf (operator, miner, server) and two CSV exit leaves that let the operator
+// leave after OperatorExitDelay blocks and the miner after MinerExitDelay blocks,
+// each together with the server. It returns the script tree and the P2TR address.
+func BuildScriptTree(
+	operatorKey *secp256k1.PublicKey,
+	minerKey *secp256k1.PublicKey,
+	serverKey *secp256k1.PublicKey,
+) (*types.ChannelScriptTree, string, error) {
+	if operatorKey == nil || minerKey == nil || serverKey == nil {
+		return nil, "", fmt.Errorf("operator, miner and server keys are required")
+	}
+
+	leaves := []*tapLeaf{
+		newTapLeaf(LeafCooperative, 0, operatorKey, minerKey, serverKey),
+		newTapLeaf(LeafOperatorExit, OperatorExitDelay, operatorKey, serverKey),
+		newTapLeaf(LeafMinerExit, MinerExitDelay, minerKey, serverKey),
+	}
+
+	// The cooperative path is the common one, so it gets the shortest proof:
+	// root = branch(cooperative, branch(operator_exit, miner_exit))
+	exitBranch := tapBranchHash(leaves[1].hash, leaves[2].hash)
+	merkleRoot := tapBranchHash(leaves[0].hash, exitBranch)
+	proofs := [][][32]byte{
+		{exitBranch},
+		{leaves[2].hash, leaves[0].hash},
+		{leaves[1].hash, leaves[0].hash},
+	}
+
+	outputKey, oddY, err := tweakInternalKey(unspendableKeyX, merkleRoot)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tree := &types.ChannelScriptTree{
+		InternalKey: hex.EncodeToString(unspendableKeyX),
+		MerkleRoot:  hex.EncodeToString(merkleRoot[:]),
+		OutputKey:   hex.EncodeToString(outputKey),
+		Leaves:      make([]*types.TapLeaf, 0, len(leaves)),
+	}
+
+	for i, leaf := range leaves {
+		pubKeys := make([]string, 0, len(leaf.pubKeys))
+		for _, key := range leaf.pubKeys {
+			pubKeys = append(pubKeys, hex.EncodeToString(schnorr.SerializePubKey(key)))
+		}
+
+		tree.Leaves = append(tree.Leaves, &types.TapLeaf{
+			Name:         leaf.name,
+			Script:       hex.EncodeToString(leaf.script),
+			LeafHash:     hex.EncodeToString(leaf.hash[:]),
+			ControlBlock: hex.EncodeToString(controlBlock(oddY, proofs[i])),
+			Locktime:     leaf.locktime,
+			PubKeys:      pubKeys,
+		})
+	}
+
+	address, err := encodeTaprootAddress(addressHRP, outputKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return tree, address, nil
+}
+
+// newTapLeaf builds a leaf requiring a signature from every key, behind a CSV
+// delay when locktime is non-zero
+func newTapLeaf(name string, locktime uint32, pubKeys ...*secp256k1.PublicKey) *tapLeaf {
+	var script bytes.Buffer
+
+	if locktime > 0 {
+		script.Write(pushData(scriptNum(int64(locktime))))
+		script.WriteByte(opChecksequenceverify)
+		script.WriteByte(opDrop)
+	}
+
+	for i, key := range pubKeys {
+		script.Write(pushData(schnorr.SerializePubKey(key)))
+		if i < len(pubKeys)-1 {
+			script.WriteByte(opChecksigverify)
+		} else {
+			script.WriteByte(opChecksig)
+		}
+	}
+
+	leaf := &tapLeaf{
+		name:     name,
+		script:   script.Bytes(),
+		locktime: locktime,
+		pubKeys:  pubKeys,
+	}
+	leaf.hash = tapLeafHash(leaf.script)
+	return leaf
+}
+
+// tapLeafHash returns the BIP-341 leaf hash of a tapscript
+func tapLeafHash(script []byte) [32]byte {
+	return schnorr.TaggedHash("TapLeaf", []byte{tapscriptLeafVersion}, compactSize(uint64(len(script))), script)
+}
+
+// tapBranchHash returns the BIP-341 branch hash of two child nodes
+func tapBranchHash(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return schnorr.TaggedHash("TapBranch", a[:], b[:])
+}
+
+// tweakInternalKey returns the x-only taproot output key Q = P + H(P||root)G
+// and whether Q has an odd Y coordinate
+func tweakInternalKey(internalKeyX []byte, merkleRoot [32]byte) ([]byte, bool, error) {
+	// BIP-340 lifts x-only keys to the point with an even Y coordinate
+	internalKey, err := secp256k1.ParsePubKey(append([]byte{0x02}, internalKeyX...))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid internal key: %w", err)
+	}
+
+	tweakHash := schnorr.TaggedHash("TapTweak", internalKeyX, merkleRoot[:])
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetBytes(&tweakHash); overflow != 0 {
+		return nil, false, fmt.Errorf("taproot tweak overflows the curve order")
+	}
+
+	var p, t, q secp256k1.JacobianPoint
+	internalKey.AsJacobian(&p)
+	secp256k1.ScalarBaseMultNonConst(&tweak, &t)
+	secp256k1.AddNonConst(&p, &t, &q)
+	if (q.X.IsZero() && q.Y.IsZero()) || q.Z.IsZero() {
+		return nil, false, fmt.Errorf("taproot output key is the point at infinity")
+	}
+	q.ToAffine()
+
+	outputKey := secp256k1.NewPublicKey(&q.X, &q.Y)
+	return schnorr.SerializePubKey(outputKey), q.Y.IsOdd(), nil
+}
+
+// controlBlock serializes the BIP-341 control block for a leaf
+func controlBlock(outputKeyOddY bool, proof [][32]byte) []byte {
+	version := byte(tapscriptLeafVersion)
+	if outputKeyOddY {
+		version |= 0x01
+	}
+
+	block := append([]byte{version}, unspendableKeyX...)
+	for _, node := range proof {
+		block = append(block, node[:]...)
+	}
+	return block
+}
+
+// scriptNum encodes n as a minimal little-endian script number
+func scriptNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+
+	var result []byte
+	for n > 0 {
+		result = append(result, byte(n&0xff))
+		n >>= 8
+	}
+
+	// The most significant bit carries the sign, so add a byte if it is taken
+	if result[len(result)-1]&0x80 != 0 {
+		if negative {
+			result = append(result, 0x80)
+		} else {
+			result = append(result, 0x00)
+		}
+	} else if negative {
+		result[len(result)-1] |= 0x80
+	}
+
+	return result
+}
+
+// pushData returns the script opcodes pushing data onto the stack
+func pushData(data []byte) []byte {
+	switch {
+	case len(data) < 0x4c:
+		return append([]byte{byte(len(data))}, data...)
+	case len(data) <= 0xff:
+		return append([]byte{0x4c, byte(len(data))}, data...)
+	default:
+		return append([]byte{0x4d, byte(len(data)), byte(len(data) >> 8)}, data...)
+	}
+}
+
+// compactSize encodes n as a Bitcoin variable length integer
+func compactSize(n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return []byte{byte(n)}
+	case n <= 0xffff:
+		return []byte{0xfd, byte(n), byte(n >> 8)}
+	case n <= 0xffffffff:
+		return []byte{0xfe, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+	default:
+		return []byte{0xff, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24),
+			byte(n >> 32), byte(n >> 40), byte(n >> 48), byte(n >> 56)}
+	}
+}
+
+// bech32Charset is the bech32 data character set
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32mConst is the BIP-350 checksum constant
+const bech32mConst = 0x2bc830a3
+
+// encodeTaprootAddress encodes a segwit v1 output key as a bech32m address
+func encodeTaprootAddress(hrp string, outputKey []byte) (string, error) {
+	program, err := convertBits(outputKey, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	data := append([]byte{1}, program...)
+
+	values := append(hrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConst
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return sb.String(), nil
+}
+
+// bech32Polymod computes the bech32 checksum polynomial
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// hrpExpand expands the human readable part for checksum computation
+func hrpExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]&31)
+	}
+	return result
+}
+
+// convertBits regroups data from fromBits-bit to toBits-bit groups
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	acc := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1)<<toBits - 1
+
+	var result []byte
+	for _, b := range data {
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte((acc>>bits)&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte((acc<<(toBits-bits))&maxValue))
+		}
+	} else if bits >= fromBits || (acc<<(toBits-bits))&maxValue != 0 {
+		return nil, fmt.Errorf("invalid padding in bit conversion")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func mustHash(t *testing.T, s string) [32]byte {
+	t.Helper()
+	var h [32]byte
+	copy(h[:], mustHex(t, s))
+	return h
+}
+
+// BIP-341 wallet test vectors (scriptPubKey section) with a tapscript tree
+func TestTaprootOutputVectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		internalKey   string
+		merkleRoot    string
+		tweakedKey    string
+		address       string
+		controlParity bool
+	}{
+		{
+			name:        "single leaf",
+			internalKey: "187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			merkleRoot:  "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+			tweakedKey:  "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+			address:     "bc1pz37fc4cn9ah8anwm4xqqhvxygjf9rjf2resrw8h8w4tmvcs0863sa2e586",
+			// Control block c1...: the output key has an odd Y
+			controlParity: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputKey, oddY, err := tweakInternalKey(mustHex(t, tt.internalKey), mustHash(t, tt.merkleRoot))
+			if err != nil {
+				t.Fatalf("tweakInternalKey: %v", err)
+			}
+			if got := hex.EncodeToString(outputKey); got != tt.tweakedKey {
+				t.Errorf("output key %s, want %s", got, tt.tweakedKey)
+			}
+			if tt.controlParity && !oddY {
+				t.Error("output key Y reported even, want odd")
+			}
+
+			address, err := encodeTaprootAddress("bc", outputKey)
+			if err != nil {
+				t.Fatalf("encodeTaprootAddress: %v", err)
+			}
+			if address != tt.address {
+				t.Errorf("address %s, want %s", address, tt.address)
+			}
+		})
+	}
+}
+
+func TestTapLeafAndBranchHashes(t *testing.T) {
+	// BIP-341 wallet test vector 1: a single tapscript leaf
+	script := mustHex(t, "20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+	leafHash := tapLeafHash(script)
+	if got := hex.EncodeToString(leafHash[:]); got != "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21" {
+		t.Errorf("leaf hash %s", got)
+	}
+
+	// Branches sort their children, so the order they are given in does not matter
+	other := tapLeafHash(mustHex(t, "51"))
+	if tapBranchHash(leafHash, other) != tapBranchHash(other, leafHash) {
+		t.Error("branch hash depends on the order of its children")
+	}
+}
+
+func TestEncodeTaprootAddress(t *testing.T) {
+	// BIP-350: the segwit v1 program of the generator point's x coordinate
+	program := mustHex(t, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	address, err := encodeTaprootAddress("bc", program)
+	if err != nil {
+		t.Fatalf("encodeTaprootAddress: %v", err)
+	}
+	if want := "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"; address != want {
+		t.Errorf("address %s, want %s", address, want)
+	}
+}
+
+func TestScriptNum(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, ""},
+		{1, "01"},
+		{127, "7f"},
+		{128, "8000"},
+		{OperatorExitDelay, "9000"},
+		{MinerExitDelay, "2001"},
+		{-1, "81"},
+		{-128, "8080"},
+	}
+
+	for _, tt := range tests {
+		if got := hex.EncodeToString(scriptNum(tt.n)); got != tt.want {
+			t.Errorf("scriptNum(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBuildScriptTreeControlBlocksCommitToOutputKey(t *testing.T) {
+	keys := make([]*secp256k1.PublicKey, 3)
+	for i := range keys {
+		priv, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		keys[i] = priv.PubKey()
+	}
+
+	tree, address, err := BuildScriptTree(keys[0], keys[1], keys[2])
+	if err != nil {
+		t.Fatalf("BuildScriptTree: %v", err)
+	}
+	if len(tree.Leaves) != 3 {
+		t.Fatalf("%d leaves, want 3", len(tree.Leaves))
+	}
+
+	outputKey := mustHex(t, tree.OutputKey)
+	if want, _ := encodeTaprootAddress(addressHRP, outputKey); address != want {
+		t.Errorf("address %s does not encode output key", address)
+	}
+
+	// Every leaf's control block must lead back to the tree's merkle root and
+	// output key, as a BIP-341 script path spend would check
+	for _, leaf := range tree.Leaves {
+		control := mustHex(t, leaf.ControlBlock)
+		if (len(control)-33)%32 != 0 {
+			t.Fatalf("leaf %s: control block is %d bytes", leaf.Name, len(control))
+		}
+		if control[0]&0xfe != tapscriptLeafVersion {
+			t.Errorf("leaf %s: leaf version %x", leaf.Name, control[0]&0xfe)
+		}
+		if !bytes.Equal(control[1:33], unspendableKeyX) {
+			t.Errorf("leaf %s: control block internal key is not the NUMS point", leaf.Name)
+		}
+
+		node := tapLeafHash(mustHex(t, leaf.Script))
+		if hex.EncodeToString(node[:]) != leaf.LeafHash {
+			t.Errorf("leaf %s: leaf hash does not match its script", leaf.Name)
+		}
+		for i := 33; i < len(control); i += 32 {
+			node = tapBranchHash(node, [32]byte(control[i:i+32]))
+		}
+		if hex.EncodeToString(node[:]) != tree.MerkleRoot {
+			t.Errorf("leaf %s: proof leads to %x, not the merkle root", leaf.Name, node)
+		}
+
+		tweaked, oddY, err := tweakInternalKey(control[1:33], node)
+		if err != nil {
+			t.Fatalf("leaf %s: %v", leaf.Name, err)
+		}
+		if !bytes.Equal(tweaked, outputKey) || oddY != (control[0]&1 == 1) {
+			t.Errorf("leaf %s: control block does not commit to the output key", leaf.Name)
+		}
+	}
+
+	// The cooperative leaf needs all three keys, the exits two behind a CSV delay
+	wantKeys := map[string][]*secp256k1.PublicKey{
+		LeafCooperative:  {keys[0], keys[1], keys[2]},
+		LeafOperatorExit: {keys[0], keys[2]},
+		LeafMinerExit:    {keys[1], keys[2]},
+	}
+	for _, leaf := range tree.Leaves {
+		want := wantKeys[leaf.Name]
+		if len(leaf.PubKeys) != len(want) {
+			t.Errorf("leaf %s has %d keys, want %d", leaf.Name, len(leaf.PubKeys), len(want))
+			continue
+		}
+		for i, key := range want {
+			if leaf.PubKeys[i] != hex.EncodeToString(schnorr.SerializePubKey(key)) {
+				t.Errorf("leaf %s key %d is wrong", leaf.Name, i)
+			}
+		}
+	}
+}
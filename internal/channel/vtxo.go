@@ -0,0 +1,129 @@
+package channel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// RefreshVSize is the estimated virtual size in vbytes of a channel VTXO refreshed
+// on its own: one input spent through the cooperative leaf into one P2TR output
+const RefreshVSize = 187
+
+// forfeitTag is the BIP-340 tag forfeit digests are computed under
+const forfeitTag = "SparkPool/Forfeit"
+
+// ExpiringChannels returns the open channels whose VTXO expires at or before
+// deadline, soonest first
+func (cm *Manager) ExpiringChannels(deadline uint64) []*types.Channel {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	expiring := make([]*types.Channel, 0)
+	for _, channel := range cm.channels {
+		if channel.Status == types.ChannelOpen && channel.VTXO != nil && channel.VTXO.ExpiryHeight <= deadline {
+			expiring = append(expiring, channel)
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		if expiring[i].VTXO.ExpiryHeight != expiring[j].VTXO.ExpiryHeight {
+			return expiring[i].VTXO.ExpiryHeight < expiring[j].VTXO.ExpiryHeight
+		}
+		return expiring[i].ID < expiring[j].ID
+	})
+	return expiring
+}
+
+// PrepareRefresh checks that an open channel can be refreshed into a new VTXO at
+// height, the operator paying fee from its side, and returns the new VTXO's
+// amount. The miner's side and the latest signed state carry over unchanged. The
+// channel is left unchanged until CompleteRefresh.
+func (cm *Manager) PrepareRefresh(channel *types.Channel, height, fee uint64) (uint64, error) {
+	if channel.Status != types.ChannelOpen {
+		return 0, ErrChannelNotOpen
+	}
+	if channel.VTXO == nil {
+		return 0, fmt.Errorf("channel %s has no VTXO", channel.ID)
+	}
+	if height >= channel.VTXO.ExpiryHeight {
+		return 0, fmt.Errorf("VTXO of channel %s expired at height %d", channel.ID, channel.VTXO.ExpiryHeight)
+	}
+	if fee > channel.CurrentBalance {
+		return 0, fmt.Errorf("channel %s cannot pay a refresh fee of %d sats", channel.ID, fee)
+	}
+	if n := len(channel.PaymentHistory); n > 0 {
+		if channel.PaymentHistory[n-1].MinerBalance != channel.InitialFunding-channel.CurrentBalance {
+			return 0, fmt.Errorf("channel %s balance does not match its latest signed state", channel.ID)
+		}
+	}
+
+	return channel.InitialFunding - fee, nil
+}
+
+// CompleteRefresh moves a channel into the VTXO the Ark server issued for its
+// refresh. The operator's side pays the difference from the old VTXO as the
+// refresh fee. Later settlements spend the new VTXO.
+func (cm *Manager) CompleteRefresh(channel *types.Channel, vtxo *types.VTXO) error {
+	if channel.Status != types.ChannelOpen {
+		return ErrChannelNotOpen
+	}
+	fee := channel.InitialFunding - vtxo.Amount
+	if vtxo.Amount > channel.InitialFunding || fee > channel.CurrentBalance {
+		return fmt.Errorf("VTXO of %d sats cannot hold channel %s", vtxo.Amount, channel.ID)
+	}
+
+	vtxo.RefreshFee = fee
+	channel.VTXOHistory = append(channel.VTXOHistory, channel.VTXO)
+	channel.VTXO = vtxo
+	channel.FundingOutpoint = vtxo.Outpoint
+	channel.InitialFunding -= fee
+	channel.CurrentBalance -= fee
+	channel.LastUpdated = time.Now()
+
+	return nil
+}
+
+// SignForfeit signs the forfeit of a channel VTXO spent in an Ark round with the
+// channel's operator key, committing to the outpoint and the round
+func (cm *Manager) SignForfeit(channel *types.Channel, outpoint, roundID string) (*types.ArkForfeit, error) {
+	operatorKey, err := cm.operatorKeys.OperatorKey(channel.OperatorKeyIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive operator key for channel %s: %w", channel.ID, err)
+	}
+	return SignForfeit(operatorKey, outpoint, roundID)
+}
+
+// SignForfeit signs the forfeit of a VTXO held by key and spent in an Ark round
+func SignForfeit(key *secp256k1.PrivateKey, outpoint, roundID string) (*types.ArkForfeit, error) {
+	digest := ForfeitDigest(outpoint, roundID)
+	sig, err := schnorr.Sign(key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign forfeit: %w", err)
+	}
+
+	return &types.ArkForfeit{
+		Outpoint:  outpoint,
+		PubKey:    hex.EncodeToString(schnorr.SerializePubKey(key.PubKey())),
+		Signature: hex.EncodeToString(sig),
+	}, nil
+}
+
+// ForfeitDigest returns the digest a forfeit of outpoint in roundID is signed over
+func ForfeitDigest(outpoint, roundID string) [32]byte {
+	return schnorr.TaggedHash(forfeitTag, []byte{byte(len(outpoint))}, []byte(outpoint), []byte(roundID))
+}
+
+// ExpireChannel marks an open channel whose VTXO was not refreshed in time as
+// expired
+func (cm *Manager) ExpireChannel(channel *types.Channel, height uint64) error {
+	if channel.VTXO == nil || height < channel.VTXO.ExpiryHeight {
+		return fmt.Errorf("VTXO of channel %s has not expired", channel.ID)
+	}
+	reason := fmt.Sprintf("VTXO expired at height %d", channel.VTXO.ExpiryHeight)
+	return Transition(channel, types.ChannelExpired, reason)
+}
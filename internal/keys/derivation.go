@@ -0,0 +1,89 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// hardenedOffset marks a BIP-32 child index as hardened
+const hardenedOffset = 0x80000000
+
+// HardenedIndex returns the hardened form of a child index
+func HardenedIndex(index uint32) uint32 {
+	return index | hardenedOffset
+}
+
+// ExtendedKey is a BIP-32 extended private key
+type ExtendedKey struct {
+	key       secp256k1.ModNScalar
+	chainCode [32]byte
+}
+
+// NewMaster derives the BIP-32 master key from a seed
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	var master ExtendedKey
+	if overflow := master.key.SetByteSlice(sum[:32]); overflow || master.key.IsZero() {
+		return nil, fmt.Errorf("seed produces an invalid master key")
+	}
+	copy(master.chainCode[:], sum[32:])
+
+	return &master, nil
+}
+
+// Child derives the hardened child key at index. Only hardened derivation is
+// supported, since the pool never hands out extended public keys.
+func (ek *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index < hardenedOffset {
+		return nil, fmt.Errorf("non-hardened derivation is not supported")
+	}
+
+	// I = HMAC-SHA512(c_par, 0x00 || ser256(k_par) || ser32(i))
+	keyBytes := ek.key.Bytes()
+	data := make([]byte, 0, 37)
+	data = append(data, 0x00)
+	data = append(data, keyBytes[:]...)
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, ek.chainCode[:])
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// k_i = parse256(I_L) + k_par (mod n)
+	var child ExtendedKey
+	if overflow := child.key.SetByteSlice(sum[:32]); overflow {
+		return nil, fmt.Errorf("child key %d is invalid, use the next index", index)
+	}
+	child.key.Add(&ek.key)
+	if child.key.IsZero() {
+		return nil, fmt.Errorf("child key %d is invalid, use the next index", index)
+	}
+	copy(child.chainCode[:], sum[32:])
+
+	return &child, nil
+}
+
+// DerivePath derives a descendant key following the given child indexes
+func (ek *ExtendedKey) DerivePath(path ...uint32) (*ExtendedKey, error) {
+	key := ek
+	for _, index := range path {
+		child, err := key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// PrivateKey returns the secp256k1 private key of the extended key
+func (ek *ExtendedKey) PrivateKey() *secp256k1.PrivateKey {
+	return secp256k1.NewPrivateKey(&ek.key)
+}
@@ -0,0 +1,85 @@
+package keys
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP-32 test vectors restricted to their hardened-only paths, the only ones the
+// pool derives
+func TestDerivationVectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      string
+		path      []uint32
+		privKey   string
+		chainCode string
+	}{
+		{
+			name:      "vector 1 m",
+			seed:      "000102030405060708090a0b0c0d0e0f",
+			privKey:   "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			chainCode: "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+		},
+		{
+			name:      "vector 1 m/0'",
+			seed:      "000102030405060708090a0b0c0d0e0f",
+			path:      []uint32{HardenedIndex(0)},
+			privKey:   "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+			chainCode: "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141",
+		},
+		{
+			// The master key starts with a zero byte, which must be kept when
+			// serialized into the child's HMAC input
+			name:      "vector 3 m",
+			seed:      "4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be",
+			privKey:   "00ddb80b067e0d4993197fe10f2657a844a384589847602d56f0c629c81aae32",
+			chainCode: "01d28a3e53cffa419ec122c968b3259e16b65076495494d97cae10bbfec3c36f",
+		},
+		{
+			name:      "vector 3 m/0'",
+			seed:      "4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be",
+			path:      []uint32{HardenedIndex(0)},
+			privKey:   "491f7a2eebc7b57028e0d3faa0acda02e75c33b03c48fb288c41e2ea44e1daef",
+			chainCode: "e5fea12a97b927fc9dc3d2cb0d1ea1cf50aa5a1fdc1f933e8906bb38df3377bd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seed, err := hex.DecodeString(tt.seed)
+			if err != nil {
+				t.Fatalf("invalid seed: %v", err)
+			}
+			master, err := NewMaster(seed)
+			if err != nil {
+				t.Fatalf("NewMaster: %v", err)
+			}
+			key, err := master.DerivePath(tt.path...)
+			if err != nil {
+				t.Fatalf("DerivePath: %v", err)
+			}
+
+			privKey := key.PrivateKey().Serialize()
+			if got := hex.EncodeToString(privKey); got != tt.privKey {
+				t.Errorf("private key %s, want %s", got, tt.privKey)
+			}
+			if got := hex.EncodeToString(key.chainCode[:]); got != tt.chainCode {
+				t.Errorf("chain code %s, want %s", got, tt.chainCode)
+			}
+		})
+	}
+}
+
+func TestChildRejectsNonHardenedIndex(t *testing.T) {
+	master, err := NewMaster(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	if _, err := master.Child(1); err == nil {
+		t.Error("derived a non-hardened child")
+	}
+	if _, err := master.DerivePath(HardenedIndex(0), 1); err == nil {
+		t.Error("derived a path with a non-hardened step")
+	}
+}
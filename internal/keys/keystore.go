@@ -0,0 +1,287 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const (
+	// keystoreVersion is the on-disk format version
+	keystoreVersion = 1
+
+	// kdfIterations is the PBKDF2-SHA256 work factor protecting the seed
+	kdfIterations = 600000
+
+	// seedSize is the size of the random master seed
+	seedSize = 32
+)
+
+// Derivation branches below the master key (all hardened)
+const (
+	operatorBranch = 0
+	serverBranch   = 1
+	treasuryBranch = 2
+)
+
+// ErrWrongPassphrase is returned when the keystore cannot be decrypted
+var ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+
+// keystoreFile is the on-disk representation of a keystore
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+
+	// NextOperatorIndex is the next unused channel key index. It is not secret.
+	NextOperatorIndex uint32 `json:"next_operator_index"`
+}
+
+// Keystore holds the pool operator's master key, encrypted at rest, and derives
+// a separate operator key for every channel
+type Keystore struct {
+	path   string
+	file   keystoreFile
+	master *ExtendedKey
+	mu     sync.Mutex
+}
+
+// Open loads the keystore at path, creating it with a fresh master seed if it does
+// not exist yet. The seed is encrypted with AES-256-GCM under a key derived from
+// passphrase with PBKDF2-SHA256.
+func Open(path string, passphrase string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return create(path, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore: %w", err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+
+	seed, err := decryptSeed(&file, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keystore{
+		path:   path,
+		file:   file,
+		master: master,
+	}, nil
+}
+
+// create generates a new master seed and writes it encrypted to path
+func create(path string, passphrase string) (*Keystore, error) {
+	seed := make([]byte, seedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate master seed: %w", err)
+	}
+
+	file, err := encryptSeed(seed, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &Keystore{
+		path:   path,
+		file:   *file,
+		master: master,
+	}
+	if err := ks.save(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// OperatorKey derives the operator key of the channel with the given index (m/0'/index')
+func (ks *Keystore) OperatorKey(index uint32) (*secp256k1.PrivateKey, error) {
+	return ks.derive(operatorBranch, index)
+}
+
+// ReserveOperatorIndex reserves the next unused channel key index. The index is
+// persisted before it is returned, so a key is never handed out twice across restarts.
+func (ks *Keystore) ReserveOperatorIndex() (uint32, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	index := ks.file.NextOperatorIndex
+	ks.file.NextOperatorIndex++
+	if err := ks.save(); err != nil {
+		ks.file.NextOperatorIndex--
+		return 0, err
+	}
+
+	return index, nil
+}
+
+// ReleaseOperatorIndex hands back an index whose channel was never created, so the
+// next channel takes it. Only the most recently reserved index can be released;
+// any other stays burned, since a later one may already be in use.
+func (ks *Keystore) ReleaseOperatorIndex(index uint32) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if index+1 != ks.file.NextOperatorIndex {
+		return nil
+	}
+	ks.file.NextOperatorIndex--
+	if err := ks.save(); err != nil {
+		ks.file.NextOperatorIndex++
+		return err
+	}
+
+	return nil
+}
+
+// ServerKey derives the stand-in Ark server key (m/1'/0'), so it stays the same
+// across restarts until the pool talks to a real Ark server
+func (ks *Keystore) ServerKey() (*secp256k1.PrivateKey, error) {
+	return ks.derive(serverBranch, 0)
+}
+
+// TreasuryKey derives the key holding the operator's boarded treasury VTXOs
+// (m/2'/0')
+func (ks *Keystore) TreasuryKey() (*secp256k1.PrivateKey, error) {
+	return ks.derive(treasuryBranch, 0)
+}
+
+// derive returns the private key at m/branch'/index'
+func (ks *Keystore) derive(branch, index uint32) (*secp256k1.PrivateKey, error) {
+	child, err := ks.master.DerivePath(HardenedIndex(branch), HardenedIndex(index))
+	if err != nil {
+		return nil, err
+	}
+	return child.PrivateKey(), nil
+}
+
+// save writes the keystore file atomically with owner-only permissions
+func (ks *Keystore) save() error {
+	data, err := json.MarshalIndent(ks.file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keystore: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ks.path), 0700); err != nil {
+		return fmt.Errorf("failed to create keystore directory: %w", err)
+	}
+
+	tmp := ks.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+	if err := os.Rename(tmp, ks.path); err != nil {
+		return fmt.Errorf("failed to replace keystore: %w", err)
+	}
+
+	return nil
+}
+
+// encryptSeed encrypts a master seed under passphrase
+func encryptSeed(seed []byte, passphrase string) (*keystoreFile, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newCipher(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &keystoreFile{
+		Version:    keystoreVersion,
+		KDF:        "pbkdf2-sha256",
+		Iterations: kdfIterations,
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(gcm.Seal(nil, nonce, seed, nil)),
+	}, nil
+}
+
+// decryptSeed decrypts the master seed of a keystore file
+func decryptSeed(file *keystoreFile, passphrase string) ([]byte, error) {
+	if file.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported keystore kdf %q", file.KDF)
+	}
+
+	salt, err := hex.DecodeString(file.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(file.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(file.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+
+	gcm, err := newCipher(passphrase, salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce size")
+	}
+
+	seed, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return seed, nil
+}
+
+// newCipher derives the AES-256-GCM cipher protecting the seed
+func newCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keystore key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keystore cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,129 @@
+package keys
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool", "keystore.json")
+
+	ks, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("create keystore: %v", err)
+	}
+	first, err := ks.ReserveOperatorIndex()
+	if err != nil {
+		t.Fatalf("ReserveOperatorIndex: %v", err)
+	}
+	operatorKey, err := ks.OperatorKey(first)
+	if err != nil {
+		t.Fatalf("OperatorKey: %v", err)
+	}
+	serverKey, err := ks.ServerKey()
+	if err != nil {
+		t.Fatalf("ServerKey: %v", err)
+	}
+	if operatorKey.Key.Equals(&serverKey.Key) {
+		t.Error("operator and server keys are the same")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat keystore: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("keystore has permissions %o, want 600", perm)
+	}
+
+	reopened, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("reopen keystore: %v", err)
+	}
+	again, err := reopened.OperatorKey(first)
+	if err != nil {
+		t.Fatalf("OperatorKey: %v", err)
+	}
+	if !again.Key.Equals(&operatorKey.Key) {
+		t.Error("operator key changed across reopening")
+	}
+	server, err := reopened.ServerKey()
+	if err != nil {
+		t.Fatalf("ServerKey: %v", err)
+	}
+	if !server.Key.Equals(&serverKey.Key) {
+		t.Error("server key changed across reopening")
+	}
+
+	// Reserved indexes are persisted, so none is handed out twice
+	next, err := reopened.ReserveOperatorIndex()
+	if err != nil {
+		t.Fatalf("ReserveOperatorIndex: %v", err)
+	}
+	if next != first+1 {
+		t.Errorf("reserved index %d after reopening, want %d", next, first+1)
+	}
+}
+
+func TestKeystoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if _, err := Open(path, "correct horse"); err != nil {
+		t.Fatalf("create keystore: %v", err)
+	}
+
+	if _, err := Open(path, "battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestKeystoreRejectsTamperedCiphertext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	ks, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("create keystore: %v", err)
+	}
+
+	// Flip a bit of the encrypted seed; GCM must refuse to decrypt it
+	ciphertext := []byte(ks.file.Ciphertext)
+	if ciphertext[0] == '0' {
+		ciphertext[0] = '1'
+	} else {
+		ciphertext[0] = '0'
+	}
+	ks.file.Ciphertext = string(ciphertext)
+	if err := ks.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := Open(path, "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestReleaseOperatorIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	ks, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("create keystore: %v", err)
+	}
+
+	first, _ := ks.ReserveOperatorIndex()
+	second, _ := ks.ReserveOperatorIndex()
+
+	// Only the latest index can be handed back, and the release is persisted
+	if err := ks.ReleaseOperatorIndex(first); err != nil {
+		t.Fatalf("ReleaseOperatorIndex: %v", err)
+	}
+	if err := ks.ReleaseOperatorIndex(second); err != nil {
+		t.Fatalf("ReleaseOperatorIndex: %v", err)
+	}
+	reopened, err := Open(path, "correct horse")
+	if err != nil {
+		t.Fatalf("reopen keystore: %v", err)
+	}
+	if next, _ := reopened.ReserveOperatorIndex(); next != second {
+		t.Errorf("reserved index %d after releasing %d", next, second)
+	}
+}
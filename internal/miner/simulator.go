@@ -6,23 +6,36 @@ import (
 	"encoding/hex"
 	"fmt"
 	mathrand "math/rand"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/internal/watchtower"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
 	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
 // Simulator simulates a Bitcoin miner
 type Simulator struct {
-	ID           string
-	Name         string
-	Address      string
-	HashRate     float64
-	IsMining     bool
-	mu           sync.RWMutex
-	ctx          context.Context
-	cancel       context.CancelFunc
-	stats        *MiningStats
+	ID          string
+	Name        string
+	Address     string
+	HashRate    float64
+	IsMining    bool
+	pool        Pool
+	channelID   string
+	minerKey    *secp256k1.PrivateKey
+	operatorKey []byte
+	latestState *types.PaymentUpdate
+	withdrawing []uint64
+	tower       Watchtower
+	mu          sync.RWMutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	stats       *MiningStats
 }
 
 // MiningStats tracks mining statistics
@@ -33,12 +46,18 @@ type MiningStats struct {
 	LastShareTime  time.Time
 	Uptime         time.Duration
 	StartTime      time.Time
+	VerifiedStates uint64
+	RejectedStates uint64
+
+	// States registered with the miner's watchtower, and those it did not take
+	TowerRegistrations uint64
+	TowerFailures      uint64
 }
 
 // NewSimulator creates a new miner simulator
 func NewSimulator(name, address string, hashRate float64) *Simulator {
 	ctx, cancel := context.WithCancel(context.Background())
-	
+
 	return &Simulator{
 		ID:       generateID(),
 		Name:     name,
@@ -103,19 +122,27 @@ func (ms *Simulator) miningLoop() {
 
 // submitShare simulates submitting a mining share
 func (ms *Simulator) submitShare() {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
+	ms.mu.RLock()
+	mining := ms.IsMining
+	ms.mu.RUnlock()
 
-	if !ms.IsMining {
+	if !mining {
 		return
 	}
 
+	// Simulate share acceptance/rejection (90% acceptance rate). The pool is
+	// called without holding the simulator lock, as the pool calls back into
+	// simulators when it delivers signed channel states.
+	accepted := mathrand.Float64() < 0.9 && ms.reportShare() == nil
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
 	// Simulate share submission
 	ms.stats.TotalShares++
 	ms.stats.LastShareTime = time.Now()
 
-	// Simulate share acceptance/rejection (90% acceptance rate)
-	if mathrand.Float64() < 0.9 {
+	if accepted {
 		ms.stats.AcceptedShares++
 	} else {
 		ms.stats.RejectedShares++
@@ -125,6 +152,190 @@ func (ms *Simulator) submitShare() {
 	ms.stats.Uptime = time.Since(ms.stats.StartTime)
 }
 
+// reportShare forwards an accepted share to the pool for payout accounting
+func (ms *Simulator) reportShare() error {
+	if ms.pool == nil {
+		return nil
+	}
+
+	// Vardiff targets one share per second, so share difficulty tracks hash rate
+	return ms.pool.SubmitShare(ms.ID, ms.GetHashRate())
+}
+
+// ReceivePaymentUpdate verifies a signed channel state from the pool and keeps it
+// as the latest state the miner can prove it is owed. States signed by another
+// key, for another channel, or older than the one already held are rejected.
+func (ms *Simulator) ReceivePaymentUpdate(update *types.PaymentUpdate) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// The state may already have arrived with the channel's settlement
+	if ms.latestState != nil && update.ID == ms.latestState.ID {
+		return nil
+	}
+
+	err := ms.checkPaymentUpdate(update)
+	if err != nil {
+		ms.stats.RejectedStates++
+		return err
+	}
+
+	if ms.latestState != nil && update.MinerBalance < ms.latestState.MinerBalance {
+		ms.removeWithdrawing(update.Amount)
+	}
+	ms.adoptState(update)
+	return nil
+}
+
+// adoptState makes a verified state the latest one and hands the state it revokes
+// to the miner's watchtower, if it has one
+func (ms *Simulator) adoptState(update *types.PaymentUpdate) {
+	revoked := ms.latestState
+	ms.latestState = update
+	ms.stats.VerifiedStates++
+
+	if ms.tower == nil {
+		return
+	}
+	reg, err := watchtower.NewRegistration(ms.channelID, revoked, update)
+	if err == nil {
+		err = ms.tower.Register(reg)
+	}
+	if err != nil {
+		ms.stats.TowerFailures++
+		return
+	}
+	ms.stats.TowerRegistrations++
+}
+
+// SetWatchtower sets the tower the miner registers its revoked states with
+func (ms *Simulator) SetWatchtower(tower Watchtower) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.tower = tower
+}
+
+// checkPaymentUpdate validates an update against the miner's own view of its channel
+func (ms *Simulator) checkPaymentUpdate(update *types.PaymentUpdate) error {
+	if update.ChannelID != ms.channelID {
+		return fmt.Errorf("update is for channel %s, not %s", update.ChannelID, ms.channelID)
+	}
+
+	if err := channel.VerifyPaymentUpdate(update, ms.operatorKey); err != nil {
+		return err
+	}
+
+	if ms.latestState != nil {
+		if update.SequenceNum <= ms.latestState.SequenceNum {
+			return fmt.Errorf("stale state %d, already hold %d", update.SequenceNum, ms.latestState.SequenceNum)
+		}
+		// Only a withdrawal the miner asked for may lower its balance, and only by
+		// the amount it asked for
+		if update.MinerBalance < ms.latestState.MinerBalance {
+			withdrawn := ms.latestState.MinerBalance - update.MinerBalance
+			if update.ToParty != channel.PartyOnchain || update.Amount != withdrawn || !slices.Contains(ms.withdrawing, withdrawn) {
+				return fmt.Errorf("state lowers miner balance from %d to %d", ms.latestState.MinerBalance, update.MinerBalance)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Withdraw asks the pool to off-board amount of the miner's channel earnings to its
+// on-chain address, or everything its latest state holds when amount is 0. The
+// miner then accepts the state that shrinks its side by that amount.
+func (ms *Simulator) Withdraw(ctx context.Context, amount uint64) (*types.Withdrawal, error) {
+	ms.mu.Lock()
+	if amount == 0 && ms.latestState != nil {
+		amount = ms.latestState.MinerBalance
+	}
+	if amount == 0 {
+		ms.mu.Unlock()
+		return nil, fmt.Errorf("miner has no channel balance to withdraw")
+	}
+	ms.withdrawing = append(ms.withdrawing, amount)
+	ms.mu.Unlock()
+
+	// The pool may ask the miner to co-sign while it handles the withdrawal, so
+	// the lock is not held across the call
+	withdrawal, err := ms.pool.WithdrawMiner(ctx, ms.ID, amount)
+	if err != nil {
+		ms.mu.Lock()
+		ms.removeWithdrawing(amount)
+		ms.mu.Unlock()
+		return nil, err
+	}
+	return withdrawal, nil
+}
+
+// removeWithdrawing forgets one withdrawal of amount the miner asked for
+func (ms *Simulator) removeWithdrawing(amount uint64) {
+	if i := slices.Index(ms.withdrawing, amount); i >= 0 {
+		ms.withdrawing = slices.Delete(ms.withdrawing, i, i+1)
+	}
+}
+
+// SignSettlement co-signs the cooperative settlement of the miner's channel after
+// checking that it pays the miner everything its latest signed state promises
+func (ms *Simulator) SignSettlement(settlement *types.Settlement, digest [32]byte) ([]byte, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.minerKey == nil {
+		return nil, fmt.Errorf("miner key is not held by the simulator")
+	}
+	if settlement.ChannelID != ms.channelID {
+		return nil, fmt.Errorf("settlement is for channel %s, not %s", settlement.ChannelID, ms.channelID)
+	}
+
+	// The final state can reach the miner with the settlement before it is delivered
+	if final := settlement.FinalState; final != nil {
+		if ms.latestState == nil || final.SequenceNum > ms.latestState.SequenceNum {
+			if err := ms.checkPaymentUpdate(final); err != nil {
+				ms.stats.RejectedStates++
+				return nil, fmt.Errorf("final state: %w", err)
+			}
+			ms.adoptState(final)
+		}
+	}
+
+	owed := uint64(0)
+	if ms.latestState != nil {
+		owed = ms.latestState.MinerBalance
+	}
+	if settlement.MinerAmount != owed {
+		return nil, fmt.Errorf("settlement pays %d sats, latest state owes %d", settlement.MinerAmount, owed)
+	}
+
+	paid := uint64(0)
+	for _, output := range settlement.Transaction.Outputs {
+		if output.Address == ms.Address {
+			paid += output.Amount
+		}
+	}
+	if paid != owed {
+		return nil, fmt.Errorf("settlement sends %d sats to %s, latest state owes %d", paid, ms.Address, owed)
+	}
+
+	expected, err := channel.SettlementDigest(settlement.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	if expected != digest {
+		return nil, fmt.Errorf("settlement digest does not match its transaction")
+	}
+
+	return schnorr.Sign(ms.minerKey, digest[:])
+}
+
+// LatestState returns the newest verified channel state held by the miner
+func (ms *Simulator) LatestState() *types.PaymentUpdate {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.latestState
+}
+
 // GetStats returns current mining statistics
 func (ms *Simulator) GetStats() *MiningStats {
 	ms.mu.RLock()
@@ -183,41 +394,154 @@ func generateID() string {
 
 // Pool represents a mining pool interface
 type Pool interface {
-	AddMiner(ctx context.Context, minerName, minerAddress string, hashRate float64) (*types.Miner, error)
-	ProcessBlockReward(ctx context.Context) (*types.BlockReward, error)
+	NewJoinChallenge() (*types.JoinChallenge, error)
+	AddMiner(ctx context.Context, req types.JoinPoolRequest) (*types.Miner, error)
+	ProcessBlockReward(ctx context.Context, req types.ProcessBlockRequest) (*types.BlockReward, error)
+	SubmitShare(minerID string, difficulty float64) error
 	GetPoolStats() *types.MiningStats
+	GetChannel(channelID string) (*types.Channel, bool)
+	OnPaymentUpdate(observer func(minerID string, update *types.PaymentUpdate))
+	WithdrawMiner(ctx context.Context, minerID string, amount uint64) (*types.Withdrawal, error)
+	SetMinerCosigner(cosigner func(minerID string, settlement *types.Settlement, digest [32]byte) ([]byte, error))
+}
+
+// Watchtower watches a miner's channel for stale exits while the miner is offline
+type Watchtower interface {
+	Register(reg *types.WatchtowerRegistration) error
 }
 
 // Manager manages multiple miner simulators
 type Manager struct {
 	simulators map[string]*Simulator
 	pool       Pool
+	tower      Watchtower
 	mu         sync.RWMutex
 }
 
 // NewManager creates a new miner manager
 func NewManager(pool Pool) *Manager {
-	return &Manager{
+	mm := &Manager{
 		simulators: make(map[string]*Simulator),
 		pool:       pool,
 	}
+
+	// Every signed state the pool sends is checked by the miner it is meant for
+	pool.OnPaymentUpdate(mm.deliverPaymentUpdate)
+
+	// Simulated miners co-sign the settlement when their channel closes
+	pool.SetMinerCosigner(mm.signSettlement)
+
+	return mm
+}
+
+// deliverPaymentUpdate hands a signed channel state to the miner's simulator
+func (mm *Manager) deliverPaymentUpdate(minerID string, update *types.PaymentUpdate) {
+	simulator, exists := mm.GetSimulator(minerID)
+	if !exists {
+		return
+	}
+	simulator.ReceivePaymentUpdate(update)
+}
+
+// signSettlement asks a miner's simulator to co-sign its channel settlement
+func (mm *Manager) signSettlement(minerID string, settlement *types.Settlement, digest [32]byte) ([]byte, error) {
+	simulator, exists := mm.GetSimulator(minerID)
+	if !exists {
+		return nil, fmt.Errorf("miner %s is not simulated", minerID)
+	}
+	return simulator.SignSettlement(settlement, digest)
+}
+
+// Withdraw off-boards amount of a miner's channel earnings to its on-chain address,
+// everything on its side when amount is 0. A simulated miner asks for the
+// withdrawal itself, so it accepts the state that pays it out.
+func (mm *Manager) Withdraw(ctx context.Context, minerID string, amount uint64) (*types.Withdrawal, error) {
+	simulator, exists := mm.GetSimulator(minerID)
+	if !exists {
+		return mm.pool.WithdrawMiner(ctx, minerID, amount)
+	}
+	return simulator.Withdraw(ctx, amount)
 }
 
-// AddSimulator adds a new miner simulator
-func (mm *Manager) AddSimulator(name, address string, hashRate float64) (*Simulator, error) {
+// SetWatchtower has every simulated miner, current and future, register its
+// revoked states with tower
+func (mm *Manager) SetWatchtower(tower Watchtower) {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
 
-	simulator := NewSimulator(name, address, hashRate)
-	mm.simulators[simulator.ID] = simulator
+	mm.tower = tower
+	for _, simulator := range mm.simulators {
+		simulator.SetWatchtower(tower)
+	}
+}
+
+// AddSimulator adds a simulator for a miner that joined with its own key and
+// proof of possession
+func (mm *Manager) AddSimulator(req types.JoinPoolRequest) (*Simulator, error) {
+	return mm.addSimulator(req, nil)
+}
+
+// SimulateMiner adds a simulated miner that generates its own channel key and
+// answers the pool's join challenge with it, like a real miner would
+func (mm *Manager) SimulateMiner(name, address string, hashRate float64) (*Simulator, error) {
+	minerKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate miner key: %w", err)
+	}
+
+	challenge, err := mm.pool.NewJoinChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join challenge: %w", err)
+	}
+	challengeBytes, err := hex.DecodeString(challenge.Challenge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid join challenge: %w", err)
+	}
+
+	digest := pool.JoinDigest(challengeBytes, minerKey.PubKey())
+	sig, err := schnorr.Sign(minerKey, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign join challenge: %w", err)
+	}
+
+	return mm.addSimulator(types.JoinPoolRequest{
+		MinerName: name,
+		Address:   address,
+		HashRate:  hashRate,
+		PubKey:    hex.EncodeToString(minerKey.PubKey().SerializeCompressed()),
+		Challenge: challenge.Challenge,
+		Signature: hex.EncodeToString(sig),
+	}, minerKey)
+}
 
+// addSimulator joins the pool and tracks a simulator for the new miner. The miner
+// key is only known for miners simulated here.
+func (mm *Manager) addSimulator(req types.JoinPoolRequest, minerKey *secp256k1.PrivateKey) (*Simulator, error) {
 	// Add to pool
-	_, err := mm.pool.AddMiner(context.Background(), name, address, hashRate)
+	miner, err := mm.pool.AddMiner(context.Background(), req)
 	if err != nil {
-		delete(mm.simulators, simulator.ID)
 		return nil, fmt.Errorf("failed to add miner to pool: %w", err)
 	}
 
+	channel, exists := mm.pool.GetChannel(miner.ChannelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found for miner %s", miner.ID)
+	}
+
+	// Share the pool's miner ID so submitted shares are credited correctly, and pin
+	// the operator key the channel was opened with to verify later states
+	simulator := NewSimulator(req.MinerName, req.Address, req.HashRate)
+	simulator.ID = miner.ID
+	simulator.pool = mm.pool
+	simulator.channelID = channel.ID
+	simulator.minerKey = minerKey
+	simulator.operatorKey = schnorr.SerializePubKey(channel.PoolOperatorKey)
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	simulator.tower = mm.tower
+	mm.simulators[simulator.ID] = simulator
+
 	return simulator, nil
 }
 
@@ -268,4 +592,4 @@ func (mm *Manager) StopAllSimulators() error {
 	}
 
 	return nil
-} 
\ No newline at end of file
+}
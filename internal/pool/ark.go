@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// ArkClient is how the pool talks to its Ark service provider (ASP). Calls are made
+// with the pool lock held and must not call back into the pool.
+type ArkClient interface {
+	// GetInfo returns the server's public key and how long its VTXOs live
+	GetInfo(ctx context.Context) (*types.ArkInfo, error)
+
+	// Board settles on-chain UTXOs into a VTXO held at address
+	Board(ctx context.Context, utxos []*types.UTXO, address string) (*types.VTXO, error)
+
+	// RegisterIntent registers VTXOs to spend and outputs to create in the next
+	// round and returns the intent's ID
+	RegisterIntent(ctx context.Context, intent *types.ArkIntent) (string, error)
+
+	// DeleteIntent withdraws a registered intent that has not joined a round
+	DeleteIntent(ctx context.Context, intentID string) error
+
+	// JoinRound batches registered intents into a round and returns the VTXOs it
+	// will issue
+	JoinRound(ctx context.Context, intentIDs []string) (*types.ArkRound, error)
+
+	// AbortRound fails a joined round before its forfeits are submitted, so the
+	// VTXOs it spends can be registered again
+	AbortRound(ctx context.Context, roundID string) error
+
+	// SubmitForfeits gives up the VTXOs a round spends, which finalizes it
+	SubmitForfeits(ctx context.Context, roundID string, forfeits []*types.ArkForfeit) error
+
+	// GetVTXOs returns the unspent VTXOs held at an address
+	GetVTXOs(ctx context.Context, address string) ([]*types.VTXO, error)
+
+	// CosignSettlement signs a channel settlement as the server
+	CosignSettlement(ctx context.Context, settlement *types.Settlement, digest [32]byte) ([]byte, error)
+}
+
+// arkCosigner signs settlements through the Ark server
+func arkCosigner(client ArkClient) channel.Cosigner {
+	return func(settlement *types.Settlement, digest [32]byte) ([]byte, error) {
+		return client.CosignSettlement(context.Background(), settlement, digest)
+	}
+}
+
+// settleRound settles a signed round with the Ark server: every intent is
+// registered, the intents join one server round, and the VTXOs they spend are
+// forfeited with the channels' operator keys. Each output then records the VTXO
+// the server issued for it. A round joined but not forfeited is aborted.
+func (pm *Manager) settleRound(sealed *types.Round) error {
+	ctx := context.Background()
+
+	intentIDs := make([]string, 0, len(sealed.Intents))
+	for _, intent := range sealed.Intents {
+		arkIntentID, err := pm.ark.RegisterIntent(ctx, &types.ArkIntent{
+			Inputs:  intent.Inputs,
+			Outputs: intentOutputs(sealed, intent.ID),
+		})
+		if err != nil {
+			pm.withdrawIntents(ctx, intentIDs)
+			return fmt.Errorf("failed to register intent %s: %w", intent.ID, err)
+		}
+		intentIDs = append(intentIDs, arkIntentID)
+	}
+
+	arkRound, err := pm.ark.JoinRound(ctx, intentIDs)
+	if err != nil {
+		pm.withdrawIntents(ctx, intentIDs)
+		return fmt.Errorf("failed to join round: %w", err)
+	}
+	if len(arkRound.VTXOs) != len(sealed.Outputs) {
+		pm.abortRound(ctx, arkRound.ID)
+		return fmt.Errorf("round %s issues %d VTXOs for %d outputs", arkRound.ID, len(arkRound.VTXOs), len(sealed.Outputs))
+	}
+
+	forfeits, err := pm.signForfeits(sealed, arkRound.ID)
+	if err != nil {
+		pm.abortRound(ctx, arkRound.ID)
+		return err
+	}
+	if err := pm.ark.SubmitForfeits(ctx, arkRound.ID, forfeits); err != nil {
+		return fmt.Errorf("failed to forfeit the VTXOs of round %s: %w", arkRound.ID, err)
+	}
+
+	sealed.ArkRoundID = arkRound.ID
+	sealed.CommitmentTxID = arkRound.CommitmentTxID
+	for i, output := range sealed.Outputs {
+		output.VTXO = arkRound.VTXOs[i]
+	}
+
+	return nil
+}
+
+// signForfeits signs the forfeit of every VTXO a round's intents spend with the
+// operator keys of their channels
+func (pm *Manager) signForfeits(sealed *types.Round, arkRoundID string) ([]*types.ArkForfeit, error) {
+	forfeits := make([]*types.ArkForfeit, 0)
+	for _, intent := range sealed.Intents {
+		ch, exists := pm.findChannel(intent.ChannelID)
+		if !exists {
+			return nil, fmt.Errorf("channel %s not found", intent.ChannelID)
+		}
+		for _, input := range intent.Inputs {
+			forfeit, err := pm.channelManager.SignForfeit(ch, input, arkRoundID)
+			if err != nil {
+				return nil, err
+			}
+			forfeits = append(forfeits, forfeit)
+		}
+	}
+	return forfeits, nil
+}
+
+// abortRound fails a joined round the pool cannot forfeit, so its intents can
+// settle in a later one
+func (pm *Manager) abortRound(ctx context.Context, arkRoundID string) {
+	if err := pm.ark.AbortRound(ctx, arkRoundID); err != nil {
+		pm.logger.Warnf("Failed to abort Ark round %s: %v", arkRoundID, err)
+	}
+}
+
+// withdrawIntents deletes intents registered for a round that will not be joined,
+// so their VTXOs can be registered again
+func (pm *Manager) withdrawIntents(ctx context.Context, intentIDs []string) {
+	for _, intentID := range intentIDs {
+		pm.ark.DeleteIntent(ctx, intentID)
+	}
+}
+
+// intentOutputs returns a round's outputs belonging to one intent
+func intentOutputs(sealed *types.Round, intentID string) []*types.RoundOutput {
+	outputs := make([]*types.RoundOutput, 0)
+	for _, output := range sealed.Outputs {
+		if output.IntentID == intentID {
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs
+}
+
+// GetChannelVTXOs asks the Ark server for the unspent VTXOs held at a channel's
+// address
+func (pm *Manager) GetChannelVTXOs(ctx context.Context, channelID string) ([]*types.VTXO, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	ch, exists := pm.findChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+	return pm.ark.GetVTXOs(ctx, ch.TaprootAddress)
+}
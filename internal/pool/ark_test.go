@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/ark"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// shortRoundASP is a mock Ark server whose rounds issue one VTXO too few
+type shortRoundASP struct {
+	*ark.MockASP
+	joined []string
+}
+
+func (s *shortRoundASP) JoinRound(ctx context.Context, intentIDs []string) (*types.ArkRound, error) {
+	arkRound, err := s.MockASP.JoinRound(ctx, intentIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.joined = append(s.joined, arkRound.ID)
+	short := *arkRound
+	short.VTXOs = arkRound.VTXOs[:len(arkRound.VTXOs)-1]
+	return &short, nil
+}
+
+func TestUnforfeitableRoundIsAborted(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	ctx := context.Background()
+
+	mock := pm.ark.(*ark.MockASP)
+	short := &shortRoundASP{MockASP: mock}
+	pm.ark = short
+	if _, err := pm.WithdrawMiner(ctx, ch.MinerID, 0); err == nil {
+		t.Fatal("withdrawal settled in a round missing a VTXO")
+	}
+	if len(short.joined) != 1 {
+		t.Fatalf("joined %d rounds, want 1", len(short.joined))
+	}
+	if aborted, _ := mock.GetRound(short.joined[0]); aborted.Status != ark.RoundFailed {
+		t.Errorf("round is %s, want %s", aborted.Status, ark.RoundFailed)
+	}
+
+	// The aborted round freed the channel's VTXO for the next one
+	pm.ark = mock
+	if _, err := pm.WithdrawMiner(ctx, ch.MinerID, 0); err != nil {
+		t.Fatalf("WithdrawMiner after the aborted round: %v", err)
+	}
+}
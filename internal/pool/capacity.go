@@ -0,0 +1,146 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Default channel capacity settings
+const (
+	DefaultCapacityBlocks = 12
+	DefaultLowWaterBlocks = 3
+	DefaultMinCapacity    = 1000000 // 0.01 BTC
+)
+
+// CapacityConfig sizes channels by how much their miner is expected to earn
+type CapacityConfig struct {
+	// Blocks is how many blocks of expected earnings a channel is funded for
+	Blocks uint64
+
+	// LowWaterBlocks triggers a top-up once a channel can cover fewer blocks than this
+	LowWaterBlocks uint64
+
+	// MinCapacity is the smallest amount a channel is ever funded with
+	MinCapacity uint64
+}
+
+// withDefaults fills in unset capacity settings
+func (cc CapacityConfig) withDefaults() CapacityConfig {
+	if cc.Blocks == 0 {
+		cc.Blocks = DefaultCapacityBlocks
+	}
+	if cc.LowWaterBlocks == 0 {
+		cc.LowWaterBlocks = DefaultLowWaterBlocks
+	}
+	if cc.MinCapacity == 0 {
+		cc.MinCapacity = DefaultMinCapacity
+	}
+	return cc
+}
+
+// expectedEarnings returns how much a miner with hashRate is expected to earn per
+// block when the pool's total hash rate is totalHashRate
+func (pm *Manager) expectedEarnings(hashRate, totalHashRate float64) uint64 {
+	if hashRate <= 0 || totalHashRate <= 0 {
+		return 0
+	}
+
+	blockValue := BlockSubsidy(pm.blockHeight+1) + pm.feeModel.MeanFees
+	return uint64(math.Ceil(float64(blockValue) * math.Min(hashRate/totalHashRate, 1)))
+}
+
+// targetCapacity returns the channel capacity for a miner with the given hash rate
+func (pm *Manager) targetCapacity(hashRate, totalHashRate float64) uint64 {
+	capacity := pm.expectedEarnings(hashRate, totalHashRate) * pm.capacity.Blocks
+	if capacity < pm.capacity.MinCapacity {
+		return pm.capacity.MinCapacity
+	}
+	return capacity
+}
+
+// ensureCapacity tops up a channel that could not cover an upcoming payout.
+// Top-ups are best effort: a channel that cannot be topped up, say because the
+// treasury is short, is left as it is and its payment fails on its own. A top-up
+// settles in its own round, so it stays in the channel whatever the payment does.
+func (pm *Manager) ensureCapacity(miner *types.Miner, channel *types.Channel, amount uint64) {
+	if channel.CurrentBalance >= amount {
+		return
+	}
+
+	// Refill to a full capacity on top of the payment about to be made
+	shortfall := amount - channel.CurrentBalance
+	topUp := shortfall + pm.targetCapacity(miner.HashRate, pm.pool.TotalHashRate)
+	if err := pm.topUpChannel(channel, topUp, "payout exceeds capacity"); err != nil {
+		pm.logger.Warnf("Cannot top up channel %s to pay miner %s: %v", channel.ID, miner.ID, err)
+	}
+}
+
+// rebalanceChannels tops up every channel that is close to running out, based on
+// its miner's expected earnings rate. A channel that cannot be refilled keeps
+// paying until it runs dry.
+func (pm *Manager) rebalanceChannels() {
+	channelIDs := make([]string, 0, len(pm.pool.ActiveChannels))
+	for channelID := range pm.pool.ActiveChannels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		channel := pm.pool.ActiveChannels[channelID]
+		if channel.Status != types.ChannelOpen {
+			continue
+		}
+		miner, exists := pm.pool.Miners[channel.MinerID]
+		if !exists || !miner.IsActive {
+			continue
+		}
+
+		// Cover what the miner already has pending as well as what it will earn
+		expected := pm.expectedEarnings(miner.HashRate, pm.pool.TotalHashRate)
+		lowWater := miner.PendingBalance + expected*pm.capacity.LowWaterBlocks
+		if channel.CurrentBalance >= lowWater {
+			continue
+		}
+
+		target := miner.PendingBalance + pm.targetCapacity(miner.HashRate, pm.pool.TotalHashRate)
+		if target <= channel.CurrentBalance {
+			continue
+		}
+		// A short treasury is expected; anything else is worth a look
+		err := pm.topUpChannel(channel, target-channel.CurrentBalance, "approaching exhaustion")
+		if err != nil && !errors.Is(err, treasury.ErrInsufficientFunds) {
+			pm.logger.Errorf("Failed to refill channel %s: %v", channel.ID, err)
+		}
+	}
+}
+
+// topUpChannel funds a top-up of a channel from the treasury in an Ark round and
+// adds it to the channel's capacity
+func (pm *Manager) topUpChannel(ch *types.Channel, amount uint64, reason string) error {
+	ctx := context.Background()
+	if ch.Status != types.ChannelOpen {
+		return fmt.Errorf("failed to top up channel %s: %w", ch.ID, channel.ErrChannelNotOpen)
+	}
+	if err := pm.treasury.Fund(ctx, amount); err != nil {
+		return fmt.Errorf("cannot fund top-up of channel %s: %w", ch.ID, err)
+	}
+	if err := pm.treasury.Allocate(ch.ID, amount); err != nil {
+		return fmt.Errorf("cannot fund top-up of channel %s: %w", ch.ID, err)
+	}
+	vtxo, err := pm.fundChannel(ctx, ch, amount)
+	if err != nil {
+		pm.treasury.Deallocate(ch.ID, amount)
+		return fmt.Errorf("failed to top up channel %s: %w", ch.ID, err)
+	}
+	if err := pm.channelManager.TopUpChannel(ch, amount, reason, vtxo); err != nil {
+		return fmt.Errorf("failed to record top-up of channel %s: %w", ch.ID, err)
+	}
+	return nil
+}
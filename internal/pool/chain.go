@@ -0,0 +1,140 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Chain event types
+const (
+	ChainEventBlock = "block"
+	ChainEventExit  = "exit"
+)
+
+// ChainObserver receives every event on the pool's simulated chain
+type ChainObserver = func(event *types.ChainEvent)
+
+// OnChainEvent registers an observer for new blocks and exit broadcasts. Observers
+// are called in order from a single goroutine, never while the pool lock is held.
+func (pm *Manager) OnChainEvent(observer ChainObserver) {
+	pm.observersMu.Lock()
+	defer pm.observersMu.Unlock()
+	pm.chainObservers = append(pm.chainObservers, observer)
+}
+
+// AdvanceChain moves the chain tip to height for a block the pool did not find.
+// Nothing is paid out, but withdrawals gain confirmations and channels whose VTXO
+// has expired leave the pool.
+func (pm *Manager) AdvanceChain(height uint64) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.advanceTip(height)
+}
+
+// DisconnectBlock rewinds the chain tip below a block a reorganisation took off
+// the best chain. If the pool had distributed the block, its reward is orphaned:
+// the miners' unpaid credits and the operator's and donation fees are reversed.
+// What a channel has already paid a miner cannot be taken back, so it stays
+// earned. The orphaned reward is returned, or nil if the pool had not found the
+// block.
+func (pm *Manager) DisconnectBlock(height uint64) (*types.BlockReward, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if height == 0 || height > pm.blockHeight {
+		return nil, fmt.Errorf("block %d is not on the chain, tip %d", height, pm.blockHeight)
+	}
+	pm.blockHeight = height - 1
+	delete(pm.processedHeights, height)
+
+	var blockReward *types.BlockReward
+	for i := len(pm.blockRewards) - 1; i >= 0; i-- {
+		if pm.blockRewards[i].BlockHeight == height && pm.blockRewards[i].Status == BlockRewardDistributed {
+			blockReward = pm.blockRewards[i]
+			break
+		}
+	}
+	if blockReward == nil {
+		return nil, nil
+	}
+
+	// Take back what the miners have not been paid yet
+	reversed := uint64(0)
+	for minerID, amount := range blockReward.Distributions {
+		miner := pm.pool.Miners[minerID]
+		unpaid := min(amount, miner.PendingBalance)
+		miner.PendingBalance -= unpaid
+		miner.TotalEarned -= unpaid
+		reversed += unpaid
+	}
+	kept := blockReward.PaidOut - blockReward.OperatorFee - blockReward.Donation - reversed
+
+	pm.pool.OperatorFeesEarned -= blockReward.OperatorFee
+	pm.pool.DonationsPaid -= blockReward.Donation
+	pm.totalPaidOut -= blockReward.PaidOut - kept
+	pm.totalSubsidy -= blockReward.Subsidy
+	pm.totalTxFees -= blockReward.TxFees
+
+	blockReward.Status = BlockRewardOrphaned
+	if kept > 0 {
+		blockReward.Error = fmt.Sprintf("%d sats were already paid through channels", kept)
+		pm.logger.Warnf("Block %d was orphaned after channels paid %d sats of it", height, kept)
+	}
+	return blockReward, nil
+}
+
+// advanceTip records a block at height and applies what follows from the new tip.
+// The tip only moves forward, even when an older height is backfilled.
+func (pm *Manager) advanceTip(height uint64) error {
+	if height > pm.blockHeight {
+		pm.blockHeight = height
+		pm.notifyBlock(height)
+	}
+	pm.lastBlockTime = time.Now()
+
+	pm.trackWithdrawals()
+
+	// Channels left in VTXOs past their expiry can no longer be paid
+	return pm.expireChannels(pm.blockHeight)
+}
+
+// notifyBlock queues the new chain tip for delivery to observers
+func (pm *Manager) notifyBlock(height uint64) {
+	pm.chainNotices.push(&types.ChainEvent{
+		Type:      ChainEventBlock,
+		Height:    height,
+		Timestamp: time.Now(),
+	})
+}
+
+// notifyExit queues a channel's exit broadcast for delivery to observers. The exit
+// is copied since a challenge may replace its state later.
+func (pm *Manager) notifyExit(channel *types.Channel) {
+	exit := *channel.Exit
+	pm.chainNotices.push(&types.ChainEvent{
+		Type:      ChainEventExit,
+		Height:    exit.BroadcastHeight,
+		ChannelID: channel.ID,
+		Exit:      &exit,
+		Timestamp: time.Now(),
+	})
+}
+
+// deliverChainEvents hands queued chain events to observers
+func (pm *Manager) deliverChainEvents() {
+	for {
+		event, ok := pm.chainNotices.pop()
+		if !ok {
+			return
+		}
+		pm.observersMu.RLock()
+		observers := pm.chainObservers
+		pm.observersMu.RUnlock()
+
+		for _, observer := range observers {
+			observer(event)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestDisconnectedBlockIsOrphaned(t *testing.T) {
+	pm := newTestManager(t, Config{FeeBasisPoints: 100}, treasury.DefaultRegtestFunding)
+	paid := joinMiner(t, pm, 1e12)
+	unpaid := joinMiner(t, pm, 1e12)
+	pm.pool.ActiveChannels[unpaid.ChannelID].Status = types.ChannelClosingCooperative
+
+	reward, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{})
+	if err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+	if paid.CurrentBalance == 0 || unpaid.PendingBalance == 0 {
+		t.Fatalf("paid miner got %d, unpaid miner has %d pending", paid.CurrentBalance, unpaid.PendingBalance)
+	}
+
+	orphaned, err := pm.DisconnectBlock(reward.BlockHeight)
+	if err != nil {
+		t.Fatalf("DisconnectBlock: %v", err)
+	}
+	if orphaned != reward || reward.Status != BlockRewardOrphaned || reward.Error == "" {
+		t.Fatalf("block reward is %s (%q)", reward.Status, reward.Error)
+	}
+	if pm.BlockHeight() != reward.BlockHeight-1 {
+		t.Errorf("tip at %d, want %d", pm.BlockHeight(), reward.BlockHeight-1)
+	}
+
+	// The channel payment stands; the pending credit and the fees are gone
+	if paid.TotalEarned != paid.CurrentBalance {
+		t.Errorf("paid miner earned %d, paid %d", paid.TotalEarned, paid.CurrentBalance)
+	}
+	if unpaid.TotalEarned != 0 || unpaid.PendingBalance != 0 {
+		t.Errorf("unpaid miner earned %d with %d pending", unpaid.TotalEarned, unpaid.PendingBalance)
+	}
+	if pm.pool.OperatorFeesEarned != 0 || pm.pool.DonationsPaid != 0 {
+		t.Errorf("operator keeps %d sats of fees and %d of donations", pm.pool.OperatorFeesEarned, pm.pool.DonationsPaid)
+	}
+	if err := pm.checkLedger(&types.BlockReward{}); err != nil {
+		t.Errorf("ledger: %v", err)
+	}
+
+	// The block replacing it at the same height is paid out
+	replacement, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{})
+	if err != nil {
+		t.Fatalf("replacement block: %v", err)
+	}
+	if replacement.BlockHeight != reward.BlockHeight {
+		t.Errorf("replacement at height %d, want %d", replacement.BlockHeight, reward.BlockHeight)
+	}
+}
+
+func TestDisconnectingABlockAboveTheTipFails(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	if _, err := pm.DisconnectBlock(pm.BlockHeight() + 1); err == nil {
+		t.Error("disconnected a block above the tip")
+	}
+}
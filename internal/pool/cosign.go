@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// MinerCosigner asks a miner to sign the settlement of its channel. It is called
+// with the pool lock held and must not call back into the pool.
+type MinerCosigner = func(minerID string, settlement *types.Settlement, digest [32]byte) ([]byte, error)
+
+// SetMinerCosigner sets how miners are asked to co-sign channel settlements
+func (pm *Manager) SetMinerCosigner(cosigner MinerCosigner) {
+	pm.observersMu.Lock()
+	defer pm.observersMu.Unlock()
+	pm.minerCosigner = cosigner
+}
+
+// minerCosignerFor returns the settlement cosigner of a single miner
+func (pm *Manager) minerCosignerFor(minerID string) channel.Cosigner {
+	pm.observersMu.RLock()
+	cosigner := pm.minerCosigner
+	pm.observersMu.RUnlock()
+
+	if cosigner == nil {
+		return nil
+	}
+	return func(settlement *types.Settlement, digest [32]byte) ([]byte, error) {
+		return cosigner(minerID, settlement, digest)
+	}
+}
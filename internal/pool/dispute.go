@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DisputeObserver receives every dispute raised against a channel's exit
+type DisputeObserver = func(event *types.DisputeEvent)
+
+// OnDispute registers an observer for channel disputes. Observers are called in
+// order from a single goroutine, never while the pool lock is held.
+func (pm *Manager) OnDispute(observer DisputeObserver) {
+	pm.observersMu.Lock()
+	defer pm.observersMu.Unlock()
+	pm.disputeObservers = append(pm.disputeObservers, observer)
+}
+
+// GetDisputes returns every dispute raised in the pool, oldest first
+func (pm *Manager) GetDisputes() []*types.DisputeEvent {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	disputes := make([]*types.DisputeEvent, len(pm.disputes))
+	copy(disputes, pm.disputes)
+	return disputes
+}
+
+// GetChannelDisputes returns the disputes raised against a channel
+func (pm *Manager) GetChannelDisputes(channelID string) ([]*types.DisputeEvent, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	channel, exists := pm.findChannel(channelID)
+	if !exists {
+		return nil, false
+	}
+	return channel.Disputes, true
+}
+
+// recordDispute keeps a dispute and queues it for delivery to observers
+func (pm *Manager) recordDispute(event *types.DisputeEvent) {
+	pm.disputes = append(pm.disputes, event)
+	pm.disputeNotices.push(event)
+}
+
+// deliverDisputes hands queued disputes to observers
+func (pm *Manager) deliverDisputes() {
+	for {
+		event, ok := pm.disputeNotices.pop()
+		if !ok {
+			return
+		}
+		pm.observersMu.RLock()
+		observers := pm.disputeObservers
+		pm.observersMu.RUnlock()
+
+		for _, observer := range observers {
+			observer(event)
+		}
+	}
+}
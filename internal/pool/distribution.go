@@ -0,0 +1,123 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Block reward statuses
+const (
+	BlockRewardDistributed = "distributed"
+	BlockRewardRejected    = "rejected"
+	BlockRewardOrphaned    = "orphaned"
+)
+
+// creditMiners adds a block's distributions to the miners' internal ledgers
+func (pm *Manager) creditMiners(distributions map[string]uint64) {
+	for minerID, amount := range distributions {
+		miner := pm.pool.Miners[minerID]
+		miner.TotalEarned += amount
+		miner.PendingBalance += amount
+		miner.LastActivity = time.Now()
+	}
+}
+
+// uncreditMiners reverses creditMiners
+func (pm *Manager) uncreditMiners(distributions map[string]uint64) {
+	for minerID, amount := range distributions {
+		miner := pm.pool.Miners[minerID]
+		miner.TotalEarned -= amount
+		miner.PendingBalance -= amount
+	}
+}
+
+// dueMiners returns the miners whose pending balance should be pushed through their
+// channel. Without force only miners at or above their payout threshold are due.
+func (pm *Manager) dueMiners(force bool) map[string]uint64 {
+	due := make(map[string]uint64)
+	for minerID, miner := range pm.pool.Miners {
+		if miner.PendingBalance == 0 || !miner.IsActive {
+			continue
+		}
+		if force || miner.PendingBalance >= pm.payoutThreshold(miner) {
+			due[minerID] = miner.PendingBalance
+		}
+	}
+	return due
+}
+
+// payoutThreshold returns the pending balance at which a miner gets paid
+func (pm *Manager) payoutThreshold(miner *types.Miner) uint64 {
+	if miner.PayoutThreshold > 0 {
+		return miner.PayoutThreshold
+	}
+	return pm.defaultPayoutThreshold
+}
+
+// payOut pushes amount of a miner's pending balance through its channel, topping
+// the channel up first if it cannot take the payment. Signing the channel's new
+// state is the last step that can fail and changes nothing when it does, so a
+// failed payment leaves the miner owed what it was. A top-up that settled stays.
+func (pm *Manager) payOut(ctx context.Context, minerID string, amount uint64) error {
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return fmt.Errorf("miner %s not found", minerID)
+	}
+	channel, exists := pm.pool.ActiveChannels[miner.ChannelID]
+	if !exists {
+		return fmt.Errorf("channel not found for miner %s", minerID)
+	}
+
+	pm.ensureCapacity(miner, channel, amount)
+
+	update, err := pm.channelManager.CreatePaymentUpdate(channel, amount, "pool_operator")
+	if err != nil {
+		return fmt.Errorf("channel %s cannot pay miner %s: %w", channel.ID, minerID, err)
+	}
+
+	// Move the amount from the miner's pending balance into the channel
+	miner.PendingBalance -= amount
+	miner.CurrentBalance += amount
+	miner.LastPayoutAt = time.Now()
+
+	pm.notifyPaymentUpdate(minerID, update)
+	return nil
+}
+
+// payOutEach pays every miner in payouts through its channel on its own, so one
+// channel that cannot be paid does not hold back everyone else. A miner whose
+// payment fails keeps its pending balance. It returns what was paid and the
+// failures joined together.
+func (pm *Manager) payOutEach(ctx context.Context, payouts map[string]uint64) (map[string]uint64, error) {
+	minerIDs := make([]string, 0, len(payouts))
+	for minerID := range payouts {
+		minerIDs = append(minerIDs, minerID)
+	}
+	sort.Strings(minerIDs)
+
+	paid := make(map[string]uint64)
+	var errs []error
+	for _, minerID := range minerIDs {
+		if err := pm.payOut(ctx, minerID, payouts[minerID]); err != nil {
+			errs = append(errs, fmt.Errorf("miner %s: %w", minerID, err))
+			continue
+		}
+		paid[minerID] = payouts[minerID]
+	}
+
+	return paid, errors.Join(errs...)
+}
+
+// rejectBlockReward records a block whose distribution failed and returns the cause
+func (pm *Manager) rejectBlockReward(blockReward *types.BlockReward, cause error) error {
+	blockReward.Status = BlockRewardRejected
+	blockReward.Error = cause.Error()
+	pm.blockRewards = append(pm.blockRewards, blockReward)
+
+	return fmt.Errorf("block %d rejected: %w", blockReward.BlockHeight, cause)
+}
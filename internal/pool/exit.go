@@ -0,0 +1,126 @@
+package pool
+
+import (
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// StartUnilateralExit closes a channel unilaterally on behalf of the operator or
+// the miner at the current chain height. Without a state the latest state the
+// operator signed is used.
+func (pm *Manager) StartUnilateralExit(channelID, party string, state *types.PaymentUpdate) (*types.Channel, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	channel, exists := pm.findChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+
+	if state == nil {
+		if n := len(channel.PaymentHistory); n > 0 {
+			state = channel.PaymentHistory[n-1]
+		}
+	}
+
+	if err := pm.channelManager.StartUnilateralExit(channel, party, state, pm.blockHeight); err != nil {
+		return nil, fmt.Errorf("failed to start exit: %w", err)
+	}
+
+	// A channel that has started closing can no longer pay its miner
+	if miner, exists := pm.pool.Miners[channel.MinerID]; exists {
+		pm.deactivateMiner(miner)
+	}
+
+	// Observers see the state that was broadcast, before any challenge replaces it
+	pm.notifyExit(channel)
+	pm.disputeStaleExit(channel)
+
+	return channel, nil
+}
+
+// disputeStaleExit challenges a miner's exit broadcast with an older state than the
+// latest one the operator signed, so the dispute is on record as soon as the exit
+// is seen. An operator exit is left to the miner and its watchtower to answer.
+func (pm *Manager) disputeStaleExit(ch *types.Channel) {
+	if ch.Exit.Party != channel.PartyMiner {
+		return
+	}
+	n := len(ch.PaymentHistory)
+	if n == 0 {
+		return
+	}
+	latest := ch.PaymentHistory[n-1]
+	if ch.Exit.State != nil && ch.Exit.State.SequenceNum >= latest.SequenceNum {
+		return
+	}
+
+	event, err := pm.channelManager.ChallengeExit(
+		ch,
+		channel.PartyOperator,
+		latest,
+		pm.blockHeight,
+		pm.pool.OperatorAddress,
+		pm.closeFeeRate,
+	)
+	if err != nil {
+		pm.logger.Warnf("Failed to dispute stale exit of channel %s: %v", ch.ID, err)
+		return
+	}
+	pm.recordDispute(event)
+}
+
+// ChallengeExit presents a later signed state against a channel's pending exit and
+// returns the dispute it raises
+func (pm *Manager) ChallengeExit(channelID, party string, state *types.PaymentUpdate) (*types.DisputeEvent, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	channel, exists := pm.findChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+
+	event, err := pm.channelManager.ChallengeExit(
+		channel,
+		party,
+		state,
+		pm.blockHeight,
+		pm.pool.OperatorAddress,
+		pm.closeFeeRate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to challenge exit: %w", err)
+	}
+
+	pm.recordDispute(event)
+	return event, nil
+}
+
+// FinalizeUnilateralExit settles a channel's exit once the chain height has passed
+// its CSV delay
+func (pm *Manager) FinalizeUnilateralExit(channelID string) (*types.Settlement, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	channel, exists := pm.findChannel(channelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+
+	settlement, err := pm.channelManager.FinalizeUnilateralExit(
+		channel,
+		pm.pool.OperatorAddress,
+		pm.closeFeeRate,
+		pm.blockHeight,
+		pm.minerCosignerFor(channel.MinerID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize exit: %w", err)
+	}
+
+	pm.retireChannel(channel, nil)
+	return settlement, nil
+}
@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// paidChannel returns a miner's channel after it was paid for two blocks
+func paidChannel(t *testing.T, pm *Manager) *types.Channel {
+	t.Helper()
+
+	miner := joinMiner(t, pm, 1e12)
+	for i := 0; i < 2; i++ {
+		if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+	}
+	ch := pm.pool.ActiveChannels[miner.ChannelID]
+	if len(ch.PaymentHistory) < 2 {
+		t.Fatalf("channel has %d states, want at least 2", len(ch.PaymentHistory))
+	}
+	return ch
+}
+
+func TestStaleMinerExitIsDisputedWhenBroadcast(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+
+	// After a withdrawal the miner's older states pay it more than it holds
+	withdraw := (ch.InitialFunding - ch.CurrentBalance) / 2
+	if _, err := pm.WithdrawMiner(context.Background(), ch.MinerID, withdraw); err != nil {
+		t.Fatalf("WithdrawMiner: %v", err)
+	}
+	stale := ch.PaymentHistory[len(ch.PaymentHistory)-2]
+	latest := ch.PaymentHistory[len(ch.PaymentHistory)-1]
+
+	if _, err := pm.StartUnilateralExit(ch.ID, channel.PartyMiner, stale); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+
+	if ch.Status != types.ChannelDisputed {
+		t.Errorf("channel is %s, want %s", ch.Status, types.ChannelDisputed)
+	}
+	if ch.Exit.State.SequenceNum != latest.SequenceNum || ch.Exit.Justice == nil {
+		t.Errorf("exit settles on state %d, want %d with a justice settlement", ch.Exit.State.SequenceNum, latest.SequenceNum)
+	}
+
+	disputes := pm.GetDisputes()
+	if len(disputes) != 1 {
+		t.Fatalf("%d disputes recorded, want 1", len(disputes))
+	}
+	dispute := disputes[0]
+	if dispute.ClaimedSequence != stale.SequenceNum || dispute.LatestSequence != latest.SequenceNum {
+		t.Errorf("dispute claims state %d against %d", dispute.ClaimedSequence, dispute.LatestSequence)
+	}
+	if !dispute.Fraud || dispute.Penalty == 0 {
+		t.Error("miner exit with a state paying it more is not flagged as fraud")
+	}
+}
+
+func TestStaleOperatorExitIsBroadcastAsIs(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	stale := ch.PaymentHistory[0]
+
+	exits := make(chan *types.ChannelExit, 1)
+	pm.OnChainEvent(func(event *types.ChainEvent) {
+		if event.Type == ChainEventExit {
+			exits <- event.Exit
+		}
+	})
+
+	if _, err := pm.StartUnilateralExit(ch.ID, channel.PartyOperator, stale); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+
+	// The pool does not answer its own exit; the miner's tower has to see it
+	if ch.Status != types.ChannelClosingUnilateral {
+		t.Errorf("channel is %s, want %s", ch.Status, types.ChannelClosingUnilateral)
+	}
+	if disputes := pm.GetDisputes(); len(disputes) != 0 {
+		t.Errorf("%d disputes recorded for an operator exit", len(disputes))
+	}
+	select {
+	case exit := <-exits:
+		if exit.State == nil || exit.State.SequenceNum != stale.SequenceNum {
+			t.Errorf("exit event carries state %+v, want the broadcast state %d", exit.State, stale.SequenceNum)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit event delivered")
+	}
+}
+
+func TestCurrentExitIsNotDisputed(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+
+	if _, err := pm.StartUnilateralExit(ch.ID, channel.PartyMiner, nil); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+
+	if ch.Status != types.ChannelClosingUnilateral {
+		t.Errorf("channel is %s, want %s", ch.Status, types.ChannelClosingUnilateral)
+	}
+	if disputes := pm.GetDisputes(); len(disputes) != 0 {
+		t.Errorf("%d disputes recorded for an exit on the latest state", len(disputes))
+	}
+}
+
+func TestExitFinalizesOnlyAtMaturity(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+
+	if _, err := pm.StartUnilateralExit(ch.ID, channel.PartyOperator, nil); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+	maturity := ch.Exit.MaturityHeight
+	if maturity <= pm.BlockHeight() {
+		t.Fatalf("exit matures at %d, already reached at %d", maturity, pm.BlockHeight())
+	}
+
+	for _, height := range []uint64{pm.BlockHeight(), maturity - 1} {
+		if err := pm.AdvanceChain(height); err != nil {
+			t.Fatalf("AdvanceChain: %v", err)
+		}
+		_, err := pm.FinalizeUnilateralExit(ch.ID)
+		var timelock *channel.TimelockError
+		if !errors.As(err, &timelock) || !errors.Is(err, channel.ErrTimelockActive) {
+			t.Fatalf("finalized at height %d: got %v, want a TimelockError", height, err)
+		}
+		if timelock.Height != height || timelock.MaturityHeight != maturity {
+			t.Errorf("timelock error at %d of %d, want %d of %d", timelock.Height, timelock.MaturityHeight, height, maturity)
+		}
+		if ch.Status != types.ChannelClosingUnilateral {
+			t.Fatalf("channel is %s before maturity", ch.Status)
+		}
+	}
+
+	if err := pm.AdvanceChain(maturity); err != nil {
+		t.Fatalf("AdvanceChain: %v", err)
+	}
+	settlement, err := pm.FinalizeUnilateralExit(ch.ID)
+	if err != nil {
+		t.Fatalf("FinalizeUnilateralExit at maturity: %v", err)
+	}
+	if ch.Status != types.ChannelClosed || settlement.Transaction.Input.Leaf != channel.LeafOperatorExit {
+		t.Errorf("channel is %s, settled through %q", ch.Status, settlement.Transaction.Input.Leaf)
+	}
+}
@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// fundChannel moves amount of the treasury's boarded funds into a channel in an
+// Ark round of its own. The round spends treasury VTXOs, and the channel's VTXO
+// when topping it up, into one VTXO at the channel's address holding the old and
+// new funds; the rest goes back to the treasury. It returns the channel's new VTXO.
+// The treasury must have allocated amount to the channel first.
+func (pm *Manager) fundChannel(ctx context.Context, ch *types.Channel, amount uint64) (*types.VTXO, error) {
+	for _, kind := range []string{round.IntentRefresh, round.IntentOffboard} {
+		if pending := pm.queuedIntent(ch.ID, kind); pending != nil {
+			return nil, fmt.Errorf("channel %s has intent %s queued to spend its VTXO", ch.ID, pending.ID)
+		}
+	}
+
+	coins, err := pm.treasury.SelectVTXOs(amount)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]string, 0, len(coins)+1)
+	total := uint64(0)
+	for _, coin := range coins {
+		inputs = append(inputs, coin.Outpoint)
+		total += coin.Amount
+	}
+	funded := amount
+	if ch.VTXO != nil {
+		inputs = append(inputs, ch.VTXO.Outpoint)
+		funded += ch.VTXO.Amount
+	}
+
+	outputs := []*types.RoundOutput{{
+		Party:   "channel",
+		Address: ch.TaprootAddress,
+		Amount:  funded,
+	}}
+	if change := total - amount; change > 0 {
+		outputs = append(outputs, &types.RoundOutput{
+			Party:   channel.PartyOperator,
+			Address: pm.pool.OperatorAddress,
+			Amount:  change,
+		})
+	}
+
+	arkIntentID, err := pm.ark.RegisterIntent(ctx, &types.ArkIntent{Inputs: inputs, Outputs: outputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register funding of channel %s: %w", ch.ID, err)
+	}
+	arkRound, err := pm.ark.JoinRound(ctx, []string{arkIntentID})
+	if err != nil {
+		pm.withdrawIntents(ctx, []string{arkIntentID})
+		return nil, fmt.Errorf("failed to join funding round of channel %s: %w", ch.ID, err)
+	}
+	if len(arkRound.VTXOs) != len(outputs) {
+		pm.abortRound(ctx, arkRound.ID)
+		return nil, fmt.Errorf("round %s issues %d VTXOs for %d outputs", arkRound.ID, len(arkRound.VTXOs), len(outputs))
+	}
+
+	forfeits, err := pm.signFundingForfeits(ch, coins, arkRound.ID)
+	if err != nil {
+		pm.abortRound(ctx, arkRound.ID)
+		return nil, err
+	}
+	if err := pm.ark.SubmitForfeits(ctx, arkRound.ID, forfeits); err != nil {
+		return nil, fmt.Errorf("failed to forfeit the VTXOs of round %s: %w", arkRound.ID, err)
+	}
+
+	var change *types.VTXO
+	if len(arkRound.VTXOs) > 1 {
+		change = arkRound.VTXOs[1]
+	}
+	pm.treasury.SpendVTXOs(coins, change)
+
+	return arkRound.VTXOs[0], nil
+}
+
+// signFundingForfeits forfeits the treasury VTXOs a funding round spends with the
+// treasury key, and the channel's own VTXO, if any, with its operator key
+func (pm *Manager) signFundingForfeits(ch *types.Channel, coins []*types.VTXO, arkRoundID string) ([]*types.ArkForfeit, error) {
+	treasuryKey, err := pm.keystore.TreasuryKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive treasury key: %w", err)
+	}
+
+	forfeits := make([]*types.ArkForfeit, 0, len(coins)+1)
+	for _, coin := range coins {
+		forfeit, err := channel.SignForfeit(treasuryKey, coin.Outpoint, arkRoundID)
+		if err != nil {
+			return nil, err
+		}
+		forfeits = append(forfeits, forfeit)
+	}
+	if ch.VTXO != nil {
+		forfeit, err := pm.channelManager.SignForfeit(ch, ch.VTXO.Outpoint, arkRoundID)
+		if err != nil {
+			return nil, err
+		}
+		forfeits = append(forfeits, forfeit)
+	}
+	return forfeits, nil
+}
@@ -0,0 +1,97 @@
+package pool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const (
+	// joinChallengeTag is the BIP-340 tag miners sign join challenges under
+	joinChallengeTag = "SparkPool/JoinChallenge"
+
+	// joinChallengeSize is the size of a join challenge in bytes
+	joinChallengeSize = 32
+
+	// JoinChallengeTTL is how long a join challenge can be answered
+	JoinChallengeTTL = 5 * time.Minute
+)
+
+// ErrInvalidJoinProof is returned when a miner cannot prove it holds its channel key
+var ErrInvalidJoinProof = errors.New("invalid join proof")
+
+// JoinDigest returns the 32-byte message a miner signs to answer a join challenge
+func JoinDigest(challenge []byte, minerKey *secp256k1.PublicKey) [32]byte {
+	return schnorr.TaggedHash(joinChallengeTag, challenge, minerKey.SerializeCompressed())
+}
+
+// NewJoinChallenge issues a single-use challenge a miner must sign with its
+// channel key to join the pool
+func (pm *Manager) NewJoinChallenge() (*types.JoinChallenge, error) {
+	challenge := make([]byte, joinChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate join challenge: %w", err)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// Forget challenges nobody answered in time
+	now := time.Now()
+	for id, expiresAt := range pm.joinChallenges {
+		if now.After(expiresAt) {
+			delete(pm.joinChallenges, id)
+		}
+	}
+
+	id := hex.EncodeToString(challenge)
+	expiresAt := now.Add(JoinChallengeTTL)
+	pm.joinChallenges[id] = expiresAt
+
+	return &types.JoinChallenge{
+		Challenge: id,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// verifyJoin checks a join request's proof of possession and returns the miner's
+// public key. The challenge is consumed whether or not the proof verifies.
+func (pm *Manager) verifyJoin(req types.JoinPoolRequest) (*secp256k1.PublicKey, error) {
+	expiresAt, exists := pm.joinChallenges[req.Challenge]
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown join challenge", ErrInvalidJoinProof)
+	}
+	delete(pm.joinChallenges, req.Challenge)
+	if time.Now().After(expiresAt) {
+		return nil, fmt.Errorf("%w: join challenge expired", ErrInvalidJoinProof)
+	}
+
+	keyBytes, err := hex.DecodeString(req.PubKey)
+	if err != nil || len(keyBytes) != secp256k1.PubKeyBytesLenCompressed {
+		return nil, fmt.Errorf("%w: public key must be %d hex-encoded compressed bytes",
+			ErrInvalidJoinProof, secp256k1.PubKeyBytesLenCompressed)
+	}
+	minerKey, err := secp256k1.ParsePubKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJoinProof, err)
+	}
+
+	sig, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidJoinProof)
+	}
+
+	challenge, _ := hex.DecodeString(req.Challenge)
+	digest := JoinDigest(challenge, minerKey)
+	if err := schnorr.Verify(schnorr.SerializePubKey(minerKey), digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJoinProof, err)
+	}
+
+	return minerKey, nil
+}
@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// joinRequest answers a join challenge for the key claimed, signed by signer
+func joinRequest(t *testing.T, challenge string, claimed, signer *secp256k1.PrivateKey) types.JoinPoolRequest {
+	t.Helper()
+
+	challengeBytes, _ := hex.DecodeString(challenge)
+	digest := JoinDigest(challengeBytes, claimed.PubKey())
+	sig, err := schnorr.Sign(signer, digest[:])
+	if err != nil {
+		t.Fatalf("sign join challenge: %v", err)
+	}
+	return types.JoinPoolRequest{
+		MinerName: "miner",
+		Address:   "bcrt1qminer",
+		HashRate:  1e12,
+		PubKey:    hex.EncodeToString(claimed.PubKey().SerializeCompressed()),
+		Challenge: challenge,
+		Signature: hex.EncodeToString(sig),
+	}
+}
+
+func TestJoinProofs(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	minerKey, _ := secp256k1.GeneratePrivateKey()
+	otherKey, _ := secp256k1.GeneratePrivateKey()
+
+	challenge := func() string {
+		issued, err := pm.NewJoinChallenge()
+		if err != nil {
+			t.Fatalf("NewJoinChallenge: %v", err)
+		}
+		return issued.Challenge
+	}
+	used := challenge()
+	if _, err := pm.AddMiner(context.Background(), joinRequest(t, used, minerKey, minerKey)); err != nil {
+		t.Fatalf("valid join refused: %v", err)
+	}
+
+	expired := challenge()
+	pm.joinChallenges[expired] = time.Now().Add(-time.Second)
+
+	tampered := joinRequest(t, challenge(), minerKey, minerKey)
+	tampered.Signature = tampered.Signature[:len(tampered.Signature)-2] + "00"
+
+	tests := []struct {
+		name string
+		req  types.JoinPoolRequest
+	}{
+		{"reused challenge", joinRequest(t, used, minerKey, minerKey)},
+		{"expired challenge", joinRequest(t, expired, minerKey, minerKey)},
+		{"unknown challenge", joinRequest(t, hex.EncodeToString(make([]byte, joinChallengeSize)), minerKey, minerKey)},
+		{"signed by another key", joinRequest(t, challenge(), minerKey, otherKey)},
+		{"tampered signature", tampered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			miners := len(pm.GetAllMiners())
+			if _, err := pm.AddMiner(context.Background(), tt.req); !errors.Is(err, ErrInvalidJoinProof) {
+				t.Fatalf("got %v, want ErrInvalidJoinProof", err)
+			}
+			if got := len(pm.GetAllMiners()); got != miners {
+				t.Errorf("%d miners after a refused join, want %d", got, miners)
+			}
+		})
+	}
+
+	// A refused proof consumes its challenge, so it cannot be retried
+	retry := joinRequest(t, challenge(), minerKey, otherKey)
+	pm.AddMiner(context.Background(), retry)
+	if _, err := pm.AddMiner(context.Background(), joinRequest(t, retry.Challenge, minerKey, minerKey)); !errors.Is(err, ErrInvalidJoinProof) {
+		t.Errorf("retried challenge: got %v, want ErrInvalidJoinProof", err)
+	}
+}
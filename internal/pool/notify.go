@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"sync"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// PaymentObserver receives every signed channel state sent to a miner
+type PaymentObserver = func(minerID string, update *types.PaymentUpdate)
+
+// paymentNotice is a signed update queued for delivery to observers
+type paymentNotice struct {
+	minerID string
+	update  *types.PaymentUpdate
+}
+
+// OnPaymentUpdate registers an observer for signed channel updates. Observers are
+// called in order from a single goroutine, never while the pool lock is held.
+func (pm *Manager) OnPaymentUpdate(observer PaymentObserver) {
+	pm.observersMu.Lock()
+	defer pm.observersMu.Unlock()
+	pm.observers = append(pm.observers, observer)
+}
+
+// notifyPaymentUpdate queues an update for delivery to observers
+func (pm *Manager) notifyPaymentUpdate(minerID string, update *types.PaymentUpdate) {
+	pm.notices.push(paymentNotice{minerID: minerID, update: update})
+}
+
+// deliverPaymentUpdates hands queued updates to observers
+func (pm *Manager) deliverPaymentUpdates() {
+	for {
+		notice, ok := pm.notices.pop()
+		if !ok {
+			return
+		}
+		pm.observersMu.RLock()
+		observers := pm.observers
+		pm.observersMu.RUnlock()
+
+		for _, observer := range observers {
+			observer(notice.minerID, notice.update)
+		}
+	}
+}
+
+// noticeQueue is an unbounded FIFO of notices waiting for their observers. Notices
+// are queued while the pool lock is held, so push never blocks: a slow observer
+// only lets the queue grow instead of stalling the pool.
+type noticeQueue[T any] struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []T
+	closed  bool
+}
+
+// newNoticeQueue creates an empty notice queue
+func newNoticeQueue[T any]() *noticeQueue[T] {
+	q := &noticeQueue[T]{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a notice without blocking. Notices pushed after close are dropped.
+func (q *noticeQueue[T]) push(notice T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.pending = append(q.pending, notice)
+	q.cond.Signal()
+}
+
+// pop waits for the oldest queued notice and removes it. It returns false once the
+// queue is closed and every notice queued before has been popped.
+func (q *noticeQueue[T]) pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	var zero T
+	if len(q.pending) == 0 {
+		return zero, false
+	}
+	notice := q.pending[0]
+	q.pending[0] = zero
+	q.pending = q.pending[1:]
+	return notice, true
+}
+
+// close stops the queue taking notices and wakes its reader
+func (q *noticeQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
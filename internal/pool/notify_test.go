@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestNoticesDoNotBlockOnStalledObservers(t *testing.T) {
+	pm := newTestManager(t, Config{}, 0)
+
+	release := make(chan struct{})
+	delivered := make(chan uint64, 1024)
+	pm.OnChainEvent(func(event *types.ChainEvent) {
+		<-release
+		delivered <- event.Height
+	})
+
+	// Far more notices than any buffer would hold, with the observer stalled
+	done := make(chan struct{})
+	go func() {
+		for height := uint64(1); height <= 1000; height++ {
+			pm.notifyBlock(height)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queueing notices blocked on a stalled observer")
+	}
+
+	close(release)
+	for want := uint64(1); want <= 1000; want++ {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Fatalf("notice %d delivered as %d", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notice %d never delivered", want)
+		}
+	}
+}
+
+func TestCloseDeliversQueuedNoticesAndStops(t *testing.T) {
+	pm := newTestManager(t, Config{}, 0)
+
+	delivered := make([]uint64, 0)
+	pm.OnChainEvent(func(event *types.ChainEvent) {
+		delivered = append(delivered, event.Height)
+	})
+	for height := uint64(1); height <= 10; height++ {
+		pm.notifyBlock(height)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		pm.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	// Close waits for the delivery goroutines, so the observer is done with the slice
+	if len(delivered) != 10 {
+		t.Errorf("%d notices delivered before Close returned, want 10", len(delivered))
+	}
+	pm.notifyBlock(11)
+	if len(delivered) != 10 {
+		t.Error("notice delivered after Close")
+	}
+}
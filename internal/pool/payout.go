@@ -0,0 +1,228 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"sort"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Payout schemes supported by the pool
+const (
+	SchemeHashRate = "hashrate"
+	SchemePPLNS    = "pplns"
+	SchemePPS      = "pps"
+	SchemeFPPS     = "fpps"
+	SchemePROP     = "prop"
+	SchemeSOLO     = "solo"
+)
+
+// PayoutRound describes a found block and the work that led up to it
+type PayoutRound struct {
+	BlockHeight  uint64
+	Subsidy      uint64
+	TxFees       uint64
+	Miners       map[string]*types.Miner
+	RoundShares  map[string]float64
+	WindowShares map[string]float64
+	ExpectedWork float64
+}
+
+// PayoutPlan is a strategy's decision on how much to pay out and in what proportions
+type PayoutPlan struct {
+	Amount   uint64
+	Weights  map[string]float64
+	FinderID string
+}
+
+// PayoutStrategy decides how a block reward is split between miners
+type PayoutStrategy interface {
+	// Scheme returns the name recorded on every block reward the strategy produces
+	Scheme() string
+
+	// Plan returns the amount to pay for the round and each miner's weight
+	Plan(round *PayoutRound) (*PayoutPlan, error)
+}
+
+// NewPayoutStrategy returns the payout strategy for a scheme name
+func NewPayoutStrategy(scheme string) (PayoutStrategy, error) {
+	switch scheme {
+	case "", SchemeHashRate:
+		return &hashRateStrategy{}, nil
+	case SchemePPLNS:
+		return &pplnsStrategy{}, nil
+	case SchemePPS:
+		return &ppsStrategy{}, nil
+	case SchemeFPPS:
+		return &ppsStrategy{includeFees: true}, nil
+	case SchemePROP:
+		return &propStrategy{}, nil
+	case SchemeSOLO:
+		return &soloStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown payout scheme %q", scheme)
+	}
+}
+
+// hashRateStrategy splits the full block reward by advertised hash rate
+type hashRateStrategy struct{}
+
+func (s *hashRateStrategy) Scheme() string {
+	return SchemeHashRate
+}
+
+func (s *hashRateStrategy) Plan(round *PayoutRound) (*PayoutPlan, error) {
+	weights := make(map[string]float64)
+	for minerID, miner := range round.Miners {
+		if miner.HashRate > 0 {
+			weights[minerID] = miner.HashRate
+		}
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no active miners")
+	}
+
+	return &PayoutPlan{
+		Amount:  round.Subsidy + round.TxFees,
+		Weights: weights,
+	}, nil
+}
+
+// pplnsStrategy splits the full block reward over the last N units of share work
+type pplnsStrategy struct{}
+
+func (s *pplnsStrategy) Scheme() string {
+	return SchemePPLNS
+}
+
+func (s *pplnsStrategy) Plan(round *PayoutRound) (*PayoutPlan, error) {
+	weights := activeWork(round.WindowShares, round.Miners)
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no shares in PPLNS window")
+	}
+
+	return &PayoutPlan{
+		Amount:  round.Subsidy + round.TxFees,
+		Weights: weights,
+	}, nil
+}
+
+// ppsStrategy pays the expected value of every share submitted during the round,
+// regardless of pool luck. FPPS additionally pays the expected transaction fees.
+type ppsStrategy struct {
+	includeFees bool
+}
+
+func (s *ppsStrategy) Scheme() string {
+	if s.includeFees {
+		return SchemeFPPS
+	}
+	return SchemePPS
+}
+
+func (s *ppsStrategy) Plan(round *PayoutRound) (*PayoutPlan, error) {
+	weights := activeWork(round.RoundShares, round.Miners)
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no shares submitted this round")
+	}
+	if round.ExpectedWork <= 0 {
+		return nil, fmt.Errorf("expected work per block is unknown")
+	}
+
+	roundWork := float64(0)
+	for _, work := range weights {
+		roundWork += work
+	}
+
+	reward := round.Subsidy
+	if s.includeFees {
+		reward += round.TxFees
+	}
+
+	return &PayoutPlan{
+		Amount:  uint64(math.Floor(float64(reward) * roundWork / round.ExpectedWork)),
+		Weights: weights,
+	}, nil
+}
+
+// propStrategy splits the full block reward by the work submitted since the last block
+type propStrategy struct{}
+
+func (s *propStrategy) Scheme() string {
+	return SchemePROP
+}
+
+func (s *propStrategy) Plan(round *PayoutRound) (*PayoutPlan, error) {
+	weights := activeWork(round.RoundShares, round.Miners)
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no shares submitted this round")
+	}
+
+	return &PayoutPlan{
+		Amount:  round.Subsidy + round.TxFees,
+		Weights: weights,
+	}, nil
+}
+
+// soloStrategy pays the whole block reward to the miner that found it
+type soloStrategy struct{}
+
+func (s *soloStrategy) Scheme() string {
+	return SchemeSOLO
+}
+
+func (s *soloStrategy) Plan(round *PayoutRound) (*PayoutPlan, error) {
+	// The finder is drawn proportionally to round work, falling back to hash rate
+	candidates := activeWork(round.RoundShares, round.Miners)
+	if len(candidates) == 0 {
+		for minerID, miner := range round.Miners {
+			if miner.HashRate > 0 {
+				candidates[minerID] = miner.HashRate
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no active miners")
+	}
+
+	finderID := pickWeighted(candidates)
+
+	return &PayoutPlan{
+		Amount:   round.Subsidy + round.TxFees,
+		Weights:  map[string]float64{finderID: 1},
+		FinderID: finderID,
+	}, nil
+}
+
+// activeWork filters share work down to miners that are still active
+func activeWork(work map[string]float64, miners map[string]*types.Miner) map[string]float64 {
+	weights := make(map[string]float64)
+	for minerID, amount := range work {
+		if _, exists := miners[minerID]; exists && amount > 0 {
+			weights[minerID] = amount
+		}
+	}
+	return weights
+}
+
+// pickWeighted picks a key at random with probability proportional to its weight
+func pickWeighted(weights map[string]float64) string {
+	ids := make([]string, 0, len(weights))
+	total := float64(0)
+	for id, weight := range weights {
+		ids = append(ids, id)
+		total += weight
+	}
+	sort.Strings(ids)
+
+	target := mathrand.Float64() * total
+	for _, id := range ids {
+		target -= weights[id]
+		if target < 0 {
+			return id
+		}
+	}
+	return ids[len(ids)-1]
+}
@@ -0,0 +1,139 @@
+package pool
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/treasury"
+)
+
+func TestPayoutStrategies(t *testing.T) {
+	tests := []struct {
+		scheme  string
+		round   *PayoutRound
+		amount  uint64
+		weights map[string]float64
+	}{
+		{
+			scheme: SchemeHashRate,
+			round:  &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b")},
+			amount: 1500, weights: map[string]float64{"a": 1, "b": 1},
+		},
+		{
+			scheme: SchemePPLNS,
+			round: &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b"),
+				WindowShares: map[string]float64{"a": 7, "b": 3, "gone": 5}},
+			amount: 1500, weights: map[string]float64{"a": 7, "b": 3},
+		},
+		{
+			// 40 of the 100 units of work a block takes pay 40% of the subsidy
+			scheme: SchemePPS,
+			round: &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b"),
+				RoundShares: map[string]float64{"a": 30, "b": 10}, ExpectedWork: 100},
+			amount: 400, weights: map[string]float64{"a": 30, "b": 10},
+		},
+		{
+			scheme: SchemeFPPS,
+			round: &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b"),
+				RoundShares: map[string]float64{"a": 30, "b": 10}, ExpectedWork: 100},
+			amount: 600, weights: map[string]float64{"a": 30, "b": 10},
+		},
+		{
+			// PPS rounds down to whole sats
+			scheme: SchemePPS,
+			round: &PayoutRound{Subsidy: 1000, Miners: testMiners("a"),
+				RoundShares: map[string]float64{"a": 1}, ExpectedWork: 3},
+			amount: 333, weights: map[string]float64{"a": 1},
+		},
+		{
+			scheme: SchemePROP,
+			round: &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b"),
+				RoundShares: map[string]float64{"a": 30, "b": 10}},
+			amount: 1500, weights: map[string]float64{"a": 30, "b": 10},
+		},
+		{
+			// Only one miner worked this round, so it found the block
+			scheme: SchemeSOLO,
+			round: &PayoutRound{Subsidy: 1000, TxFees: 500, Miners: testMiners("a", "b"),
+				RoundShares: map[string]float64{"b": 10}},
+			amount: 1500, weights: map[string]float64{"b": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			strategy, err := NewPayoutStrategy(tt.scheme)
+			if err != nil {
+				t.Fatalf("NewPayoutStrategy: %v", err)
+			}
+			if strategy.Scheme() != tt.scheme {
+				t.Errorf("strategy reports scheme %q", strategy.Scheme())
+			}
+			plan, err := strategy.Plan(tt.round)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if plan.Amount != tt.amount {
+				t.Errorf("pays %d, want %d", plan.Amount, tt.amount)
+			}
+			if !reflect.DeepEqual(plan.Weights, tt.weights) {
+				t.Errorf("weights %v, want %v", plan.Weights, tt.weights)
+			}
+			if tt.scheme == SchemeSOLO && plan.FinderID != "b" {
+				t.Errorf("finder %q, want b", plan.FinderID)
+			}
+		})
+	}
+}
+
+func TestPayoutStrategiesRefuseRoundsWithoutWork(t *testing.T) {
+	noShares := &PayoutRound{Subsidy: 1000, Miners: testMiners("a"), ExpectedWork: 100}
+	unknownWork := &PayoutRound{Subsidy: 1000, Miners: testMiners("a"), RoundShares: map[string]float64{"a": 1}}
+	noMiners := &PayoutRound{Subsidy: 1000}
+
+	tests := []struct {
+		scheme string
+		round  *PayoutRound
+	}{
+		{SchemeHashRate, noMiners},
+		{SchemePPLNS, noShares},
+		{SchemePPS, noShares},
+		{SchemePPS, unknownWork},
+		{SchemeFPPS, unknownWork},
+		{SchemePROP, noShares},
+		{SchemeSOLO, noMiners},
+	}
+	for _, tt := range tests {
+		strategy, _ := NewPayoutStrategy(tt.scheme)
+		if plan, err := strategy.Plan(tt.round); err == nil {
+			t.Errorf("%s planned %d sats for %+v", tt.scheme, plan.Amount, tt.round)
+		}
+	}
+
+	if _, err := NewPayoutStrategy("pps+"); err == nil {
+		t.Error("unknown scheme accepted")
+	}
+}
+
+func TestExpectedWorkIsOneBlockIntervalOfHashRate(t *testing.T) {
+	pm := newTestManager(t, Config{BlockInterval: 30 * time.Second}, treasury.DefaultRegtestFunding)
+	miner := joinMiner(t, pm, 1e12)
+
+	round := pm.payoutRound(pm.BlockHeight()+1, 1000, 0)
+	if want := 1e12 * 30.0; round.ExpectedWork != want {
+		t.Fatalf("expected work %v, want %v", round.ExpectedWork, want)
+	}
+
+	// A miner submitting one share a second at its hash rate for a block interval
+	// is paid the whole subsidy under PPS
+	round.RoundShares = map[string]float64{miner.ID: miner.HashRate * 30}
+	strategy, _ := NewPayoutStrategy(SchemePPS)
+	plan, err := strategy.Plan(round)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.Amount != 1000 {
+		t.Errorf("a block interval of shares pays %d sats, want the 1000 sat subsidy", plan.Amount)
+	}
+}
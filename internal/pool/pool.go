@@ -4,177 +4,586 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"math"
 	"sync"
 	"time"
 
 	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/keys"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
 	"github.com/chdwlch/spark-pool/pkg/types"
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/sirupsen/logrus"
 )
 
+// ErrBlockAlreadyProcessed is returned when a block height has already been paid out
+var ErrBlockAlreadyProcessed = errors.New("block already processed")
+
+// ErrStaleBlockHeight is returned for a block at or below the chain tip that is
+// not marked as a replay
+var ErrStaleBlockHeight = errors.New("block height is not above the chain tip")
+
+// Config holds the settings used to create a pool manager
+type Config struct {
+	PoolName        string
+	OperatorAddress string
+	PayoutScheme    string
+	PPLNSWindow     float64
+	BlockInterval   time.Duration
+	StartHeight     uint64
+
+	// PayoutThreshold is the default pending balance at which a miner is paid
+	// through its channel. Zero pays out after every block.
+	PayoutThreshold uint64
+
+	// Capacity sizes and refills channels from each miner's expected earnings
+	Capacity CapacityConfig
+
+	// FeeModel controls the transaction fees collected by simulated blocks
+	FeeModel FeeModel
+
+	// CloseFeeRate is the fee rate in sat/vB of cooperative channel settlements
+	CloseFeeRate uint64
+
+	// RoundInterval is how long intents such as cooperative closes are collected
+	// before they settle together in one round. Zero settles every intent in a
+	// round of its own straight away.
+	RoundInterval time.Duration
+
+	// FeeBasisPoints is the operator fee taken from every miner's gross share
+	FeeBasisPoints uint32
+
+	// DonationBasisPoints is the part of the collected operator fee sent to DonationAddress
+	DonationAddress     string
+	DonationBasisPoints uint32
+
+	// Logger records failures of work that follows a block, such as refilling
+	// channels, which do not undo the block itself. Defaults to a new logger.
+	Logger *logrus.Logger
+}
+
+// maxBasisPoints is 100% expressed in basis points
+const maxBasisPoints = 10000
+
 // Manager handles mining pool operations
 type Manager struct {
-	pool           *types.MiningPool
-	channelManager *channel.Manager
-	mu             sync.RWMutex
-	blockHeight    uint64
-	lastBlockTime  time.Time
-	blockInterval  time.Duration
+	pool                   *types.MiningPool
+	channelManager         *channel.Manager
+	ark                    ArkClient
+	keystore               *keys.Keystore
+	closeFeeRate           uint64
+	batcher                *round.Batcher
+	roundInterval          time.Duration
+	nextRoundAt            time.Time
+	minerCosigner          MinerCosigner
+	strategy               PayoutStrategy
+	shareWindow            *ShareWindow
+	roundShares            map[string]float64
+	totalPaidOut           uint64
+	feeModel               FeeModel
+	capacity               CapacityConfig
+	defaultPayoutThreshold uint64
+	nextScheduledPayout    time.Time
+	notices                *noticeQueue[paymentNotice]
+	observers              []PaymentObserver
+	observersMu            sync.RWMutex
+	disputes               []*types.DisputeEvent
+	disputeNotices         *noticeQueue[*types.DisputeEvent]
+	disputeObservers       []DisputeObserver
+	chainNotices           *noticeQueue[*types.ChainEvent]
+	chainObservers         []ChainObserver
+	roundNotices           *noticeQueue[*types.Round]
+	roundObservers         []RoundObserver
+	delivering             sync.WaitGroup
+	treasury               *treasury.Treasury
+	withdrawals            []*types.Withdrawal
+	totalSubsidy           uint64
+	totalTxFees            uint64
+	blockRewards           []*types.BlockReward
+	processedHeights       map[uint64]struct{}
+	joinChallenges         map[string]time.Time
+	mu                     sync.RWMutex
+	blockHeight            uint64
+	lastBlockTime          time.Time
+	blockInterval          time.Duration
+	logger                 *logrus.Logger
 }
 
-// NewManager creates a new mining pool manager
-func NewManager(poolName string, operatorAddress string, serverPubKey *secp256k1.PublicKey) *Manager {
+// NewManager creates a new mining pool manager. Channel operator keys are derived
+// from the keystore's master key, and channels are built on, co-signed by and
+// settled in rounds of the Ark server behind arkClient. Channels are funded from
+// the operator UTXOs in wallet, boarded into the Ark server as they are needed.
+func NewManager(cfg Config, keystore *keys.Keystore, arkClient ArkClient, wallet treasury.Wallet) (*Manager, error) {
+	strategy, err := NewPayoutStrategy(cfg.PayoutScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := arkClient.GetInfo(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Ark server info: %w", err)
+	}
+	serverKeyBytes, err := hex.DecodeString(info.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ark server public key: %w", err)
+	}
+	serverKey, err := schnorr.ParsePubKey(serverKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ark server public key: %w", err)
+	}
+
+	if cfg.FeeBasisPoints > maxBasisPoints {
+		return nil, fmt.Errorf("operator fee exceeds %d basis points", maxBasisPoints)
+	}
+	if cfg.DonationBasisPoints > maxBasisPoints {
+		return nil, fmt.Errorf("donation exceeds %d basis points", maxBasisPoints)
+	}
+	if cfg.DonationBasisPoints > 0 && cfg.DonationAddress == "" {
+		return nil, fmt.Errorf("donation address is required when donating")
+	}
+
+	startHeight := cfg.StartHeight
+	if startHeight == 0 {
+		startHeight = DefaultStartHeight
+	}
+
+	blockInterval := cfg.BlockInterval
+	if blockInterval <= 0 {
+		blockInterval = 10 * time.Minute // 10 minutes per block
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logrus.New()
+	}
+
 	pool := &types.MiningPool{
-		ID:              generateID(),
-		Name:            poolName,
-		OperatorAddress: operatorAddress,
-		TotalHashRate:   0,
-		BlockReward:     625000000, // 6.25 BTC in satoshis
-		Miners:          make(map[string]*types.Miner),
-		ActiveChannels:  make(map[string]*types.Channel),
-		CreatedAt:       time.Now(),
+		ID:                  generateID(),
+		Name:                cfg.PoolName,
+		OperatorAddress:     cfg.OperatorAddress,
+		TotalHashRate:       0,
+		BlockReward:         BlockSubsidy(startHeight),
+		PayoutScheme:        strategy.Scheme(),
+		FeeBasisPoints:      cfg.FeeBasisPoints,
+		DonationAddress:     cfg.DonationAddress,
+		DonationBasisPoints: cfg.DonationBasisPoints,
+		Miners:              make(map[string]*types.Miner),
+		ActiveChannels:      make(map[string]*types.Channel),
+		ClosedChannels:      make(map[string]*types.Channel),
+		CreatedAt:           time.Now(),
 	}
 
-	return &Manager{
-		pool:           pool,
-		channelManager: channel.NewManager(serverPubKey),
-		blockHeight:    100000,
-		lastBlockTime:  time.Now(),
-		blockInterval:  10 * time.Minute, // 10 minutes per block
+	pm := &Manager{
+		pool:                   pool,
+		channelManager:         channel.NewManager(serverKey, arkCosigner(arkClient), keystore),
+		ark:                    arkClient,
+		keystore:               keystore,
+		closeFeeRate:           cfg.CloseFeeRate,
+		batcher:                round.NewBatcher(cfg.CloseFeeRate),
+		roundInterval:          cfg.RoundInterval,
+		strategy:               strategy,
+		shareWindow:            NewShareWindow(cfg.PPLNSWindow),
+		roundShares:            make(map[string]float64),
+		processedHeights:       make(map[uint64]struct{}),
+		joinChallenges:         make(map[string]time.Time),
+		blockHeight:            startHeight,
+		feeModel:               cfg.FeeModel,
+		capacity:               cfg.Capacity.withDefaults(),
+		defaultPayoutThreshold: cfg.PayoutThreshold,
+		lastBlockTime:          time.Now(),
+		blockInterval:          blockInterval,
+		logger:                 logger,
+		notices:                newNoticeQueue[paymentNotice](),
+		disputes:               make([]*types.DisputeEvent, 0),
+		disputeNotices:         newNoticeQueue[*types.DisputeEvent](),
+		chainNotices:           newNoticeQueue[*types.ChainEvent](),
+		roundNotices:           newNoticeQueue[*types.Round](),
+		withdrawals:            make([]*types.Withdrawal, 0),
+		treasury:               treasury.New(wallet, arkClient, cfg.OperatorAddress),
 	}
+	for _, deliver := range []func(){pm.deliverPaymentUpdates, pm.deliverDisputes, pm.deliverChainEvents, pm.deliverRounds} {
+		pm.delivering.Add(1)
+		go func() {
+			defer pm.delivering.Done()
+			deliver()
+		}()
+	}
+
+	return pm, nil
 }
 
-// AddMiner adds a new miner to the pool
-func (pm *Manager) AddMiner(ctx context.Context, minerName, minerAddress string, hashRate float64) (*types.Miner, error) {
+// Close stops delivering notices to observers once those already queued are
+// delivered, and waits for them. It must not be called from an observer.
+func (pm *Manager) Close() {
+	pm.notices.close()
+	pm.disputeNotices.close()
+	pm.chainNotices.close()
+	pm.roundNotices.close()
+	pm.delivering.Wait()
+}
+
+// AddMiner adds a new miner to the pool. The miner supplies its own channel key
+// and proves it holds the private key by signing a challenge from NewJoinChallenge.
+func (pm *Manager) AddMiner(ctx context.Context, req types.JoinPoolRequest) (*types.Miner, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	// Generate miner key (in real implementation, miner would provide this)
-	minerPrivKey, err := secp256k1.GeneratePrivateKey()
+	minerKey, err := pm.verifyJoin(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate miner key: %w", err)
+		return nil, err
+	}
+
+	// Size the channel for the miner's expected earnings, counting its own hash
+	// rate, and refuse the miner if the treasury cannot fund it. Anything boarded
+	// here stays available to the treasury if the channel is not created.
+	initialFunding := pm.targetCapacity(req.HashRate, pm.pool.TotalHashRate+req.HashRate)
+	if err := pm.treasury.Fund(ctx, initialFunding); err != nil {
+		return nil, fmt.Errorf("cannot fund channel: %w", err)
 	}
 
-	// Get pool operator key (simulated)
-	poolOperatorKey, err := secp256k1.GeneratePrivateKey()
+	// Reserve a fresh operator key for the channel
+	operatorKeyIndex, err := pm.keystore.ReserveOperatorIndex()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate pool operator key: %w", err)
+		return nil, fmt.Errorf("failed to reserve pool operator key: %w", err)
 	}
 
 	// Create miner
 	miner := &types.Miner{
-		ID:            generateID(),
-		Address:       minerAddress,
-		Name:          minerName,
-		HashRate:      hashRate,
-		TotalEarned:   0,
+		ID:             generateID(),
+		Address:        req.Address,
+		Name:           req.MinerName,
+		HashRate:       req.HashRate,
+		TotalEarned:    0,
 		CurrentBalance: 0,
-		JoinedAt:      time.Now(),
-		LastActivity:  time.Now(),
-		IsActive:      true,
+		JoinedAt:       time.Now(),
+		LastActivity:   time.Now(),
+		IsActive:       true,
 	}
 
-	// Create Virtual Channel for the miner
-	initialFunding := uint64(1000000) // 0.01 BTC initial funding
 	channel, err := pm.channelManager.CreateMiningPoolChannel(
-		poolOperatorKey.PubKey(),
-		minerPrivKey.PubKey(),
+		operatorKeyIndex,
+		minerKey,
 		initialFunding,
 	)
 	if err != nil {
+		pm.releaseOperatorIndex(operatorKeyIndex)
 		return nil, fmt.Errorf("failed to create channel: %w", err)
 	}
+	if err := pm.treasury.Allocate(channel.ID, initialFunding); err != nil {
+		pm.channelManager.DiscardChannel(channel.ID)
+		pm.releaseOperatorIndex(operatorKeyIndex)
+		return nil, fmt.Errorf("failed to allocate channel funding: %w", err)
+	}
+
+	// The channel opens once a round has moved its funding into its VTXO
+	vtxo, err := pm.fundChannel(ctx, channel, initialFunding)
+	if err == nil {
+		err = pm.channelManager.OpenChannel(channel, vtxo)
+	}
+	if err != nil {
+		pm.treasury.Deallocate(channel.ID, initialFunding)
+		pm.channelManager.DiscardChannel(channel.ID)
+		pm.releaseOperatorIndex(operatorKeyIndex)
+		return nil, fmt.Errorf("failed to fund channel: %w", err)
+	}
 
 	channel.MinerID = miner.ID
-	channel.MinerAddress = minerAddress
+	channel.MinerAddress = req.Address
 
 	// Add to pool
 	pm.pool.Miners[miner.ID] = miner
 	pm.pool.ActiveChannels[channel.ID] = channel
-	pm.pool.TotalHashRate += hashRate
+	pm.pool.TotalHashRate += req.HashRate
 
 	miner.ChannelID = channel.ID
 
 	return miner, nil
 }
 
-// ProcessBlockReward processes a block reward and distributes it to miners
-func (pm *Manager) ProcessBlockReward(ctx context.Context) (*types.BlockReward, error) {
+// releaseOperatorIndex hands back the operator key index of a channel that was
+// not created
+func (pm *Manager) releaseOperatorIndex(index uint32) {
+	if err := pm.keystore.ReleaseOperatorIndex(index); err != nil {
+		pm.logger.Warnf("Failed to release operator key %d: %v", index, err)
+	}
+}
+
+// ProcessBlockReward processes a block reward and distributes it to miners.
+// A zero height in the request means the next block after the current chain tip.
+// A height at or below the tip is only accepted as a replay, since its payouts are
+// made against today's channels and shares.
+// The subsidy follows the halving schedule for that height; a non-zero reward in
+// the request is taken as the block's total, with anything above the subsidy
+// counted as transaction fees.
+func (pm *Manager) ProcessBlockReward(ctx context.Context, req types.ProcessBlockRequest) (*types.BlockReward, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	pm.blockHeight++
-	pm.lastBlockTime = time.Now()
+	height := req.BlockHeight
+	if height == 0 {
+		height = pm.blockHeight + 1
+	}
+	if _, processed := pm.processedHeights[height]; processed {
+		return nil, fmt.Errorf("%w: height %d", ErrBlockAlreadyProcessed, height)
+	}
+	if height <= pm.blockHeight && !req.Replay {
+		return nil, fmt.Errorf("%w: height %d, tip %d", ErrStaleBlockHeight, height, pm.blockHeight)
+	}
+
+	subsidy, txFees := pm.blockRewardParts(height, req)
 
-	// Calculate total hash rate
-	totalHashRate := pm.pool.TotalHashRate
-	if totalHashRate == 0 {
-		return nil, fmt.Errorf("no active miners")
+	// Ask the payout strategy how much to pay and in what proportions
+	plan, err := pm.strategy.Plan(pm.payoutRound(height, subsidy, txFees))
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the payout exactly, down to the last satoshi
+	grossShares, err := splitReward(plan.Amount, plan.Weights)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create block reward
 	blockReward := &types.BlockReward{
-		ID:           generateID(),
-		BlockHeight:  pm.blockHeight,
-		TotalReward:  pm.pool.BlockReward,
-		Distributions: make(map[string]uint64),
-		CreatedAt:    time.Now(),
+		ID:              generateID(),
+		BlockHeight:     height,
+		TotalReward:     subsidy + txFees,
+		Subsidy:         subsidy,
+		TxFees:          txFees,
+		PayoutScheme:    pm.strategy.Scheme(),
+		PaidOut:         plan.Amount,
+		FinderID:        plan.FinderID,
+		Distributions:   make(map[string]uint64),
+		OperatorAddress: pm.pool.OperatorAddress,
+		DonationAddress: pm.pool.DonationAddress,
+		CreatedAt:       time.Now(),
 	}
 
-	// Distribute rewards to each miner based on hash rate
-	for minerID, miner := range pm.pool.Miners {
-		if !miner.IsActive {
-			continue
+	// Take the operator fee from each miner's gross share
+	for minerID, grossReward := range grossShares {
+		fee := basisPointsOf(grossReward, pm.minerFee(pm.pool.Miners[minerID]))
+		blockReward.OperatorFee += fee
+		if grossReward > fee {
+			blockReward.Distributions[minerID] = grossReward - fee
 		}
+	}
 
-		// Calculate miner's share
-		share := float64(pm.pool.BlockReward) * (miner.HashRate / totalHashRate)
-		minerReward := uint64(math.Floor(share))
-
-		if minerReward > 0 {
-			blockReward.Distributions[minerID] = minerReward
-			
-			// Update miner stats
-			miner.TotalEarned += minerReward
-			miner.LastActivity = time.Now()
-
-			// Process payment through Virtual Channel
-			err := pm.processMinerPayment(ctx, miner, minerReward)
-			if err != nil {
-				return nil, fmt.Errorf("failed to process payment for miner %s: %w", minerID, err)
-			}
-		}
+	// Part of the operator fee goes to the donation address
+	blockReward.Donation = basisPointsOf(blockReward.OperatorFee, pm.pool.DonationBasisPoints)
+	blockReward.OperatorFee -= blockReward.Donation
+
+	if err := verifyBlockReward(blockReward); err != nil {
+		return nil, pm.rejectBlockReward(blockReward, err)
 	}
 
+	// Credit every miner's ledger and check the books balance with the block in
+	// them. If they do not, the credits are reversed so the block leaves no trace.
+	pm.creditMiners(blockReward.Distributions)
+	if err := pm.checkLedger(blockReward); err != nil {
+		pm.uncreditMiners(blockReward.Distributions)
+		return nil, pm.rejectBlockReward(blockReward, err)
+	}
+
+	blockReward.Status = BlockRewardDistributed
+	pm.pool.OperatorFeesEarned += blockReward.OperatorFee
+	pm.pool.DonationsPaid += blockReward.Donation
+	pm.totalPaidOut += blockReward.PaidOut
+	pm.totalSubsidy += blockReward.Subsidy
+	pm.totalTxFees += blockReward.TxFees
+	pm.pool.BlockReward = blockReward.TotalReward
+	pm.processedHeights[height] = struct{}{}
+	pm.blockRewards = append(pm.blockRewards, blockReward)
+
+	// A new round starts once the block has been paid out
+	pm.roundShares = make(map[string]float64)
+
+	// Push the balances that crossed their threshold through the channels. A
+	// miner whose channel cannot take its payment keeps it pending for later.
+	if _, err := pm.payOutEach(ctx, pm.dueMiners(false)); err != nil {
+		pm.logger.Errorf("Failed to pay out after block %d: %v", height, err)
+	}
+
+	// The block is paid; what follows from it is not undone when it fails
+	if err := pm.advanceTip(height); err != nil {
+		pm.logger.Errorf("Failed to advance the chain tip to block %d: %v", height, err)
+	}
+
+	// Refill channels that are about to run dry before the next block
+	pm.rebalanceChannels()
+
 	return blockReward, nil
 }
 
-// processMinerPayment processes a payment to a miner through their Virtual Channel
-func (pm *Manager) processMinerPayment(ctx context.Context, miner *types.Miner, amount uint64) error {
-	channel, exists := pm.pool.ActiveChannels[miner.ChannelID]
+// blockRewardParts returns the subsidy and transaction fees for a block at height
+func (pm *Manager) blockRewardParts(height uint64, req types.ProcessBlockRequest) (uint64, uint64) {
+	subsidy := BlockSubsidy(height)
+
+	switch {
+	case req.Reward > 0:
+		// An explicit total can never pay more subsidy than consensus allows
+		if req.Reward < subsidy {
+			return req.Reward, 0
+		}
+		return subsidy, req.Reward - subsidy
+	case req.TxFees > 0:
+		return subsidy, req.TxFees
+	default:
+		return subsidy, pm.feeModel.BlockFees()
+	}
+}
+
+// verifyBlockReward checks that a block's distributions and fees add up to what it paid out
+func verifyBlockReward(blockReward *types.BlockReward) error {
+	total := blockReward.OperatorFee + blockReward.Donation
+	for _, amount := range blockReward.Distributions {
+		total += amount
+	}
+
+	if total != blockReward.PaidOut {
+		return fmt.Errorf("%w: block %d distributed %d sats but paid out %d",
+			ErrPayoutInvariant, blockReward.BlockHeight, total, blockReward.PaidOut)
+	}
+	return nil
+}
+
+// checkLedger checks that everything the pool has paid out, including a block
+// whose distributions are credited but whose fees are not yet booked, is
+// accounted for
+func (pm *Manager) checkLedger(blockReward *types.BlockReward) error {
+	accounted := pm.calculateTotalEarned() + pm.pool.OperatorFeesEarned + pm.pool.DonationsPaid +
+		blockReward.OperatorFee + blockReward.Donation
+	paidOut := pm.totalPaidOut + blockReward.PaidOut
+	if accounted != paidOut {
+		return fmt.Errorf("%w: pool accounts for %d sats but paid out %d",
+			ErrPayoutInvariant, accounted, paidOut)
+	}
+	return nil
+}
+
+// minerFee returns the fee in basis points charged to a miner
+func (pm *Manager) minerFee(miner *types.Miner) uint32 {
+	if miner.FeeBasisPoints != nil {
+		return *miner.FeeBasisPoints
+	}
+	return pm.pool.FeeBasisPoints
+}
+
+// SetMinerFee overrides the operator fee for a single miner. A nil fee removes
+// the override so the pool-wide fee applies again.
+func (pm *Manager) SetMinerFee(minerID string, feeBasisPoints *uint32) (*types.Miner, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	miner, exists := pm.pool.Miners[minerID]
 	if !exists {
-		return fmt.Errorf("channel not found for miner %s", miner.ID)
+		return nil, fmt.Errorf("miner not found")
 	}
 
-	// Create payment update
-	_, err := pm.channelManager.CreatePaymentUpdate(
-		channel,
-		amount,
-		"pool_operator",
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create payment update: %w", err)
+	if feeBasisPoints != nil {
+		if *feeBasisPoints > maxBasisPoints {
+			return nil, fmt.Errorf("fee exceeds %d basis points", maxBasisPoints)
+		}
+		fee := *feeBasisPoints
+		feeBasisPoints = &fee
+	}
+
+	miner.FeeBasisPoints = feeBasisPoints
+	return miner, nil
+}
+
+// GetPayoutSettings returns a miner's pending balance and payout configuration
+func (pm *Manager) GetPayoutSettings(minerID string) (*types.PayoutSettings, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return nil, fmt.Errorf("miner not found")
+	}
+
+	return pm.payoutSettings(miner), nil
+}
+
+// SetPayoutThreshold sets a miner's payout threshold. Zero reverts to the pool default.
+func (pm *Manager) SetPayoutThreshold(minerID string, threshold uint64) (*types.PayoutSettings, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return nil, fmt.Errorf("miner not found")
+	}
+
+	miner.PayoutThreshold = threshold
+	return pm.payoutSettings(miner), nil
+}
+
+// payoutSettings builds the payout view of a miner
+func (pm *Manager) payoutSettings(miner *types.Miner) *types.PayoutSettings {
+	return &types.PayoutSettings{
+		MinerID:            miner.ID,
+		PendingBalance:     miner.PendingBalance,
+		PayoutThreshold:    miner.PayoutThreshold,
+		EffectiveThreshold: pm.payoutThreshold(miner),
+		LastPayoutAt:       miner.LastPayoutAt,
+		NextScheduledAt:    pm.nextScheduledPayout,
+	}
+}
+
+// payoutRound collects the inputs the payout strategy needs for a block
+func (pm *Manager) payoutRound(blockHeight, subsidy, txFees uint64) *PayoutRound {
+	miners := make(map[string]*types.Miner)
+	for minerID, miner := range pm.pool.Miners {
+		if miner.IsActive {
+			miners[minerID] = miner
+		}
+	}
+
+	roundShares := make(map[string]float64, len(pm.roundShares))
+	for minerID, work := range pm.roundShares {
+		roundShares[minerID] = work
+	}
+
+	// Simulated miners submit one share per second at a difficulty equal to their
+	// hash rate, so the pool expects this much work for every block it finds
+	expectedWork := pm.pool.TotalHashRate * pm.blockInterval.Seconds()
+
+	return &PayoutRound{
+		BlockHeight:  blockHeight,
+		Subsidy:      subsidy,
+		TxFees:       txFees,
+		Miners:       miners,
+		RoundShares:  roundShares,
+		WindowShares: pm.shareWindow.Weights(),
+		ExpectedWork: expectedWork,
 	}
+}
+
+// SubmitShare records an accepted share of the given difficulty for a miner
+func (pm *Manager) SubmitShare(minerID string, difficulty float64) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 
-	// Update miner's current balance
-	miner.CurrentBalance += amount
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return fmt.Errorf("miner not found")
+	}
+	if !miner.IsActive {
+		return fmt.Errorf("miner is not active")
+	}
+	if difficulty <= 0 {
+		return fmt.Errorf("invalid share difficulty")
+	}
 
-	// In a real implementation, you would:
-	// 1. Sign the PSBT as pool operator
-	// 2. Send to miner for storage
-	// 3. Miner would store the latest payment update
+	pm.shareWindow.Add(minerID, difficulty)
+	pm.roundShares[minerID] += difficulty
+
+	miner.AcceptedShares++
+	miner.LastShareAt = time.Now()
+	miner.LastActivity = time.Now()
 
 	return nil
 }
@@ -196,11 +605,43 @@ func (pm *Manager) GetPoolStats() *types.MiningStats {
 		ActiveMiners:    activeMiners,
 		TotalHashRate:   pm.pool.TotalHashRate,
 		TotalEarned:     pm.calculateTotalEarned(),
-		ActiveChannels:  len(pm.pool.ActiveChannels),
+		ActiveChannels:  pm.countOpenChannels(),
 		LastBlockReward: pm.pool.BlockReward,
+		CurrentSubsidy:  BlockSubsidy(pm.blockHeight + 1),
+		TotalSubsidy:    pm.totalSubsidy,
+		TotalTxFees:     pm.totalTxFees,
+		PayoutScheme:    pm.pool.PayoutScheme,
+		WindowShares:    pm.shareWindow.Len(),
+		WindowWork:      pm.shareWindow.Work(),
+		FeeBasisPoints:  pm.pool.FeeBasisPoints,
+		OperatorFees:    pm.pool.OperatorFeesEarned,
+		DonationsPaid:   pm.pool.DonationsPaid,
+		TotalPending:    pm.calculateTotalPending(),
 	}
 }
 
+// BlockHeight returns the height of the most recent block the pool has processed
+func (pm *Manager) BlockHeight() uint64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.blockHeight
+}
+
+// GetTreasury returns the treasury's balances and how much of them channels hold
+func (pm *Manager) GetTreasury(ctx context.Context) (*types.TreasuryStats, error) {
+	return pm.treasury.Stats(ctx)
+}
+
+// GetBlockRewards returns every block reward the pool has processed, oldest first
+func (pm *Manager) GetBlockRewards() []*types.BlockReward {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]*types.BlockReward, len(pm.blockRewards))
+	copy(result, pm.blockRewards)
+	return result
+}
+
 // GetMiner returns a miner by ID
 func (pm *Manager) GetMiner(minerID string) (*types.Miner, bool) {
 	pm.mu.RLock()
@@ -210,13 +651,12 @@ func (pm *Manager) GetMiner(minerID string) (*types.Miner, bool) {
 	return miner, exists
 }
 
-// GetChannel returns a channel by ID
+// GetChannel returns a channel by ID, including channels that have been closed
 func (pm *Manager) GetChannel(channelID string) (*types.Channel, bool) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
-	channel, exists := pm.pool.ActiveChannels[channelID]
-	return channel, exists
+	return pm.findChannel(channelID)
 }
 
 // GetAllMiners returns all miners
@@ -231,45 +671,140 @@ func (pm *Manager) GetAllMiners() map[string]*types.Miner {
 	return result
 }
 
-// GetAllChannels returns all channels
+// GetAllChannels returns all channels, including channels that have been closed
 func (pm *Manager) GetAllChannels() map[string]*types.Channel {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
-	result := make(map[string]*types.Channel)
-	for id, channel := range pm.pool.ActiveChannels {
-		result[id] = channel
+	return pm.channelManager.GetChannels()
+}
+
+// GetChannelBalance returns the balance sheet of a channel
+func (pm *Manager) GetChannelBalance(channelID string) (*types.ChannelBalance, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	return pm.channelManager.GetChannelBalance(channelID)
+}
+
+// GetChannelStats returns a channel's statistics
+func (pm *Manager) GetChannelStats(channelID string) (*types.ChannelStats, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	stats, err := pm.channelManager.GetChannelStats(channelID)
+	if err != nil {
+		return nil, err
 	}
-	return result
+	if miner, exists := pm.pool.Miners[stats.MinerID]; exists {
+		stats.MinerName = miner.Name
+	}
+	return stats, nil
 }
 
-// CloseMinerChannel closes a miner's channel
-func (pm *Manager) CloseMinerChannel(minerID string) error {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+// GetMinerStats returns a miner's earnings alongside its side of the channel
+func (pm *Manager) GetMinerStats(minerID string) (*types.MinerStats, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
 
 	miner, exists := pm.pool.Miners[minerID]
 	if !exists {
-		return fmt.Errorf("miner not found")
+		return nil, fmt.Errorf("miner not found")
 	}
 
-	channel, exists := pm.pool.ActiveChannels[miner.ChannelID]
+	stats := &types.MinerStats{
+		MinerID:        miner.ID,
+		Name:           miner.Name,
+		HashRate:       miner.HashRate,
+		TotalEarned:    miner.TotalEarned,
+		CurrentBalance: miner.CurrentBalance,
+		PendingBalance: miner.PendingBalance,
+		IsActive:       miner.IsActive,
+		LastActivity:   miner.LastActivity.Format(time.RFC3339),
+	}
+	if balance, err := pm.channelManager.GetChannelBalance(miner.ChannelID); err == nil {
+		stats.ChannelBalance = balance.MinerBalance
+	}
+	return stats, nil
+}
+
+// GetChannelSettlement returns the settlement of a closed channel after checking
+// it against the channel's script tree
+func (pm *Manager) GetChannelSettlement(channelID string) (*types.Settlement, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	ch, exists := pm.findChannel(channelID)
 	if !exists {
-		return fmt.Errorf("channel not found")
+		return nil, fmt.Errorf("channel not found")
+	}
+	if ch.Settlement == nil {
+		return nil, fmt.Errorf("channel has not been settled")
 	}
 
-	// Close channel
-	err := pm.channelManager.CloseChannel(channel)
-	if err != nil {
-		return fmt.Errorf("failed to close channel: %w", err)
+	if err := channel.VerifySettlement(ch.Settlement, ch); err != nil {
+		return nil, fmt.Errorf("invalid settlement: %w", err)
 	}
+	return ch.Settlement, nil
+}
 
-	// Remove from active channels
-	delete(pm.pool.ActiveChannels, miner.ChannelID)
+// deactivateMiner stops a miner from earning
+func (pm *Manager) deactivateMiner(miner *types.Miner) {
+	if !miner.IsActive {
+		return
+	}
 	pm.pool.TotalHashRate -= miner.HashRate
 	miner.IsActive = false
+}
 
-	return nil
+// activateMiner puts a miner whose channel was reopened back into the payouts
+func (pm *Manager) activateMiner(miner *types.Miner) {
+	if miner.IsActive {
+		return
+	}
+	pm.pool.TotalHashRate += miner.HashRate
+	miner.IsActive = true
+}
+
+// findChannel looks a channel up in the channel manager, whatever its state
+func (pm *Manager) findChannel(channelID string) (*types.Channel, bool) {
+	return pm.channelManager.GetChannel(channelID)
+}
+
+// retireChannel moves a channel in a terminal state to the closed channels and
+// returns the operator's share of its settlement to the treasury, in vtxo if the
+// settlement went through an Ark round. An expired channel returns nothing.
+func (pm *Manager) retireChannel(ch *types.Channel, vtxo *types.VTXO) {
+	if channel.IsTerminal(ch.Status) {
+		delete(pm.pool.ActiveChannels, ch.ID)
+		pm.pool.ClosedChannels[ch.ID] = ch
+
+		returned := uint64(0)
+		if ch.Settlement != nil {
+			returned = ch.Settlement.OperatorChange
+		}
+		pm.treasury.Release(ch.ID, returned, vtxo)
+	}
+}
+
+// countOpenChannels counts the channels that can currently take payments
+func (pm *Manager) countOpenChannels() int {
+	count := 0
+	for _, channel := range pm.pool.ActiveChannels {
+		if channel.Status == types.ChannelOpen {
+			count++
+		}
+	}
+	return count
+}
+
+// calculateTotalPending sums the balances miners have earned but not yet been paid
+func (pm *Manager) calculateTotalPending() uint64 {
+	total := uint64(0)
+	for _, miner := range pm.pool.Miners {
+		total += miner.PendingBalance
+	}
+	return total
 }
 
 // calculateTotalEarned calculates total earned by all miners
@@ -286,4 +821,4 @@ func generateID() string {
 	bytes := make([]byte, 16)
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
-} 
\ No newline at end of file
+}
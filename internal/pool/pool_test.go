@@ -0,0 +1,335 @@
+package pool
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/ark"
+	"github.com/chdwlch/spark-pool/internal/keys"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// newTestManager creates a pool manager on a mock Ark server whose treasury
+// wallet holds funding sats
+func newTestManager(t *testing.T, cfg Config, funding uint64) *Manager {
+	t.Helper()
+
+	keystore, err := keys.Open(filepath.Join(t.TempDir(), "keystore.json"), "test")
+	if err != nil {
+		t.Fatalf("open keystore: %v", err)
+	}
+	serverKey, err := keystore.ServerKey()
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+
+	if cfg.OperatorAddress == "" {
+		cfg.OperatorAddress = "bcrt1qoperator"
+	}
+	wallet := treasury.NewRegtestWallet(cfg.OperatorAddress)
+	if funding > 0 {
+		if _, err := wallet.Fund(funding, DefaultStartHeight); err != nil {
+			t.Fatalf("fund wallet: %v", err)
+		}
+	}
+
+	asp := ark.NewMockASP(serverKey)
+	pm, err := NewManager(cfg, keystore, asp, wallet)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(pm.Close)
+	asp.SetHeight(pm.BlockHeight())
+	pm.OnChainEvent(asp.HandleChainEvent)
+	return pm
+}
+
+// joinMiner joins a miner with a fresh key to the pool
+func joinMiner(t *testing.T, pm *Manager, hashRate float64) *types.Miner {
+	t.Helper()
+
+	miner, _ := joinMinerWithKey(t, pm, hashRate)
+	return miner
+}
+
+// joinMinerWithKey joins a miner with a fresh key to the pool and returns the key
+func joinMinerWithKey(t *testing.T, pm *Manager, hashRate float64) (*types.Miner, *secp256k1.PrivateKey) {
+	t.Helper()
+
+	minerKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate miner key: %v", err)
+	}
+	challenge, err := pm.NewJoinChallenge()
+	if err != nil {
+		t.Fatalf("join challenge: %v", err)
+	}
+	req := joinRequest(t, challenge.Challenge, minerKey, minerKey)
+	req.HashRate = hashRate
+	miner, err := pm.AddMiner(context.Background(), req)
+	if err != nil {
+		t.Fatalf("AddMiner: %v", err)
+	}
+	return miner, minerKey
+}
+
+// treasuryStats returns the pool treasury's balances
+func treasuryStats(t *testing.T, pm *Manager) *types.TreasuryStats {
+	t.Helper()
+
+	stats, err := pm.treasury.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("treasury stats: %v", err)
+	}
+	return stats
+}
+
+func TestPayoutTopsUpAChannelItExceeds(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	miner := joinMiner(t, pm, 1e12)
+	ch := pm.pool.ActiveChannels[miner.ChannelID]
+	funding := ch.InitialFunding
+	allocated := treasuryStats(t, pm).Allocated
+
+	miner.PendingBalance = funding + 1
+	if err := pm.payOut(context.Background(), miner.ID, funding+1); err != nil {
+		t.Fatalf("payOut: %v", err)
+	}
+
+	if len(ch.TopUps) != 1 {
+		t.Fatalf("channel has %d top-ups, want 1", len(ch.TopUps))
+	}
+	topUp := ch.TopUps[0].Amount
+	if ch.InitialFunding != funding+topUp || ch.CurrentBalance != funding+topUp-(funding+1) {
+		t.Errorf("channel funding %d, balance %d after a %d sat top-up", ch.InitialFunding, ch.CurrentBalance, topUp)
+	}
+	if miner.PendingBalance != 0 || miner.CurrentBalance != funding+1 || len(ch.PaymentHistory) != 1 {
+		t.Errorf("miner paid %d with %d pending in %d states", miner.CurrentBalance, miner.PendingBalance, len(ch.PaymentHistory))
+	}
+	if got := treasuryStats(t, pm).Allocated; got != allocated+topUp {
+		t.Errorf("treasury allocates %d sats, want %d", got, allocated+topUp)
+	}
+}
+
+func TestFailedPayoutLeavesTheMinerOwed(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	miner := joinMiner(t, pm, 1e12)
+	ch := pm.pool.ActiveChannels[miner.ChannelID]
+	ch.Status = types.ChannelClosingCooperative
+	balance := ch.CurrentBalance
+
+	miner.PendingBalance = 1000
+	if err := pm.payOut(context.Background(), miner.ID, 1000); err == nil {
+		t.Fatal("payout to a closing channel succeeded")
+	}
+	if miner.PendingBalance != 1000 || miner.CurrentBalance != 0 {
+		t.Errorf("miner paid %d with %d pending", miner.CurrentBalance, miner.PendingBalance)
+	}
+	if len(ch.PaymentHistory) != 0 || ch.CurrentBalance != balance {
+		t.Errorf("channel holds %d sats in %d states, want %d in none", ch.CurrentBalance, len(ch.PaymentHistory), balance)
+	}
+}
+
+func TestChannelsAreFundedByTheArkServer(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	miner := joinMiner(t, pm, 1e12)
+	ch := pm.pool.ActiveChannels[miner.ChannelID]
+	ctx := context.Background()
+
+	vtxos, err := pm.ark.GetVTXOs(ctx, ch.TaprootAddress)
+	if err != nil {
+		t.Fatalf("GetVTXOs: %v", err)
+	}
+	if len(vtxos) != 1 || vtxos[0].Outpoint != ch.FundingOutpoint || vtxos[0].Amount != ch.InitialFunding {
+		t.Fatalf("server holds %v for a channel funded with %d sats at %s", vtxos, ch.InitialFunding, ch.FundingOutpoint)
+	}
+
+	// A top-up spends the channel's VTXO into a bigger one
+	if err := pm.topUpChannel(ch, 1000, "test"); err != nil {
+		t.Fatalf("topUpChannel: %v", err)
+	}
+	vtxos, _ = pm.ark.GetVTXOs(ctx, ch.TaprootAddress)
+	if len(vtxos) != 1 || vtxos[0].Outpoint != ch.FundingOutpoint || vtxos[0].Amount != ch.InitialFunding {
+		t.Fatalf("server holds %v for a channel of %d sats at %s after its top-up", vtxos, ch.InitialFunding, ch.FundingOutpoint)
+	}
+
+	// Whatever the treasury has not allocated is held at its address
+	treasuryVTXOs, _ := pm.ark.GetVTXOs(ctx, pm.pool.OperatorAddress)
+	held := uint64(0)
+	for _, vtxo := range treasuryVTXOs {
+		held += vtxo.Amount
+	}
+	if stats := treasuryStats(t, pm); held != stats.Available {
+		t.Errorf("treasury address holds %d sats, %d available", held, stats.Available)
+	}
+}
+
+func TestBlockPaysMinersIndependently(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	open := joinMiner(t, pm, 1e12)
+	closing := joinMiner(t, pm, 1e12)
+	pm.pool.ActiveChannels[closing.ChannelID].Status = types.ChannelClosingCooperative
+
+	for i := 0; i < 2; i++ {
+		reward, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{})
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		if reward.Status != BlockRewardDistributed {
+			t.Fatalf("block %d is %s", i, reward.Status)
+		}
+	}
+
+	if open.PendingBalance != 0 || open.CurrentBalance != open.TotalEarned || open.TotalEarned == 0 {
+		t.Errorf("open miner: earned %d, paid %d, pending %d", open.TotalEarned, open.CurrentBalance, open.PendingBalance)
+	}
+	if closing.CurrentBalance != 0 || closing.PendingBalance != closing.TotalEarned || closing.TotalEarned == 0 {
+		t.Errorf("closing miner: earned %d, paid %d, pending %d", closing.TotalEarned, closing.CurrentBalance, closing.PendingBalance)
+	}
+}
+
+func TestShortTreasuryOnlySkipsTheMinerItCannotCover(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	big := joinMiner(t, pm, 1e12)
+	small := joinMiner(t, pm, 1e12)
+
+	// Hand every remaining sat to another channel so nothing can be topped up
+	stats := treasuryStats(t, pm)
+	if err := pm.treasury.Fund(context.Background(), stats.Available+stats.OnchainBalance); err != nil {
+		t.Fatalf("board treasury: %v", err)
+	}
+	if err := pm.treasury.Allocate("elsewhere", treasuryStats(t, pm).Available); err != nil {
+		t.Fatalf("drain treasury: %v", err)
+	}
+
+	// The big miner is owed more than its channel holds
+	capacity := pm.pool.ActiveChannels[big.ChannelID].CurrentBalance
+	big.PendingBalance = capacity + 1
+
+	if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+
+	if big.CurrentBalance != 0 || big.PendingBalance <= capacity {
+		t.Errorf("big miner paid %d with %d pending, channel holds %d", big.CurrentBalance, big.PendingBalance, capacity)
+	}
+	if small.CurrentBalance == 0 || small.PendingBalance != 0 {
+		t.Errorf("small miner paid %d with %d pending", small.CurrentBalance, small.PendingBalance)
+	}
+}
+
+func TestUnfundedMinerReservesNothing(t *testing.T) {
+	pm := newTestManager(t, Config{}, 0)
+	next, err := pm.keystore.ReserveOperatorIndex()
+	if err != nil {
+		t.Fatalf("ReserveOperatorIndex: %v", err)
+	}
+	if err := pm.keystore.ReleaseOperatorIndex(next); err != nil {
+		t.Fatalf("ReleaseOperatorIndex: %v", err)
+	}
+
+	minerKey, _ := secp256k1.GeneratePrivateKey()
+	challenge, _ := pm.NewJoinChallenge()
+	challengeBytes, _ := hex.DecodeString(challenge.Challenge)
+	digest := JoinDigest(challengeBytes, minerKey.PubKey())
+	sig, _ := schnorr.Sign(minerKey, digest[:])
+
+	_, err = pm.AddMiner(context.Background(), types.JoinPoolRequest{
+		MinerName: "miner",
+		Address:   "bcrt1qminer",
+		HashRate:  1e12,
+		PubKey:    hex.EncodeToString(minerKey.PubKey().SerializeCompressed()),
+		Challenge: challenge.Challenge,
+		Signature: hex.EncodeToString(sig),
+	})
+	if !errors.Is(err, treasury.ErrInsufficientFunds) {
+		t.Fatalf("got %v, want ErrInsufficientFunds", err)
+	}
+
+	if got, _ := pm.keystore.ReserveOperatorIndex(); got != next {
+		t.Errorf("operator index %d burned by a miner that never joined", next)
+	}
+	if stats := treasuryStats(t, pm); stats.Allocated != 0 || len(pm.channelManager.GetChannels()) != 0 {
+		t.Errorf("treasury allocated %d sats to %d channels", stats.Allocated, len(pm.channelManager.GetChannels()))
+	}
+}
+
+func TestOperatorFeeAndDonationSplit(t *testing.T) {
+	pm := newTestManager(t, Config{
+		FeeBasisPoints:      250,
+		DonationAddress:     "bcrt1qdonation",
+		DonationBasisPoints: 1000,
+	}, treasury.DefaultRegtestFunding)
+	charged := joinMiner(t, pm, 1e12)
+	exempt := joinMiner(t, pm, 1e12)
+	noFee := uint32(0)
+	if _, err := pm.SetMinerFee(exempt.ID, &noFee); err != nil {
+		t.Fatalf("SetMinerFee: %v", err)
+	}
+
+	// An odd total leaves a sat of dust for one of the two equal shares
+	total := BlockSubsidy(pm.BlockHeight()+1) + 1
+	reward, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{Reward: total})
+	if err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+
+	chargedGross := reward.Distributions[charged.ID] + reward.OperatorFee + reward.Donation
+	exemptGross := reward.Distributions[exempt.ID]
+	if chargedGross+exemptGross != total || max(chargedGross, exemptGross)-min(chargedGross, exemptGross) != 1 {
+		t.Fatalf("gross shares %d and %d of %d", chargedGross, exemptGross, total)
+	}
+
+	// The fee is 2.5% of the charged miner's gross share rounded down, and a tenth
+	// of it is donated, rounded down too
+	fee := chargedGross * 250 / 10000
+	if reward.OperatorFee+reward.Donation != fee {
+		t.Errorf("fee %d + donation %d, want %d in all", reward.OperatorFee, reward.Donation, fee)
+	}
+	if reward.Donation != fee/10 || reward.DonationAddress != "bcrt1qdonation" {
+		t.Errorf("donated %d to %q, want %d", reward.Donation, reward.DonationAddress, fee/10)
+	}
+	if reward.PaidOut != total || pm.pool.OperatorFeesEarned != reward.OperatorFee || pm.pool.DonationsPaid != reward.Donation {
+		t.Errorf("paid out %d, operator earned %d, donated %d", reward.PaidOut, pm.pool.OperatorFeesEarned, pm.pool.DonationsPaid)
+	}
+}
+
+func TestMinerBalanceCountsPendingAndChannel(t *testing.T) {
+	pm := newTestManager(t, Config{PayoutThreshold: math.MaxUint64}, treasury.DefaultRegtestFunding)
+	miner := joinMiner(t, pm, 1e12)
+
+	// Below the threshold the first block stays pending; a forced payout then
+	// moves it into the channel, and the next block is pending again
+	if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+	paid := miner.PendingBalance
+	if err := pm.payOut(context.Background(), miner.ID, paid); err != nil {
+		t.Fatalf("payOut: %v", err)
+	}
+	if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+
+	stats, err := pm.GetMinerStats(miner.ID)
+	if err != nil {
+		t.Fatalf("GetMinerStats: %v", err)
+	}
+	if stats.ChannelBalance != paid || stats.CurrentBalance != paid {
+		t.Errorf("channel holds %d for the miner, paid %d, want %d", stats.ChannelBalance, stats.CurrentBalance, paid)
+	}
+	if stats.PendingBalance == 0 || stats.ChannelBalance+stats.PendingBalance != stats.TotalEarned {
+		t.Errorf("channel %d + pending %d, earned %d", stats.ChannelBalance, stats.PendingBalance, stats.TotalEarned)
+	}
+
+	if _, err := pm.GetMinerStats("unknown"); err == nil {
+		t.Error("stats of an unknown miner")
+	}
+}
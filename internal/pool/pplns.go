@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// DefaultPPLNSWindow is the default amount of share work (N) kept in the PPLNS window
+const DefaultPPLNSWindow = 10000
+
+// ShareWindow keeps the last N units of accepted share work for PPLNS payouts
+type ShareWindow struct {
+	size   float64
+	shares []types.Share
+	work   float64
+}
+
+// NewShareWindow creates a share window holding the last size units of work
+func NewShareWindow(size float64) *ShareWindow {
+	if size <= 0 {
+		size = DefaultPPLNSWindow
+	}
+
+	return &ShareWindow{
+		size:   size,
+		shares: make([]types.Share, 0),
+	}
+}
+
+// Add appends an accepted share and evicts the oldest shares outside the window
+func (sw *ShareWindow) Add(minerID string, difficulty float64) {
+	sw.shares = append(sw.shares, types.Share{
+		MinerID:    minerID,
+		Difficulty: difficulty,
+		Timestamp:  time.Now(),
+	})
+	sw.work += difficulty
+
+	// Drop the oldest shares while the remaining ones still cover the window
+	evict := 0
+	for evict < len(sw.shares)-1 && sw.work-sw.shares[evict].Difficulty >= sw.size {
+		sw.work -= sw.shares[evict].Difficulty
+		evict++
+	}
+	if evict > 0 {
+		sw.shares = append(sw.shares[:0], sw.shares[evict:]...)
+	}
+}
+
+// Weights returns the difficulty-weighted work per miner inside the window
+func (sw *ShareWindow) Weights() map[string]float64 {
+	weights := make(map[string]float64)
+	for _, share := range sw.shares {
+		weights[share.MinerID] += share.Difficulty
+	}
+	return weights
+}
+
+// Len returns the number of shares currently in the window
+func (sw *ShareWindow) Len() int {
+	return len(sw.shares)
+}
+
+// Work returns the total share work currently in the window
+func (sw *ShareWindow) Work() float64 {
+	return sw.work
+}
+
+// Size returns the configured window size N
+func (sw *ShareWindow) Size() float64 {
+	return sw.size
+}
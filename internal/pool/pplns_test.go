@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// testMiners returns active miners with the given IDs, each at a hash rate of 1
+func testMiners(ids ...string) map[string]*types.Miner {
+	miners := make(map[string]*types.Miner, len(ids))
+	for _, id := range ids {
+		miners[id] = &types.Miner{ID: id, HashRate: 1, IsActive: true}
+	}
+	return miners
+}
+
+func TestShareWindowEvictsAtTheEdge(t *testing.T) {
+	sw := NewShareWindow(10)
+	sw.Add("a", 4)
+	sw.Add("b", 4)
+	sw.Add("c", 2)
+
+	// Exactly full: dropping the oldest share would leave less than N
+	if sw.Len() != 3 || sw.Work() != 10 {
+		t.Fatalf("window holds %d shares of %v work, want 3 of 10", sw.Len(), sw.Work())
+	}
+
+	// One more unit of work lets the oldest share go, leaving exactly N
+	sw.Add("c", 4)
+	if sw.Len() != 3 || sw.Work() != 10 {
+		t.Fatalf("window holds %d shares of %v work, want 3 of 10", sw.Len(), sw.Work())
+	}
+	if want := map[string]float64{"b": 4, "c": 6}; !reflect.DeepEqual(sw.Weights(), want) {
+		t.Errorf("weights %v, want %v", sw.Weights(), want)
+	}
+
+	// A share short of the edge keeps the oldest one, which still covers part of N
+	sw.Add("a", 3)
+	if want := map[string]float64{"b": 4, "c": 6, "a": 3}; !reflect.DeepEqual(sw.Weights(), want) {
+		t.Errorf("weights %v, want %v", sw.Weights(), want)
+	}
+}
+
+func TestShareWindowKeepsAShareLargerThanTheWindow(t *testing.T) {
+	sw := NewShareWindow(10)
+	sw.Add("a", 3)
+	sw.Add("b", 25)
+
+	if want := map[string]float64{"b": 25}; !reflect.DeepEqual(sw.Weights(), want) {
+		t.Errorf("weights %v, want %v", sw.Weights(), want)
+	}
+	if sw.Work() != 25 {
+		t.Errorf("window work %v, want 25", sw.Work())
+	}
+}
+
+func TestEmptyShareWindow(t *testing.T) {
+	sw := NewShareWindow(0)
+	if sw.Size() != DefaultPPLNSWindow {
+		t.Errorf("window size %v, want the default %v", sw.Size(), DefaultPPLNSWindow)
+	}
+	if sw.Len() != 0 || sw.Work() != 0 || len(sw.Weights()) != 0 {
+		t.Errorf("empty window holds %d shares of %v work", sw.Len(), sw.Work())
+	}
+
+	// PPLNS cannot pay out a block over an empty window
+	strategy, _ := NewPayoutStrategy(SchemePPLNS)
+	round := &PayoutRound{Subsidy: 1000, WindowShares: sw.Weights(), Miners: testMiners("a")}
+	if _, err := strategy.Plan(round); err == nil {
+		t.Error("PPLNS planned a payout over an empty window")
+	}
+}
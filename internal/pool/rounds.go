@@ -0,0 +1,279 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// RoundObserver receives every round the pool seals
+type RoundObserver = func(round *types.Round)
+
+// CloseMinerChannel starts the cooperative close of a miner's channel and queues
+// its settlement for the next round. Whatever the miner is still owed is pushed
+// through the channel first, so the settlement covers all of its earnings. Without
+// a round interval the round is sealed straight away. A close dropped from its
+// round reopens the channel.
+func (pm *Manager) CloseMinerChannel(ctx context.Context, minerID string) (*types.RoundIntent, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return nil, fmt.Errorf("miner not found")
+	}
+
+	ch, exists := pm.findChannel(miner.ChannelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+	if pending := pm.queuedIntent(ch.ID, round.IntentClose); pending != nil {
+		return nil, fmt.Errorf("channel %s is already queued to close in intent %s", ch.ID, pending.ID)
+	}
+	if pending := pm.queuedIntent(ch.ID, round.IntentOffboard); pending != nil {
+		return nil, fmt.Errorf("channel %s has a withdrawal queued in intent %s", ch.ID, pending.ID)
+	}
+
+	if miner.PendingBalance > 0 && ch.Status == types.ChannelOpen {
+		if err := pm.payOut(ctx, miner.ID, miner.PendingBalance); err != nil {
+			return nil, fmt.Errorf("failed to pay out pending balance: %w", err)
+		}
+	}
+
+	finalState, err := pm.channelManager.BeginClose(ch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close channel: %w", err)
+	}
+
+	// A channel that has started closing can no longer pay its miner
+	pm.deactivateMiner(miner)
+
+	var intent *types.RoundIntent
+	var settlement *types.Settlement
+	var outputs []*types.RoundOutput
+	intent = pm.batcher.Submit(round.IntentClose, ch.ID, miner.ID, &round.Participant{
+		Inputs:          []string{ch.FundingOutpoint},
+		Outputs:         2,
+		StandaloneVSize: channel.SettlementVSize(channel.LeafCooperative),
+		Sign: func(roundID string, fee uint64) ([]*types.RoundOutput, uint64, error) {
+			var err error
+			settlement, err = pm.channelManager.SignClose(
+				ch,
+				finalState,
+				pm.pool.OperatorAddress,
+				fee,
+				roundID,
+				pm.minerCosignerFor(miner.ID),
+			)
+			if err != nil {
+				return nil, 0, err
+			}
+			outputs = settlementOutputs(intent.ID, settlement)
+			return outputs, settlement.Fee, nil
+		},
+		Commit: func() error {
+			if err := pm.channelManager.CompleteClose(ch, settlement); err != nil {
+				return err
+			}
+			intent.Settlement = settlement
+
+			// The operator's change comes back to the treasury in the round
+			var returned *types.VTXO
+			for _, output := range outputs {
+				if output.Party == channel.PartyOperator {
+					returned = output.VTXO
+				}
+			}
+			pm.retireChannel(ch, returned)
+			return nil
+		},
+		Drop: func(cause error) {
+			if err := pm.channelManager.AbortClose(ch, "close dropped: "+cause.Error()); err != nil {
+				pm.logger.Warnf("Failed to reopen channel %s: %v", ch.ID, err)
+				return
+			}
+			if ch.Status == types.ChannelOpen {
+				pm.activateMiner(miner)
+			}
+		},
+	})
+
+	// Without a round interval the close only stands once its round settled; a
+	// dropped close leaves the channel open
+	if pm.roundInterval == 0 {
+		if _, err := pm.sealRound(); err != nil {
+			pm.batcher.Cancel(intent.ID, err)
+			return nil, err
+		}
+		if intent.Status == round.IntentDropped {
+			return nil, fmt.Errorf("failed to close channel %s: %s", ch.ID, intent.Error)
+		}
+	}
+
+	return intent, nil
+}
+
+// queuedIntent returns a channel's intent of the given kind waiting for the next
+// round, if any
+func (pm *Manager) queuedIntent(channelID, kind string) *types.RoundIntent {
+	for _, pending := range pm.batcher.Pending() {
+		if pending.Kind == kind && pending.ChannelID == channelID {
+			return pending
+		}
+	}
+	return nil
+}
+
+// SealRound settles every queued intent in one round and returns it, or nil if no
+// intent settled
+func (pm *Manager) SealRound() (*types.Round, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.sealRound()
+}
+
+// sealRound seals the queued intents at the current chain height and queues the
+// round for delivery to observers
+func (pm *Manager) sealRound() (*types.Round, error) {
+	sealed, err := pm.batcher.Seal(pm.blockHeight, pm.settleRound)
+	pm.trackWithdrawals()
+
+	// A round whose intents settled but failed to commit still happened; its
+	// intents keep the error until the pool state is repaired
+	var commitErr *round.CommitError
+	if errors.As(err, &commitErr) {
+		pm.logger.Errorf("Failed to apply settled round: %v", commitErr)
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal round: %w", err)
+	}
+	if sealed != nil {
+		pm.roundNotices.push(sealed)
+	}
+	return sealed, nil
+}
+
+// settlementOutputs lists a settlement's outputs as round outputs of an intent
+func settlementOutputs(intentID string, settlement *types.Settlement) []*types.RoundOutput {
+	outputs := make([]*types.RoundOutput, 0, len(settlement.Transaction.Outputs))
+	for _, output := range settlement.Transaction.Outputs {
+		outputs = append(outputs, &types.RoundOutput{
+			IntentID: intentID,
+			Party:    output.Party,
+			Address:  output.Address,
+			Amount:   output.Amount,
+		})
+	}
+	return outputs
+}
+
+// GetRounds returns every sealed round, oldest first
+func (pm *Manager) GetRounds() []*types.Round {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.batcher.Rounds()
+}
+
+// GetRound returns a sealed round by ID
+func (pm *Manager) GetRound(roundID string) (*types.Round, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.batcher.Round(roundID)
+}
+
+// GetRoundIntent returns an intent by ID, whatever its status
+func (pm *Manager) GetRoundIntent(intentID string) (*types.RoundIntent, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.batcher.Intent(intentID)
+}
+
+// GetPendingRound returns the intents waiting for the next round
+func (pm *Manager) GetPendingRound() *types.PendingRound {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	return &types.PendingRound{
+		Intents:     pm.batcher.Pending(),
+		FeeRate:     pm.batcher.FeeRate(),
+		NextRoundAt: pm.nextRoundAt,
+	}
+}
+
+// OnRound registers an observer for sealed rounds. Observers are called in order
+// from a single goroutine, never while the pool lock is held.
+func (pm *Manager) OnRound(observer RoundObserver) {
+	pm.observersMu.Lock()
+	defer pm.observersMu.Unlock()
+	pm.roundObservers = append(pm.roundObservers, observer)
+}
+
+// deliverRounds hands sealed rounds to observers
+func (pm *Manager) deliverRounds() {
+	for {
+		sealed, ok := pm.roundNotices.pop()
+		if !ok {
+			return
+		}
+		pm.observersMu.RLock()
+		observers := pm.roundObservers
+		pm.observersMu.RUnlock()
+
+		for _, observer := range observers {
+			observer(sealed)
+		}
+	}
+}
+
+// RoundScheduler seals a round at the end of every collection window
+type RoundScheduler struct {
+	pool     *Manager
+	interval time.Duration
+
+	// OnRound is called after every window with the sealed round, nil if no intent
+	// settled, and any error
+	OnRound func(round *types.Round, err error)
+}
+
+// NewRoundScheduler creates a scheduler sealing a round every interval
+func NewRoundScheduler(pool *Manager, interval time.Duration) *RoundScheduler {
+	return &RoundScheduler{
+		pool:     pool,
+		interval: interval,
+	}
+}
+
+// Run seals a round every interval until ctx is cancelled
+func (rs *RoundScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+
+	rs.pool.setNextRoundAt(time.Now().Add(rs.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rs.pool.setNextRoundAt(time.Now().Add(rs.interval))
+
+			sealed, err := rs.pool.SealRound()
+			if rs.OnRound != nil {
+				rs.OnRound(sealed, err)
+			}
+		}
+	}
+}
+
+// setNextRoundAt records when the scheduler will next seal a round
+func (pm *Manager) setNextRoundAt(next time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.nextRoundAt = next
+}
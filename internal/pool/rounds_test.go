@@ -0,0 +1,81 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestDroppedCloseReopensTheChannel(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	miner := pm.pool.Miners[ch.MinerID]
+	pm.SetMinerCosigner(func(string, *types.Settlement, [32]byte) ([]byte, error) {
+		return nil, errors.New("miner is offline")
+	})
+
+	if _, err := pm.CloseMinerChannel(context.Background(), miner.ID); err == nil {
+		t.Fatal("close without the miner's signature succeeded")
+	}
+
+	if ch.Status != types.ChannelOpen {
+		t.Errorf("channel status = %s, want %s", ch.Status, types.ChannelOpen)
+	}
+	if !miner.IsActive || pm.pool.TotalHashRate != miner.HashRate {
+		t.Errorf("miner active = %v with pool hash rate %v, want it back earning", miner.IsActive, pm.pool.TotalHashRate)
+	}
+	if pending := pm.GetPendingRound().Intents; len(pending) != 0 {
+		t.Errorf("%d intents still queued after the failed close", len(pending))
+	}
+}
+
+func TestCooperativeCloseSettlesAndReturnsTheChange(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	miner, minerKey := joinMinerWithKey(t, pm, 1e12)
+	if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+		t.Fatalf("ProcessBlockReward: %v", err)
+	}
+	ch := pm.pool.ActiveChannels[miner.ChannelID]
+	pm.SetMinerCosigner(func(_ string, _ *types.Settlement, digest [32]byte) ([]byte, error) {
+		return schnorr.Sign(minerKey, digest[:])
+	})
+	before := treasuryStats(t, pm)
+
+	intent, err := pm.CloseMinerChannel(context.Background(), miner.ID)
+	if err != nil {
+		t.Fatalf("CloseMinerChannel: %v", err)
+	}
+	if intent.Status != round.IntentSettled || intent.Settlement == nil {
+		t.Fatalf("close intent is %s with settlement %v", intent.Status, intent.Settlement)
+	}
+	if ch.Status != types.ChannelClosed {
+		t.Errorf("channel status = %s, want %s", ch.Status, types.ChannelClosed)
+	}
+
+	settlement, err := pm.GetChannelSettlement(ch.ID)
+	if err != nil {
+		t.Fatalf("GetChannelSettlement: %v", err)
+	}
+	if settlement != intent.Settlement {
+		t.Error("channel settled differently from its intent")
+	}
+	if settlement.MinerAmount+settlement.OperatorChange+settlement.Fee != settlement.ChannelAmount {
+		t.Errorf("settlement pays %d + %d + %d fee of %d", settlement.MinerAmount, settlement.OperatorChange,
+			settlement.Fee, settlement.ChannelAmount)
+	}
+
+	// The operator's change is a VTXO the treasury can fund channels from again
+	after := treasuryStats(t, pm)
+	if after.Returned != before.Returned+settlement.OperatorChange {
+		t.Errorf("treasury got %d sats back, want %d", after.Returned-before.Returned, settlement.OperatorChange)
+	}
+	if after.Available != before.Available+settlement.OperatorChange || after.Allocated != before.Allocated-ch.InitialFunding {
+		t.Errorf("treasury has %d available and %d allocated, want %d and %d", after.Available, after.Allocated,
+			before.Available+settlement.OperatorChange, before.Allocated-ch.InitialFunding)
+	}
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// PayoutScheduler pays out every pending balance on a fixed schedule, regardless
+// of the miners' thresholds, so small miners still get paid eventually
+type PayoutScheduler struct {
+	pool     *Manager
+	interval time.Duration
+
+	// OnPayout is called after every scheduled run with the amounts paid and any error
+	OnPayout func(payouts map[string]uint64, err error)
+}
+
+// NewPayoutScheduler creates a scheduler paying out every interval (e.g. hourly or daily)
+func NewPayoutScheduler(pool *Manager, interval time.Duration) *PayoutScheduler {
+	return &PayoutScheduler{
+		pool:     pool,
+		interval: interval,
+	}
+}
+
+// Run pays out pending balances every interval until ctx is cancelled
+func (ps *PayoutScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(ps.interval)
+	defer ticker.Stop()
+
+	ps.pool.setNextScheduledPayout(time.Now().Add(ps.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ps.pool.setNextScheduledPayout(time.Now().Add(ps.interval))
+
+			payouts, err := ps.pool.RunScheduledPayouts(ctx)
+			if ps.OnPayout != nil {
+				ps.OnPayout(payouts, err)
+			}
+		}
+	}
+}
+
+// RunScheduledPayouts pushes every pending balance through its channel. Each miner is
+// paid independently so one exhausted channel does not hold back everyone else.
+func (pm *Manager) RunScheduledPayouts(ctx context.Context) (map[string]uint64, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	return pm.payOutEach(ctx, pm.dueMiners(true))
+}
+
+// setNextScheduledPayout records when the scheduler will next run
+func (pm *Manager) setNextScheduledPayout(next time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.nextScheduledPayout = next
+}
@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// ErrPayoutInvariant is returned when distributed amounts do not add up to what was paid out
+var ErrPayoutInvariant = errors.New("payout invariant violated")
+
+// weightScale is the integer resolution strategy weights are quantised to
+const weightScale = 1 << 40
+
+// splitReward splits amount between weights using the largest-remainder method.
+// Every miner first receives the floor of its exact quota; the satoshis left over
+// go one each to the miners with the largest remainders, ties broken by miner ID.
+// The result always sums to exactly amount.
+func splitReward(amount uint64, weights map[string]float64) (map[string]uint64, error) {
+	units, err := quantizeWeights(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	totalUnits := uint64(0)
+	for _, u := range units {
+		totalUnits += u
+	}
+
+	type quota struct {
+		minerID   string
+		remainder uint64
+	}
+
+	shares := make(map[string]uint64, len(units))
+	quotas := make([]quota, 0, len(units))
+	allocated := uint64(0)
+	for minerID, u := range units {
+		// amount*u/totalUnits never exceeds amount, so the 128-bit division cannot overflow
+		hi, lo := bits.Mul64(amount, u)
+		share, remainder := bits.Div64(hi, lo, totalUnits)
+
+		shares[minerID] = share
+		allocated += share
+		quotas = append(quotas, quota{minerID: minerID, remainder: remainder})
+	}
+
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].remainder != quotas[j].remainder {
+			return quotas[i].remainder > quotas[j].remainder
+		}
+		return quotas[i].minerID < quotas[j].minerID
+	})
+
+	// Fewer satoshis are left over than there are miners
+	for i := uint64(0); i < amount-allocated; i++ {
+		shares[quotas[i].minerID]++
+	}
+
+	return shares, nil
+}
+
+// quantizeWeights converts float weights to integer units relative to the largest weight
+func quantizeWeights(weights map[string]float64) (map[string]uint64, error) {
+	maxWeight := float64(0)
+	for minerID, weight := range weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid payout weight %v for miner %s", weight, minerID)
+		}
+		maxWeight = math.Max(maxWeight, weight)
+	}
+	if maxWeight == 0 {
+		return nil, fmt.Errorf("no positive payout weights")
+	}
+
+	units := make(map[string]uint64, len(weights))
+	for minerID, weight := range weights {
+		if u := uint64(math.Round(weight / maxWeight * weightScale)); u > 0 {
+			units[minerID] = u
+		}
+	}
+	return units, nil
+}
+
+// basisPointsOf returns amount*bps/10000 rounded down without overflowing
+func basisPointsOf(amount uint64, bps uint32) uint64 {
+	hi, lo := bits.Mul64(amount, uint64(bps))
+	result, _ := bits.Div64(hi, lo, maxBasisPoints)
+	return result
+}
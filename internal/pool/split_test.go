@@ -0,0 +1,179 @@
+package pool
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestSplitReward(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  uint64
+		weights map[string]float64
+		want    map[string]uint64
+	}{
+		{
+			name:    "single miner takes everything",
+			amount:  312500000,
+			weights: map[string]float64{"a": 7},
+			want:    map[string]uint64{"a": 312500000},
+		},
+		{
+			name:    "even split",
+			amount:  100,
+			weights: map[string]float64{"a": 1, "b": 1, "c": 2},
+			want:    map[string]uint64{"a": 25, "b": 25, "c": 50},
+		},
+		{
+			name:    "ties broken by miner ID",
+			amount:  100,
+			weights: map[string]float64{"c": 1, "a": 1, "b": 1},
+			want:    map[string]uint64{"a": 34, "b": 33, "c": 33},
+		},
+		{
+			name:    "largest remainder wins",
+			amount:  10,
+			weights: map[string]float64{"a": 0.15, "b": 0.25, "c": 0.6},
+			want:    map[string]uint64{"a": 1, "b": 3, "c": 6},
+		},
+		{
+			name:    "zero weight gets nothing",
+			amount:  1000,
+			weights: map[string]float64{"a": 3, "b": 0},
+			want:    map[string]uint64{"a": 1000},
+		},
+		{
+			name:    "more miners than sats",
+			amount:  2,
+			weights: map[string]float64{"a": 1, "b": 1, "c": 1},
+			want:    map[string]uint64{"a": 1, "b": 1, "c": 0},
+		},
+		{
+			name:    "full supply does not overflow",
+			amount:  2100000000000000,
+			weights: map[string]float64{"a": 1, "b": 2},
+			want:    map[string]uint64{"a": 700000000000000, "b": 1400000000000000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitReward(tt.amount, tt.weights)
+			if err != nil {
+				t.Fatalf("splitReward: %v", err)
+			}
+			for minerID, want := range tt.want {
+				if got[minerID] != want {
+					t.Errorf("miner %s got %d, want %d", minerID, got[minerID], want)
+				}
+			}
+			if sum := sumShares(got); sum != tt.amount {
+				t.Errorf("shares sum to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestSplitRewardSumsExactly(t *testing.T) {
+	weights := map[string]float64{
+		"a": 1.3e12, "b": 97.1e12, "c": 0.004e12, "d": 13e12, "e": 13e12, "f": 2.2e12, "g": 1,
+	}
+	for _, amount := range []uint64{0, 1, 6, 7, 999, 312500000, 337512345, 5000000000} {
+		shares, err := splitReward(amount, weights)
+		if err != nil {
+			t.Fatalf("splitReward(%d): %v", amount, err)
+		}
+		if sum := sumShares(shares); sum != amount {
+			t.Errorf("splitReward(%d) sums to %d", amount, sum)
+		}
+	}
+}
+
+func TestSplitRewardIsDeterministic(t *testing.T) {
+	weights := map[string]float64{"m1": 1, "m2": 1, "m3": 1, "m4": 1, "m5": 1, "m6": 1, "m7": 1}
+	first, err := splitReward(1000003, weights)
+	if err != nil {
+		t.Fatalf("splitReward: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		again, _ := splitReward(1000003, weights)
+		for minerID, amount := range first {
+			if again[minerID] != amount {
+				t.Fatalf("run %d gave miner %s %d sats, first run %d", i, minerID, again[minerID], amount)
+			}
+		}
+	}
+	// The four leftover sats go to the lowest miner IDs
+	if first["m1"] != 142858 || first["m4"] != 142858 || first["m5"] != 142857 {
+		t.Errorf("leftover sats not given by miner ID: %v", first)
+	}
+}
+
+func TestSplitRewardRejectsInvalidWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]float64
+	}{
+		{"no weights", map[string]float64{}},
+		{"all zero", map[string]float64{"a": 0, "b": 0}},
+		{"negative", map[string]float64{"a": 1, "b": -1}},
+		{"NaN", map[string]float64{"a": math.NaN()}},
+		{"infinite", map[string]float64{"a": math.Inf(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := splitReward(100, tt.weights); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestBasisPointsOf(t *testing.T) {
+	tests := []struct {
+		amount uint64
+		bps    uint32
+		want   uint64
+	}{
+		{312500000, 100, 3125000},
+		{999, 1, 0},
+		{10001, 1, 1},
+		{math.MaxUint64, maxBasisPoints, math.MaxUint64},
+	}
+
+	for _, tt := range tests {
+		if got := basisPointsOf(tt.amount, tt.bps); got != tt.want {
+			t.Errorf("basisPointsOf(%d, %d) = %d, want %d", tt.amount, tt.bps, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyBlockReward(t *testing.T) {
+	reward := &types.BlockReward{
+		BlockHeight:   840000,
+		PaidOut:       1000,
+		Distributions: map[string]uint64{"a": 600, "b": 380},
+		OperatorFee:   15,
+		Donation:      5,
+	}
+	if err := verifyBlockReward(reward); err != nil {
+		t.Fatalf("balanced reward rejected: %v", err)
+	}
+
+	reward.Distributions["b"]++
+	if err := verifyBlockReward(reward); !errors.Is(err, ErrPayoutInvariant) {
+		t.Errorf("got %v, want ErrPayoutInvariant", err)
+	}
+}
+
+func sumShares(shares map[string]uint64) uint64 {
+	sum := uint64(0)
+	for _, share := range shares {
+		sum += share
+	}
+	return sum
+}
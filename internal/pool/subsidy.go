@@ -0,0 +1,42 @@
+package pool
+
+import (
+	mathrand "math/rand"
+)
+
+const (
+	// HalvingInterval is the number of blocks between subsidy halvings
+	HalvingInterval = 210000
+
+	// initialSubsidy is the block subsidy of the first epoch (50 BTC in satoshis)
+	initialSubsidy = 50 * 100000000
+
+	// DefaultStartHeight is the chain height the simulated pool starts at (fourth halving)
+	DefaultStartHeight = 840000
+)
+
+// BlockSubsidy returns the consensus block subsidy at the given height
+func BlockSubsidy(height uint64) uint64 {
+	halvings := height / HalvingInterval
+	if halvings >= 64 {
+		return 0
+	}
+	return initialSubsidy >> halvings
+}
+
+// FeeModel produces the transaction fees collected by a simulated block
+type FeeModel struct {
+	// MeanFees is the average fee total per block in satoshis
+	MeanFees uint64
+
+	// Simulate varies fees per block between 50% and 150% of MeanFees
+	Simulate bool
+}
+
+// BlockFees returns the transaction fees for the next simulated block
+func (fm FeeModel) BlockFees() uint64 {
+	if !fm.Simulate || fm.MeanFees == 0 {
+		return fm.MeanFees
+	}
+	return fm.MeanFees/2 + uint64(mathrand.Int63n(int64(fm.MeanFees)+1))
+}
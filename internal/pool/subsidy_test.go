@@ -0,0 +1,42 @@
+package pool
+
+import "testing"
+
+func TestBlockSubsidy(t *testing.T) {
+	tests := []struct {
+		height uint64
+		want   uint64
+	}{
+		{0, 5000000000},
+		{209999, 5000000000},
+		{210000, 2500000000},
+		{419999, 2500000000},
+		{420000, 1250000000},
+		{DefaultStartHeight, 312500000},
+		{32 * HalvingInterval, 1},
+		{33 * HalvingInterval, 0},
+		{64*HalvingInterval - 1, 0},
+		{64 * HalvingInterval, 0},
+		{100 * HalvingInterval, 0},
+	}
+
+	for _, tt := range tests {
+		if got := BlockSubsidy(tt.height); got != tt.want {
+			t.Errorf("BlockSubsidy(%d) = %d, want %d", tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestFixedFeeModel(t *testing.T) {
+	fees := FeeModel{MeanFees: 1000}
+	if got := fees.BlockFees(); got != 1000 {
+		t.Errorf("fixed fees %d, want 1000", got)
+	}
+
+	simulated := FeeModel{MeanFees: 1000, Simulate: true}
+	for i := 0; i < 100; i++ {
+		if got := simulated.BlockFees(); got < 500 || got > 1500 {
+			t.Fatalf("simulated fees %d outside 500..1500", got)
+		}
+	}
+}
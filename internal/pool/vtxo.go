@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// QueueRefreshes queues a refresh into the next round for every open channel whose
+// VTXO expires at or before deadline and returns the new intents. Channels already
+// queued for a refresh, or for a withdrawal that moves them into a new VTXO anyway,
+// are skipped. Without a round interval the round is sealed straight away, and if
+// it fails nothing is left queued.
+func (pm *Manager) QueueRefreshes(deadline uint64) ([]*types.RoundIntent, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	intents := make([]*types.RoundIntent, 0)
+	for _, ch := range pm.channelManager.ExpiringChannels(deadline) {
+		if _, active := pm.pool.ActiveChannels[ch.ID]; !active {
+			continue
+		}
+		if pm.queuedIntent(ch.ID, round.IntentRefresh) != nil || pm.queuedIntent(ch.ID, round.IntentOffboard) != nil {
+			continue
+		}
+		intents = append(intents, pm.queueRefresh(ch))
+	}
+
+	// Without a round interval a refresh only stands once its round settled; the
+	// intents of a round that failed are not left queued
+	if len(intents) > 0 && pm.roundInterval == 0 {
+		if _, err := pm.sealRound(); err != nil {
+			for _, intent := range intents {
+				pm.batcher.Cancel(intent.ID, err)
+			}
+			return nil, err
+		}
+	}
+
+	return intents, nil
+}
+
+// queueRefresh submits an intent moving a channel's VTXO into the next round. The
+// operator pays the channel's share of the round fee.
+func (pm *Manager) queueRefresh(ch *types.Channel) *types.RoundIntent {
+	var intent *types.RoundIntent
+	var output *types.RoundOutput
+	intent = pm.batcher.Submit(round.IntentRefresh, ch.ID, ch.MinerID, &round.Participant{
+		Inputs:          []string{ch.VTXO.Outpoint},
+		Outputs:         1,
+		StandaloneVSize: channel.RefreshVSize,
+		Sign: func(roundID string, fee uint64) ([]*types.RoundOutput, uint64, error) {
+			amount, err := pm.channelManager.PrepareRefresh(ch, pm.blockHeight, fee)
+			if err != nil {
+				return nil, 0, err
+			}
+			output = &types.RoundOutput{
+				IntentID: intent.ID,
+				Party:    "channel",
+				Address:  ch.TaprootAddress,
+				Amount:   amount,
+			}
+			return []*types.RoundOutput{output}, fee, nil
+		},
+		Commit: func() error {
+			if output.VTXO == nil {
+				return fmt.Errorf("no VTXO was issued for the refresh of channel %s", ch.ID)
+			}
+			return pm.channelManager.CompleteRefresh(ch, output.VTXO)
+		},
+	})
+	return intent
+}
+
+// expireChannels marks the open channels whose VTXO expired by height as expired.
+// Their miners can no longer be paid, so they leave the pool like a closed channel.
+func (pm *Manager) expireChannels(height uint64) error {
+	channelIDs := make([]string, 0, len(pm.pool.ActiveChannels))
+	for channelID := range pm.pool.ActiveChannels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		ch := pm.pool.ActiveChannels[channelID]
+		if ch.Status != types.ChannelOpen || ch.VTXO == nil || height < ch.VTXO.ExpiryHeight {
+			continue
+		}
+		if err := pm.channelManager.ExpireChannel(ch, height); err != nil {
+			return fmt.Errorf("failed to expire channel %s: %w", ch.ID, err)
+		}
+		if miner, exists := pm.pool.Miners[ch.MinerID]; exists {
+			pm.deactivateMiner(miner)
+		}
+		pm.retireChannel(ch, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/ark"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestRefreshMovesTheChannelIntoANewVTXO(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	old := ch.VTXO
+	minerSide := ch.InitialFunding - ch.CurrentBalance
+
+	// Blocks reach the Ark server asynchronously; it must issue at today's height
+	pm.ark.(*ark.MockASP).SetHeight(pm.BlockHeight())
+
+	intents, err := pm.QueueRefreshes(old.ExpiryHeight)
+	if err != nil {
+		t.Fatalf("QueueRefreshes: %v", err)
+	}
+	if len(intents) != 1 || intents[0].ChannelID != ch.ID {
+		t.Fatalf("queued %d refreshes, want the channel's", len(intents))
+	}
+
+	if ch.VTXO == old || ch.VTXO.ExpiryHeight <= old.ExpiryHeight {
+		t.Fatalf("channel still in a VTXO expiring at %d", ch.VTXO.ExpiryHeight)
+	}
+	if ch.FundingOutpoint != ch.VTXO.Outpoint || ch.VTXOHistory[len(ch.VTXOHistory)-1] != old {
+		t.Errorf("old VTXO %s not replaced by %s", old.Outpoint, ch.FundingOutpoint)
+	}
+	if ch.InitialFunding != old.Amount-ch.VTXO.RefreshFee || ch.InitialFunding-ch.CurrentBalance != minerSide {
+		t.Errorf("refresh fee of %d changed the miner's side to %d, want %d",
+			ch.VTXO.RefreshFee, ch.InitialFunding-ch.CurrentBalance, minerSide)
+	}
+
+	// A channel refreshed already is not due again
+	if intents, err := pm.QueueRefreshes(old.ExpiryHeight); err != nil || len(intents) != 0 {
+		t.Errorf("second refresh queued %d intents: %v", len(intents), err)
+	}
+}
+
+func TestFailedRefreshRoundLeavesNothingQueued(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	old := ch.VTXO
+
+	// Another intent already spends the channel's VTXO, so the round cannot settle
+	if _, err := pm.ark.RegisterIntent(context.Background(), &types.ArkIntent{
+		Inputs:  []string{old.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bcrt1qelsewhere", Amount: 1000}},
+	}); err != nil {
+		t.Fatalf("RegisterIntent: %v", err)
+	}
+
+	if _, err := pm.QueueRefreshes(old.ExpiryHeight); err == nil {
+		t.Fatal("refresh succeeded on a spent VTXO")
+	}
+	if pending := pm.batcher.Pending(); len(pending) != 0 {
+		t.Errorf("%d intents still queued after the round failed", len(pending))
+	}
+	if ch.VTXO != old {
+		t.Errorf("channel moved to VTXO %s", ch.VTXO.Outpoint)
+	}
+}
+
+func TestExpiredChannelLeavesThePool(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	miner, _ := pm.GetMiner(ch.MinerID)
+
+	if err := pm.AdvanceChain(ch.VTXO.ExpiryHeight - 1); err != nil {
+		t.Fatalf("AdvanceChain: %v", err)
+	}
+	if ch.Status != types.ChannelOpen {
+		t.Fatalf("channel is %s a block before its VTXO expires", ch.Status)
+	}
+
+	if err := pm.AdvanceChain(ch.VTXO.ExpiryHeight); err != nil {
+		t.Fatalf("AdvanceChain: %v", err)
+	}
+	if ch.Status != types.ChannelExpired {
+		t.Errorf("channel is %s at its VTXO's expiry", ch.Status)
+	}
+	if _, active := pm.pool.ActiveChannels[ch.ID]; active {
+		t.Error("expired channel is still active")
+	}
+	if miner.IsActive {
+		t.Error("miner of an expired channel is still active")
+	}
+}
@@ -0,0 +1,193 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// WithdrawalConfirmations is how deep a withdrawal's commitment transaction must be
+// buried before the withdrawal is confirmed
+const WithdrawalConfirmations = 6
+
+// WithdrawMiner off-boards amount of a miner's channel earnings to its on-chain
+// address in the next round, or everything on its side when amount is 0. The
+// miner pays its share of the round fee from the amount, and the rest of the
+// channel moves into a new VTXO. Without a round interval the round is sealed
+// straight away.
+func (pm *Manager) WithdrawMiner(ctx context.Context, minerID string, amount uint64) (*types.Withdrawal, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	miner, exists := pm.pool.Miners[minerID]
+	if !exists {
+		return nil, fmt.Errorf("miner not found")
+	}
+
+	ch, exists := pm.findChannel(miner.ChannelID)
+	if !exists {
+		return nil, fmt.Errorf("channel not found")
+	}
+	if ch.Status != types.ChannelOpen {
+		return nil, channel.ErrChannelNotOpen
+	}
+	for _, kind := range []string{round.IntentOffboard, round.IntentClose} {
+		if pending := pm.queuedIntent(ch.ID, kind); pending != nil {
+			return nil, fmt.Errorf("channel %s already has a %s queued in intent %s", ch.ID, kind, pending.ID)
+		}
+	}
+
+	minerBalance := ch.InitialFunding - ch.CurrentBalance
+	if amount == 0 {
+		amount = minerBalance
+	}
+	if amount == 0 {
+		return nil, fmt.Errorf("miner has no channel balance to withdraw")
+	}
+	if amount > minerBalance {
+		return nil, fmt.Errorf("withdrawal of %d sats exceeds the miner's %d sat channel balance", amount, minerBalance)
+	}
+
+	withdrawal := &types.Withdrawal{
+		ID:        generateID(),
+		MinerID:   miner.ID,
+		ChannelID: ch.ID,
+		Address:   miner.Address,
+		Amount:    amount,
+		Status:    types.WithdrawalQueued,
+		CreatedAt: time.Now(),
+	}
+
+	var intent *types.RoundIntent
+	var output *types.RoundOutput
+	intent = pm.batcher.Submit(round.IntentOffboard, ch.ID, miner.ID, &round.Participant{
+		Inputs:          []string{ch.VTXO.Outpoint},
+		Outputs:         2,
+		StandaloneVSize: channel.SettlementVSize(channel.LeafCooperative),
+		Sign: func(roundID string, fee uint64) ([]*types.RoundOutput, uint64, error) {
+			net, err := pm.channelManager.PrepareOffboard(ch, amount, fee)
+			if err != nil {
+				return nil, 0, err
+			}
+			output = &types.RoundOutput{
+				IntentID: intent.ID,
+				Party:    "channel",
+				Address:  ch.TaprootAddress,
+				Amount:   ch.InitialFunding - amount,
+			}
+			return []*types.RoundOutput{
+				{
+					IntentID: intent.ID,
+					Party:    channel.PartyMiner,
+					Address:  miner.Address,
+					Amount:   net,
+				},
+				output,
+			}, fee, nil
+		},
+		Commit: func() error {
+			if output.VTXO == nil {
+				return fmt.Errorf("no VTXO was issued for channel %s after its withdrawal", ch.ID)
+			}
+			update, err := pm.channelManager.CompleteOffboard(ch, amount, output.VTXO)
+			if err != nil {
+				return err
+			}
+			miner.CurrentBalance -= min(amount, miner.CurrentBalance)
+			miner.Withdrawn += amount
+			withdrawal.SequenceNum = update.SequenceNum
+			pm.notifyPaymentUpdate(miner.ID, update)
+			return nil
+		},
+	})
+	withdrawal.IntentID = intent.ID
+
+	// Without a round interval the withdrawal is only kept once its round sealed,
+	// and an intent the round could not settle must not settle in a later one
+	if pm.roundInterval == 0 {
+		if _, err := pm.sealRound(); err != nil {
+			pm.batcher.Cancel(intent.ID, err)
+			return nil, err
+		}
+	}
+	pm.withdrawals = append(pm.withdrawals, withdrawal)
+	pm.trackWithdrawals()
+
+	return withdrawal, nil
+}
+
+// trackWithdrawals follows unconfirmed withdrawals through their round and the
+// blocks after it
+func (pm *Manager) trackWithdrawals() {
+	for _, withdrawal := range pm.withdrawals {
+		switch withdrawal.Status {
+		case types.WithdrawalQueued:
+			intent, exists := pm.batcher.Intent(withdrawal.IntentID)
+			if !exists {
+				continue
+			}
+			switch intent.Status {
+			case round.IntentDropped:
+				withdrawal.Status = types.WithdrawalFailed
+				withdrawal.Error = intent.Error
+				continue
+			case round.IntentSettled:
+				sealed, exists := pm.batcher.Round(intent.RoundID)
+				if !exists {
+					continue
+				}
+				withdrawal.Status = types.WithdrawalUnconfirmed
+				withdrawal.Error = intent.Error
+				withdrawal.Fee = intent.Fee
+				withdrawal.NetAmount = withdrawal.Amount - intent.Fee
+				withdrawal.RoundID = sealed.ID
+				withdrawal.CommitmentTxID = sealed.CommitmentTxID
+				withdrawal.Height = sealed.Height
+			default:
+				continue
+			}
+		case types.WithdrawalUnconfirmed:
+		default:
+			continue
+		}
+
+		// The commitment transaction is mined in the block after its round
+		if pm.blockHeight > withdrawal.Height {
+			withdrawal.Confirmations = pm.blockHeight - withdrawal.Height
+		}
+		if withdrawal.Confirmations >= WithdrawalConfirmations {
+			withdrawal.Status = types.WithdrawalConfirmed
+		}
+	}
+}
+
+// GetWithdrawals returns a miner's withdrawals, oldest first
+func (pm *Manager) GetWithdrawals(minerID string) []*types.Withdrawal {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	withdrawals := make([]*types.Withdrawal, 0)
+	for _, withdrawal := range pm.withdrawals {
+		if withdrawal.MinerID == minerID {
+			withdrawals = append(withdrawals, withdrawal)
+		}
+	}
+	return withdrawals
+}
+
+// GetWithdrawal returns a withdrawal by ID
+func (pm *Manager) GetWithdrawal(withdrawalID string) (*types.Withdrawal, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, withdrawal := range pm.withdrawals {
+		if withdrawal.ID == withdrawalID {
+			return withdrawal, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+func TestFailedWithdrawalRoundLeavesNothingQueued(t *testing.T) {
+	pm := newTestManager(t, Config{}, treasury.DefaultRegtestFunding)
+	ch := paidChannel(t, pm)
+	ctx := context.Background()
+
+	// Another intent already spends the channel's VTXO, so the round cannot settle
+	blocker, err := pm.ark.RegisterIntent(ctx, &types.ArkIntent{
+		Inputs:  []string{ch.VTXO.Outpoint},
+		Outputs: []*types.RoundOutput{{Address: "bcrt1qelsewhere", Amount: 1000}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterIntent: %v", err)
+	}
+	if _, err := pm.WithdrawMiner(ctx, ch.MinerID, 0); err == nil {
+		t.Fatal("withdrawal succeeded on a spent VTXO")
+	}
+
+	if withdrawals := pm.GetWithdrawals(ch.MinerID); len(withdrawals) != 0 {
+		t.Errorf("%d withdrawals kept after the round failed", len(withdrawals))
+	}
+	if pending := pm.batcher.Pending(); len(pending) != 0 {
+		t.Fatalf("%d intents still queued after the round failed", len(pending))
+	}
+
+	// Once the VTXO is free again the withdrawal goes through on its own
+	if err := pm.ark.DeleteIntent(ctx, blocker); err != nil {
+		t.Fatalf("DeleteIntent: %v", err)
+	}
+	withdrawal, err := pm.WithdrawMiner(ctx, ch.MinerID, 0)
+	if err != nil {
+		t.Fatalf("WithdrawMiner: %v", err)
+	}
+	if withdrawal.Status != types.WithdrawalUnconfirmed {
+		t.Errorf("withdrawal is %s, want %s", withdrawal.Status, types.WithdrawalUnconfirmed)
+	}
+	intent, _ := pm.GetRoundIntent(withdrawal.IntentID)
+	if intent == nil || intent.Status != round.IntentSettled {
+		t.Errorf("withdrawal intent is not settled: %+v", intent)
+	}
+	if withdrawals := pm.GetWithdrawals(ch.MinerID); len(withdrawals) != 1 {
+		t.Errorf("%d withdrawals kept, want 1", len(withdrawals))
+	}
+}
@@ -0,0 +1,331 @@
+package round
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Intent kinds
+const (
+	IntentClose    = "close"
+	IntentOffboard = "offboard"
+	IntentRefresh  = "refresh"
+)
+
+// Intent statuses
+const (
+	IntentQueued  = "queued"
+	IntentSettled = "settled"
+	IntentDropped = "dropped"
+)
+
+const (
+	// baseVSize is the estimated size in vbytes of a commitment transaction without
+	// participant outputs: version, locktime and counts, the server's funding input,
+	// and the VTXO tree and connector outputs
+	baseVSize = 11 + 58 + 43 + 43
+
+	// outputVSize is the size in vbytes of a P2TR output
+	outputVSize = 43
+)
+
+// Participant settles one intent's part of a round
+type Participant struct {
+	// Inputs are the outpoints of the VTXOs the intent spends
+	Inputs []string
+
+	// Outputs is the number of outputs the intent adds to the commitment transaction
+	Outputs int
+
+	// StandaloneVSize is the size of the transaction the intent would need on its own
+	StandaloneVSize uint64
+
+	// Sign prepares the intent's part of a round paying fee and returns its outputs
+	// and the fee it actually pays. It is called again with a new fee if other
+	// intents drop out of the round.
+	Sign func(roundID string, fee uint64) ([]*types.RoundOutput, uint64, error)
+
+	// Commit applies the signed part once the round is final
+	Commit func() error
+
+	// Drop, if set, undoes what queueing the intent did once it is dropped
+	// without settling. It must not call back into the batcher.
+	Drop func(cause error)
+}
+
+// SettleFunc settles a signed round with the Ark server before its intents commit.
+// It may fill in the round's Ark round, commitment transaction and output VTXOs.
+type SettleFunc func(round *types.Round) error
+
+// pendingIntent is an intent waiting for the next round
+type pendingIntent struct {
+	intent      *types.RoundIntent
+	participant *Participant
+	outputs     []*types.RoundOutput
+}
+
+// Batcher collects intents and settles them together in rounds, so the fixed cost
+// of a commitment transaction is shared by everyone in it
+type Batcher struct {
+	feeRate uint64
+	pending []*pendingIntent
+	intents map[string]*types.RoundIntent
+	rounds  []*types.Round
+	mu      sync.Mutex
+}
+
+// NewBatcher creates a batcher settling rounds at feeRate sat/vB
+func NewBatcher(feeRate uint64) *Batcher {
+	return &Batcher{
+		feeRate: feeRate,
+		pending: make([]*pendingIntent, 0),
+		intents: make(map[string]*types.RoundIntent),
+		rounds:  make([]*types.Round, 0),
+	}
+}
+
+// FeeRate returns the fee rate rounds are settled at
+func (b *Batcher) FeeRate() uint64 {
+	return b.feeRate
+}
+
+// Submit queues an intent for the next round
+func (b *Batcher) Submit(kind, channelID, minerID string, participant *Participant) *types.RoundIntent {
+	intent := &types.RoundIntent{
+		ID:        generateID(),
+		Kind:      kind,
+		ChannelID: channelID,
+		MinerID:   minerID,
+		Inputs:    participant.Inputs,
+		Status:    IntentQueued,
+		CreatedAt: time.Now(),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, &pendingIntent{intent: intent, participant: participant})
+	b.intents[intent.ID] = intent
+
+	return intent
+}
+
+// Seal settles every queued intent in one round at the given chain height. Intents
+// that cannot be signed are dropped and the others signed again with their new fee
+// share. The signed round is then settled; if that fails, its intents are queued
+// again for the next round. An intent that fails to commit after the round
+// settled stays settled with its error, and Seal returns the round with a
+// CommitError. It returns nil if no intent settled.
+func (b *Batcher) Seal(height uint64, settle SettleFunc) (*types.Round, error) {
+	b.mu.Lock()
+	active := b.pending
+	b.pending = make([]*pendingIntent, 0)
+	b.mu.Unlock()
+
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	round := &types.Round{
+		ID:        generateID(),
+		Height:    height,
+		FeeRate:   b.feeRate,
+		CreatedAt: time.Now(),
+	}
+
+	for len(active) > 0 {
+		fees := b.splitFees(active)
+		signed := make([]*pendingIntent, 0, len(active))
+		for i, p := range active {
+			outputs, fee, err := p.participant.Sign(round.ID, fees[i])
+			if err != nil {
+				p.drop(err)
+				continue
+			}
+			p.intent.Fee = fee
+			p.outputs = outputs
+			signed = append(signed, p)
+		}
+		if len(signed) == len(active) {
+			break
+		}
+		active = signed
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	for _, p := range active {
+		round.Intents = append(round.Intents, p.intent)
+		round.Outputs = append(round.Outputs, p.outputs...)
+	}
+	if err := settle(round); err != nil {
+		b.requeue(active)
+		return nil, fmt.Errorf("failed to settle round: %w", err)
+	}
+
+	// The server settled every intent, so one that fails to commit is still part of
+	// the round; it keeps the error so the pool state can be repaired
+	round.Intents = nil
+	round.Outputs = nil
+	var failed []error
+	for _, p := range active {
+		if err := p.participant.Commit(); err != nil {
+			p.intent.Error = err.Error()
+			failed = append(failed, fmt.Errorf("intent %s: %w", p.intent.ID, err))
+		}
+
+		p.intent.Status = IntentSettled
+		p.intent.RoundID = round.ID
+		p.intent.SettledAt = time.Now()
+
+		round.Intents = append(round.Intents, p.intent)
+		round.Outputs = append(round.Outputs, p.outputs...)
+		round.Fee += p.intent.Fee
+		round.StandaloneFee += b.feeRate * p.participant.StandaloneVSize
+	}
+
+	round.VSize = baseVSize + outputVSize*uint64(len(round.Outputs))
+	tree, err := commitmentTree(round.Outputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build commitment tree: %w", err)
+	}
+	round.CommitmentTree = tree
+	round.CommitmentRoot = tree[len(tree)-1][0]
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rounds = append(b.rounds, round)
+
+	if len(failed) > 0 {
+		return round, &CommitError{RoundID: round.ID, Err: errors.Join(failed...)}
+	}
+	return round, nil
+}
+
+// CommitError reports intents a round settled with the Ark server that could not
+// be applied to the pool afterwards. Seal returns it together with the round.
+type CommitError struct {
+	RoundID string
+	Err     error
+}
+
+func (e *CommitError) Error() string {
+	return fmt.Sprintf("round %s settled but failed to commit: %v", e.RoundID, e.Err)
+}
+
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// requeue puts intents back at the front of the queue, ahead of any submitted
+// while their round was being sealed
+func (b *Batcher) requeue(intents []*pendingIntent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(intents, b.pending...)
+}
+
+// splitFees shares a round's fee between intents: each pays for its own outputs
+// and an equal part of the commitment transaction, the earliest intents taking
+// the remainder
+func (b *Batcher) splitFees(intents []*pendingIntent) []uint64 {
+	n := uint64(len(intents))
+	base := b.feeRate * baseVSize
+
+	fees := make([]uint64, len(intents))
+	for i, p := range intents {
+		fees[i] = b.feeRate*outputVSize*uint64(p.participant.Outputs) + base/n
+		if uint64(i) < base%n {
+			fees[i]++
+		}
+	}
+	return fees
+}
+
+// Cancel drops a queued intent with the cause, so it is left out of every later
+// round. It reports whether the intent was still queued.
+func (b *Batcher) Cancel(intentID string, cause error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, p := range b.pending {
+		if p.intent.ID == intentID {
+			b.pending = append(b.pending[:i:i], b.pending[i+1:]...)
+			p.drop(cause)
+			return true
+		}
+	}
+	return false
+}
+
+// Pending returns the intents waiting for the next round, oldest first
+func (b *Batcher) Pending() []*types.RoundIntent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	intents := make([]*types.RoundIntent, len(b.pending))
+	for i, p := range b.pending {
+		intents[i] = p.intent
+	}
+	return intents
+}
+
+// Intent returns an intent by ID, whatever its status
+func (b *Batcher) Intent(intentID string) (*types.RoundIntent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	intent, exists := b.intents[intentID]
+	return intent, exists
+}
+
+// Rounds returns every sealed round, oldest first
+func (b *Batcher) Rounds() []*types.Round {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rounds := make([]*types.Round, len(b.rounds))
+	copy(rounds, b.rounds)
+	return rounds
+}
+
+// Round returns a sealed round by ID
+func (b *Batcher) Round(roundID string) (*types.Round, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, round := range b.rounds {
+		if round.ID == roundID {
+			return round, true
+		}
+	}
+	return nil, false
+}
+
+// drop takes an intent out of every round and lets its participant undo it
+func (p *pendingIntent) drop(cause error) {
+	dropIntent(p.intent, cause)
+	if p.participant.Drop != nil {
+		p.participant.Drop(cause)
+	}
+}
+
+// dropIntent records why an intent left its round
+func dropIntent(intent *types.RoundIntent, cause error) {
+	intent.Status = IntentDropped
+	intent.Error = cause.Error()
+}
+
+// generateID generates a unique round or intent ID
+func generateID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
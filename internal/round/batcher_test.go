@@ -0,0 +1,68 @@
+package round
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// participant returns a participant paying one output of amount
+func participant(amount uint64, commit func() error) *Participant {
+	return &Participant{
+		Inputs:  []string{"input"},
+		Outputs: 1,
+		Sign: func(roundID string, fee uint64) ([]*types.RoundOutput, uint64, error) {
+			return []*types.RoundOutput{{Party: "miner", Address: "bcrt1qminer", Amount: amount}}, fee, nil
+		},
+		Commit: commit,
+	}
+}
+
+func TestFailedCommitKeepsTheIntentSettled(t *testing.T) {
+	b := NewBatcher(1)
+	ok := b.Submit(IntentRefresh, "channel-1", "miner-1", participant(10_000, func() error { return nil }))
+	failed := b.Submit(IntentRefresh, "channel-2", "miner-2", participant(20_000, func() error {
+		return errors.New("no VTXO was issued")
+	}))
+
+	sealed, err := b.Seal(100, func(*types.Round) error { return nil })
+	var commitErr *CommitError
+	if !errors.As(err, &commitErr) {
+		t.Fatalf("Seal error = %v, want a CommitError", err)
+	}
+	if sealed == nil || commitErr.RoundID != sealed.ID {
+		t.Fatalf("Seal returned round %v for a CommitError of round %s", sealed, commitErr.RoundID)
+	}
+	if len(sealed.Intents) != 2 {
+		t.Errorf("round has %d intents, want both the server settled", len(sealed.Intents))
+	}
+	if ok.Status != IntentSettled || ok.Error != "" {
+		t.Errorf("committed intent: status %s, error %q", ok.Status, ok.Error)
+	}
+	if failed.Status != IntentSettled || failed.Error == "" {
+		t.Errorf("uncommitted intent: status %s, error %q, want settled with its error", failed.Status, failed.Error)
+	}
+	if len(b.Pending()) != 0 {
+		t.Errorf("%d intents queued again after their round settled", len(b.Pending()))
+	}
+}
+
+func TestDroppedIntentIsUndone(t *testing.T) {
+	b := NewBatcher(1)
+	var undone error
+	p := participant(10_000, func() error { return nil })
+	p.Sign = func(string, uint64) ([]*types.RoundOutput, uint64, error) {
+		return nil, 0, errors.New("miner is offline")
+	}
+	p.Drop = func(cause error) { undone = cause }
+	intent := b.Submit(IntentClose, "channel-1", "miner-1", p)
+
+	sealed, err := b.Seal(100, func(*types.Round) error { return nil })
+	if err != nil || sealed != nil {
+		t.Fatalf("Seal = %v, %v, want no round", sealed, err)
+	}
+	if intent.Status != IntentDropped || undone == nil {
+		t.Errorf("intent status %s, undone with %v, want dropped and undone", intent.Status, undone)
+	}
+}
@@ -0,0 +1,57 @@
+package round
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// BIP-340 tags of the commitment tree's leaves and branches
+const (
+	leafTag   = "SparkPool/RoundLeaf"
+	branchTag = "SparkPool/RoundBranch"
+)
+
+// commitmentTree builds the Merkle tree committing to a round's outputs and
+// returns its levels as hex hashes, from the leaves up to the root. A node without
+// a sibling moves up a level unchanged.
+func commitmentTree(outputs []*types.RoundOutput) ([][]string, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("round has no outputs")
+	}
+
+	level := make([][32]byte, len(outputs))
+	for i, output := range outputs {
+		var amount [8]byte
+		binary.BigEndian.PutUint64(amount[:], output.Amount)
+		level[i] = schnorr.TaggedHash(leafTag, []byte(output.IntentID), []byte(output.Address), amount[:])
+	}
+
+	tree := [][]string{encodeLevel(level)}
+	for len(level) > 1 {
+		next := make([][32]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, schnorr.TaggedHash(branchTag, level[i][:], level[i+1][:]))
+		}
+		level = next
+		tree = append(tree, encodeLevel(level))
+	}
+
+	return tree, nil
+}
+
+// encodeLevel hex-encodes one level of the tree
+func encodeLevel(level [][32]byte) []string {
+	encoded := make([]string, len(level))
+	for i, node := range level {
+		encoded[i] = hex.EncodeToString(node[:])
+	}
+	return encoded
+}
@@ -0,0 +1,294 @@
+package treasury
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// ErrInsufficientFunds is returned when the treasury cannot fund an allocation,
+// even after boarding every on-chain UTXO
+var ErrInsufficientFunds = errors.New("insufficient treasury funds")
+
+// Wallet holds the operator's on-chain UTXOs
+type Wallet interface {
+	// ListUnspent returns the wallet's unspent outputs, largest first
+	ListUnspent(ctx context.Context) ([]*types.UTXO, error)
+
+	// Spend removes outputs from the wallet once they have been spent
+	Spend(ctx context.Context, outpoints []string) error
+}
+
+// Boarder moves on-chain UTXOs into a VTXO on the Ark server
+type Boarder interface {
+	Board(ctx context.Context, utxos []*types.UTXO, address string) (*types.VTXO, error)
+}
+
+// Treasury tracks the operator funds backing the pool's channels. On-chain UTXOs
+// are boarded into the Ark server as they are needed, and every channel is
+// allocated its capacity from the boarded funds. The boarded funds are held in
+// VTXOs at the treasury's address, which the rounds funding channels spend.
+// Callers serialize changes.
+type Treasury struct {
+	wallet      Wallet
+	ark         Boarder
+	address     string
+	boarded     uint64
+	available   uint64
+	allocated   uint64
+	returned    uint64
+	allocations map[string]uint64
+	boardings   []*types.Boarding
+	vtxos       []*types.VTXO
+
+	// unswept holds boarded UTXOs the wallet failed to mark spent. They are never
+	// boarded again, and marking them spent is retried on the next Fund.
+	unswept map[string]bool
+
+	mu sync.RWMutex
+}
+
+// New creates a treasury boarding the UTXOs in wallet through ark, holding the
+// boarded VTXOs at address
+func New(wallet Wallet, ark Boarder, address string) *Treasury {
+	return &Treasury{
+		wallet:      wallet,
+		ark:         ark,
+		address:     address,
+		allocations: make(map[string]uint64),
+		boardings:   make([]*types.Boarding, 0),
+		vtxos:       make([]*types.VTXO, 0),
+		unswept:     make(map[string]bool),
+	}
+}
+
+// Fund makes sure amount can be allocated, boarding on-chain UTXOs, largest first,
+// to cover any shortfall. Nothing is boarded if the wallet cannot cover it.
+func (t *Treasury) Fund(ctx context.Context, amount uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(ctx)
+	if amount <= t.available {
+		return nil
+	}
+	shortfall := amount - t.available
+
+	utxos, err := t.wallet.ListUnspent(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list treasury UTXOs: %w", err)
+	}
+	selected := make([]*types.UTXO, 0)
+	total := uint64(0)
+	for _, utxo := range utxos {
+		if total >= shortfall {
+			break
+		}
+		if t.unswept[utxo.Outpoint] {
+			continue
+		}
+		selected = append(selected, utxo)
+		total += utxo.Amount
+	}
+	if total < shortfall {
+		return fmt.Errorf("%w: %d sats needed, %d available and %d on-chain", ErrInsufficientFunds, amount, t.available, total)
+	}
+
+	return t.board(ctx, selected)
+}
+
+// board moves UTXOs into a VTXO held at the treasury's address
+func (t *Treasury) board(ctx context.Context, utxos []*types.UTXO) error {
+	inputs := make([]string, len(utxos))
+	for i, utxo := range utxos {
+		inputs[i] = utxo.Outpoint
+	}
+
+	vtxo, err := t.ark.Board(ctx, utxos, t.address)
+	if err != nil {
+		return fmt.Errorf("failed to board treasury UTXOs: %w", err)
+	}
+
+	// The VTXO is the treasury's as soon as the server issued it, whether or not
+	// the wallet manages to mark the boarded UTXOs spent
+	t.boarded += vtxo.Amount
+	t.available += vtxo.Amount
+	t.boardings = append(t.boardings, &types.Boarding{
+		Inputs:    inputs,
+		Amount:    vtxo.Amount,
+		VTXO:      vtxo,
+		CreatedAt: time.Now(),
+	})
+	t.vtxos = append(t.vtxos, vtxo)
+	for _, input := range inputs {
+		t.unswept[input] = true
+	}
+	t.sweep(ctx)
+	return nil
+}
+
+// sweep marks boarded UTXOs spent in the wallet. Those the wallet cannot mark yet
+// stay unswept for the next attempt.
+func (t *Treasury) sweep(ctx context.Context) {
+	if len(t.unswept) == 0 {
+		return
+	}
+	outpoints := make([]string, 0, len(t.unswept))
+	for outpoint := range t.unswept {
+		outpoints = append(outpoints, outpoint)
+	}
+	sort.Strings(outpoints)
+
+	if err := t.wallet.Spend(ctx, outpoints); err != nil {
+		return
+	}
+	clear(t.unswept)
+}
+
+// Allocate moves amount of boarded funds into a channel's capacity. Fund must
+// have covered it first.
+func (t *Treasury) Allocate(channelID string, amount uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if amount > t.available {
+		return fmt.Errorf("%w: %d sats needed, %d available", ErrInsufficientFunds, amount, t.available)
+	}
+	t.available -= amount
+	t.allocated += amount
+	t.allocations[channelID] += amount
+	return nil
+}
+
+// SelectVTXOs picks treasury VTXOs, largest first, worth at least amount for a
+// round that funds a channel. Nothing changes until the round settles and
+// SpendVTXOs records it.
+func (t *Treasury) SelectVTXOs(amount uint64) ([]*types.VTXO, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	vtxos := make([]*types.VTXO, len(t.vtxos))
+	copy(vtxos, t.vtxos)
+	sort.Slice(vtxos, func(i, j int) bool {
+		if vtxos[i].Amount != vtxos[j].Amount {
+			return vtxos[i].Amount > vtxos[j].Amount
+		}
+		return vtxos[i].Outpoint < vtxos[j].Outpoint
+	})
+
+	selected := make([]*types.VTXO, 0)
+	total := uint64(0)
+	for _, vtxo := range vtxos {
+		if total >= amount {
+			break
+		}
+		selected = append(selected, vtxo)
+		total += vtxo.Amount
+	}
+	if total < amount {
+		return nil, fmt.Errorf("%w: %d sats needed, %d held in VTXOs", ErrInsufficientFunds, amount, total)
+	}
+	return selected, nil
+}
+
+// SpendVTXOs records a settled round that spent treasury VTXOs and returned
+// change, if any, in a new VTXO
+func (t *Treasury) SpendVTXOs(spent []*types.VTXO, change *types.VTXO) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	gone := make(map[string]bool, len(spent))
+	for _, vtxo := range spent {
+		gone[vtxo.Outpoint] = true
+	}
+	kept := make([]*types.VTXO, 0, len(t.vtxos)+1)
+	for _, vtxo := range t.vtxos {
+		if !gone[vtxo.Outpoint] {
+			kept = append(kept, vtxo)
+		}
+	}
+	if change != nil {
+		kept = append(kept, change)
+	}
+	t.vtxos = kept
+}
+
+// Deallocate gives amount of a channel's allocation back to the boarded funds,
+// undoing an Allocate whose channel never took it
+func (t *Treasury) Deallocate(channelID string, amount uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	allocation := t.allocations[channelID]
+	if amount > allocation {
+		amount = allocation
+	}
+	if amount == allocation {
+		delete(t.allocations, channelID)
+	} else {
+		t.allocations[channelID] -= amount
+	}
+	t.allocated -= amount
+	t.available += amount
+}
+
+// Release ends a channel's allocation once the channel is settled. The operator's
+// share of the settlement, returned, is available to other channels again if it
+// came back through the Ark server in vtxo. A share settled on-chain, with a nil
+// vtxo, goes to the wallet and is boarded again from there.
+func (t *Treasury) Release(channelID string, returned uint64, vtxo *types.VTXO) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	allocation, exists := t.allocations[channelID]
+	if !exists {
+		return
+	}
+	delete(t.allocations, channelID)
+	t.allocated -= allocation
+	t.returned += returned
+	if vtxo != nil {
+		t.available += vtxo.Amount
+		t.vtxos = append(t.vtxos, vtxo)
+	}
+}
+
+// Stats returns the treasury's balances and how much of them channels hold
+func (t *Treasury) Stats(ctx context.Context) (*types.TreasuryStats, error) {
+	utxos, err := t.wallet.ListUnspent(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list treasury UTXOs: %w", err)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stats := &types.TreasuryStats{
+		Address:      t.address,
+		OnchainUTXOs: len(utxos),
+		Boarded:      t.boarded,
+		Available:    t.available,
+		Allocated:    t.allocated,
+		Returned:     t.returned,
+		Channels:     len(t.allocations),
+		Boardings:    make([]*types.Boarding, len(t.boardings)),
+	}
+	for _, utxo := range utxos {
+		if t.unswept[utxo.Outpoint] {
+			stats.OnchainUTXOs--
+			continue
+		}
+		stats.OnchainBalance += utxo.Amount
+	}
+	if total := t.available + t.allocated; total > 0 {
+		stats.Utilisation = float64(t.allocated) / float64(total)
+	}
+	copy(stats.Boardings, t.boardings)
+
+	return stats, nil
+}
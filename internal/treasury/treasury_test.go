@@ -0,0 +1,74 @@
+package treasury
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// flakyWallet is a regtest wallet that fails to mark UTXOs spent until fixed
+type flakyWallet struct {
+	*RegtestWallet
+	broken bool
+}
+
+func (w *flakyWallet) Spend(ctx context.Context, outpoints []string) error {
+	if w.broken {
+		return errors.New("wallet is offline")
+	}
+	return w.RegtestWallet.Spend(ctx, outpoints)
+}
+
+// onceBoarder boards every UTXO at most once, like an Ark server
+type onceBoarder struct {
+	boarded map[string]bool
+}
+
+func (b *onceBoarder) Board(ctx context.Context, utxos []*types.UTXO, address string) (*types.VTXO, error) {
+	amount := uint64(0)
+	for _, utxo := range utxos {
+		if b.boarded[utxo.Outpoint] {
+			return nil, errors.New("UTXO " + utxo.Outpoint + " is already boarded")
+		}
+		b.boarded[utxo.Outpoint] = true
+		amount += utxo.Amount
+	}
+	return &types.VTXO{Outpoint: "vtxo:0", Amount: amount}, nil
+}
+
+func TestBoardingSurvivesAWalletThatCannotSpend(t *testing.T) {
+	ctx := context.Background()
+	wallet := &flakyWallet{RegtestWallet: NewRegtestWallet("bcrt1qoperator"), broken: true}
+	if _, err := wallet.Fund(100000, 0); err != nil {
+		t.Fatalf("Fund wallet: %v", err)
+	}
+	if _, err := wallet.Fund(50000, 0); err != nil {
+		t.Fatalf("Fund wallet: %v", err)
+	}
+	treasury := New(wallet, &onceBoarder{boarded: make(map[string]bool)}, "bcrt1qoperator")
+
+	if err := treasury.Fund(ctx, 80000); err != nil {
+		t.Fatalf("Fund: %v", err)
+	}
+	stats, _ := treasury.Stats(ctx)
+	if stats.Boarded != 100000 || stats.Available != 100000 {
+		t.Errorf("boarded %d with %d available, want the boarded VTXO kept", stats.Boarded, stats.Available)
+	}
+	if stats.OnchainBalance != 50000 || stats.OnchainUTXOs != 1 {
+		t.Errorf("on-chain %d sats in %d UTXOs, want only the unboarded one", stats.OnchainBalance, stats.OnchainUTXOs)
+	}
+
+	// The boarded UTXO is skipped next time, and marked spent once the wallet works
+	wallet.broken = false
+	if err := treasury.Fund(ctx, 150000); err != nil {
+		t.Fatalf("Fund after the wallet recovered: %v", err)
+	}
+	if utxos, _ := wallet.ListUnspent(ctx); len(utxos) != 0 {
+		t.Errorf("wallet still lists %d boarded UTXOs", len(utxos))
+	}
+	if stats, _ := treasury.Stats(ctx); stats.Boarded != 150000 {
+		t.Errorf("boarded %d, want 150000", stats.Boarded)
+	}
+}
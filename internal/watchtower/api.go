@@ -0,0 +1,59 @@
+package watchtower
+
+import (
+	"net/http"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupRoutes sets up the tower's API routes
+func (t *Tower) SetupRoutes(r *gin.Engine) {
+	apiGroup := r.Group("/api/v1")
+	{
+		apiGroup.POST("/register", t.handleRegister)
+		apiGroup.GET("/responses", t.handleResponses)
+		apiGroup.GET("/stats", t.handleStats)
+	}
+}
+
+// handleRegister stores a miner's registration
+func (t *Tower) handleRegister(c *gin.Context) {
+	var req types.WatchtowerRegistration
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := t.Register(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    req,
+	})
+}
+
+// handleResponses returns the breaches the tower has answered
+func (t *Tower) handleResponses(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    t.Responses(),
+	})
+}
+
+// handleStats returns what the tower holds
+func (t *Tower) handleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    t.Stats(),
+	})
+}
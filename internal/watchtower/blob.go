@@ -0,0 +1,139 @@
+package watchtower
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+const (
+	// hintTag and keyTag are the BIP-340 tags a revoked state's hint and blob key
+	// are derived under
+	hintTag = "SparkPool/WatchtowerHint"
+	keyTag  = "SparkPool/WatchtowerKey"
+
+	// HintSize is the size of a breach hint in bytes
+	HintSize = 16
+)
+
+// ErrInvalidBlob is returned when a blob does not decrypt to a state
+var ErrInvalidBlob = errors.New("invalid watchtower blob")
+
+// Hint returns the breach hint of a revoked state of a channel. A nil state stands
+// for the channel before its first payment.
+func Hint(channelID string, revoked *types.PaymentUpdate) (string, error) {
+	material, err := breachMaterial(channelID, revoked)
+	if err != nil {
+		return "", err
+	}
+	hint := schnorr.TaggedHash(hintTag, material...)
+	return hex.EncodeToString(hint[:HintSize]), nil
+}
+
+// NewRegistration encrypts the state that revokes an older one under a key only
+// the older state's broadcast reveals. The state signature is part of both the
+// hint and the key, so the tower cannot link a registration to a state it has not
+// seen. The channel before its first payment has no signature: its hint and key
+// depend on the channel ID alone, so the first state is the one a tower could read
+// early.
+func NewRegistration(channelID string, revoked, next *types.PaymentUpdate) (*types.WatchtowerRegistration, error) {
+	if next == nil || next.ChannelID != channelID {
+		return nil, fmt.Errorf("registered state must belong to channel %s", channelID)
+	}
+	if revoked != nil && revoked.SequenceNum >= next.SequenceNum {
+		return nil, fmt.Errorf("state %d does not revoke state %d", next.SequenceNum, revoked.SequenceNum)
+	}
+
+	hint, err := Hint(channelID, revoked)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newCipher(channelID, revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &types.WatchtowerRegistration{
+		Hint: hint,
+		Blob: hex.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)),
+	}, nil
+}
+
+// openBlob decrypts the state a blob holds with the revoked state it was
+// registered against
+func openBlob(channelID string, revoked *types.PaymentUpdate, blob []byte) (*types.PaymentUpdate, error) {
+	gcm, err := newCipher(channelID, revoked)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidBlob)
+	}
+
+	nonce, ciphertext := blob[:gcm.NonceSize()], blob[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBlob, err)
+	}
+
+	var state types.PaymentUpdate
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBlob, err)
+	}
+	if state.ChannelID != channelID {
+		return nil, fmt.Errorf("%w: state is for channel %s", ErrInvalidBlob, state.ChannelID)
+	}
+	return &state, nil
+}
+
+// newCipher derives the AES-256-GCM cipher of the blob registered against a
+// revoked state
+func newCipher(channelID string, revoked *types.PaymentUpdate) (cipher.AEAD, error) {
+	material, err := breachMaterial(channelID, revoked)
+	if err != nil {
+		return nil, err
+	}
+	key := schnorr.TaggedHash(keyTag, material...)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// breachMaterial returns what identifies a revoked state once it is broadcast
+func breachMaterial(channelID string, revoked *types.PaymentUpdate) ([][]byte, error) {
+	if revoked == nil {
+		return [][]byte{[]byte(channelID)}, nil
+	}
+	if revoked.ChannelID != channelID {
+		return nil, fmt.Errorf("state is for channel %s, not %s", revoked.ChannelID, channelID)
+	}
+
+	sig, err := hex.DecodeString(revoked.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state signature: %w", err)
+	}
+	var sequence [8]byte
+	binary.BigEndian.PutUint64(sequence[:], revoked.SequenceNum)
+
+	return [][]byte{[]byte(channelID), sequence[:], sig}, nil
+}
@@ -0,0 +1,115 @@
+package watchtower
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// testState returns a signed-looking state of a channel
+func testState(channelID string, sequence uint64, sig byte) *types.PaymentUpdate {
+	return &types.PaymentUpdate{
+		ChannelID:    channelID,
+		SequenceNum:  sequence,
+		MinerBalance: sequence * 1000,
+		Signature:    strings.Repeat(hex.EncodeToString([]byte{sig}), 64),
+	}
+}
+
+func TestBlobRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		revoked *types.PaymentUpdate
+		next    *types.PaymentUpdate
+	}{
+		{"first state", nil, testState("chan-1", 1, 0x01)},
+		{"later state", testState("chan-1", 1, 0x01), testState("chan-1", 2, 0x02)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := NewRegistration("chan-1", tt.revoked, tt.next)
+			if err != nil {
+				t.Fatalf("NewRegistration: %v", err)
+			}
+			hint, err := Hint("chan-1", tt.revoked)
+			if err != nil {
+				t.Fatalf("Hint: %v", err)
+			}
+			if reg.Hint != hint || len(reg.Hint) != 2*HintSize {
+				t.Errorf("registration hint %s, want %s", reg.Hint, hint)
+			}
+
+			blob, _ := hex.DecodeString(reg.Blob)
+			state, err := openBlob("chan-1", tt.revoked, blob)
+			if err != nil {
+				t.Fatalf("openBlob: %v", err)
+			}
+			if state.SequenceNum != tt.next.SequenceNum || state.Signature != tt.next.Signature {
+				t.Errorf("opened state %d, want %d", state.SequenceNum, tt.next.SequenceNum)
+			}
+		})
+	}
+}
+
+func TestBlobNeedsTheRevokedState(t *testing.T) {
+	revoked := testState("chan-1", 1, 0x01)
+	reg, err := NewRegistration("chan-1", revoked, testState("chan-1", 2, 0x02))
+	if err != nil {
+		t.Fatalf("NewRegistration: %v", err)
+	}
+	blob, _ := hex.DecodeString(reg.Blob)
+
+	// Another signature over the same sequence number derives another key
+	if _, err := openBlob("chan-1", testState("chan-1", 1, 0x03), blob); !errors.Is(err, ErrInvalidBlob) {
+		t.Errorf("opened with another state's signature: %v", err)
+	}
+	if _, err := openBlob("chan-1", nil, blob); !errors.Is(err, ErrInvalidBlob) {
+		t.Errorf("opened without the revoked state: %v", err)
+	}
+
+	// Tampering with the ciphertext breaks the GCM tag
+	blob[len(blob)-1] ^= 1
+	if _, err := openBlob("chan-1", revoked, blob); !errors.Is(err, ErrInvalidBlob) {
+		t.Errorf("opened a tampered blob: %v", err)
+	}
+}
+
+func TestHintsDependOnTheRevokedState(t *testing.T) {
+	hints := make(map[string]string)
+	for name, revoked := range map[string]*types.PaymentUpdate{
+		"before first payment": nil,
+		"state 1":              testState("chan-1", 1, 0x01),
+		"state 1 resigned":     testState("chan-1", 1, 0x02),
+		"state 2":              testState("chan-1", 2, 0x01),
+	} {
+		hint, err := Hint("chan-1", revoked)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if other, exists := hints[hint]; exists {
+			t.Errorf("%s and %s share a hint", name, other)
+		}
+		hints[hint] = name
+	}
+
+	other, _ := Hint("chan-2", nil)
+	if _, exists := hints[other]; exists {
+		t.Error("two channels share their first hint")
+	}
+}
+
+func TestNewRegistrationRejectsStatesThatDoNotRevoke(t *testing.T) {
+	if _, err := NewRegistration("chan-1", testState("chan-1", 2, 0x01), testState("chan-1", 2, 0x02)); err == nil {
+		t.Error("registered a state with the revoked state's sequence number")
+	}
+	if _, err := NewRegistration("chan-1", nil, testState("chan-2", 1, 0x01)); err == nil {
+		t.Error("registered another channel's state")
+	}
+	if _, err := NewRegistration("chan-1", testState("chan-2", 1, 0x01), testState("chan-1", 2, 0x02)); err == nil {
+		t.Error("registered against another channel's revoked state")
+	}
+}
@@ -0,0 +1,163 @@
+package watchtower
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/gorilla/websocket"
+)
+
+// chainEventMessage is the WebSocket message type the pool broadcasts chain events as
+const chainEventMessage = "chain_event"
+
+// requestTimeout bounds every HTTP request made to the pool or a tower
+const requestTimeout = 10 * time.Second
+
+// Client registers states with a tower running behind its HTTP API
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the tower at baseURL, e.g. http://localhost:8090
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Register hands a registration to the remote tower
+func (c *Client) Register(reg *types.WatchtowerRegistration) error {
+	return postJSON(c.http, c.baseURL+"/api/v1/register", reg, nil)
+}
+
+// PoolClient is the Chain of a pool reached over its HTTP API. Chain events arrive
+// over the pool's WebSocket once Run is called.
+type PoolClient struct {
+	baseURL   string
+	http      *http.Client
+	observers []func(event *types.ChainEvent)
+	mu        sync.RWMutex
+}
+
+// NewPoolClient creates a client for the pool at baseURL, e.g. http://localhost:8080
+func NewPoolClient(baseURL string) *PoolClient {
+	return &PoolClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// OnChainEvent registers an observer for the pool's chain events
+func (pc *PoolClient) OnChainEvent(observer func(event *types.ChainEvent)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.observers = append(pc.observers, observer)
+}
+
+// ChallengeExit presents a state against a channel's exit through the pool's API
+func (pc *PoolClient) ChallengeExit(channelID, party string, state *types.PaymentUpdate) (*types.DisputeEvent, error) {
+	var event types.DisputeEvent
+	endpoint := pc.baseURL + "/api/v1/channels/" + url.PathEscape(channelID) + "/exit/challenge"
+	err := postJSON(pc.http, endpoint, types.ExitChallengeRequest{Party: party, State: state}, &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Run follows the pool's WebSocket and hands chain events to observers until ctx
+// is done, reconnecting whenever the connection drops
+func (pc *PoolClient) Run(ctx context.Context, onError func(err error)) {
+	wsURL := "ws" + strings.TrimPrefix(pc.baseURL, "http") + "/ws"
+
+	for ctx.Err() == nil {
+		if err := pc.follow(ctx, wsURL); err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// follow reads chain events from one WebSocket connection
+func (pc *PoolClient) follow(ctx context.Context, wsURL string) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to pool: %w", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var message struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			return fmt.Errorf("pool connection lost: %w", err)
+		}
+		if message.Type != chainEventMessage {
+			continue
+		}
+
+		var event types.ChainEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return fmt.Errorf("invalid chain event: %w", err)
+		}
+
+		pc.mu.RLock()
+		observers := pc.observers
+		pc.mu.RUnlock()
+		for _, observer := range observers {
+			observer(&event)
+		}
+	}
+}
+
+// postJSON posts body to an API endpoint and decodes the response data into out
+func postJSON(client *http.Client, endpoint string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", endpoint, err)
+	}
+	if !apiResp.Success {
+		return fmt.Errorf("%s: %s", resp.Status, apiResp.Error)
+	}
+
+	if out != nil {
+		return json.Unmarshal(apiResp.Data, out)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package watchtower
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/ark"
+	"github.com/chdwlch/spark-pool/internal/channel"
+	"github.com/chdwlch/spark-pool/internal/keys"
+	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/internal/treasury"
+	"github.com/chdwlch/spark-pool/pkg/schnorr"
+	"github.com/chdwlch/spark-pool/pkg/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// newTestPool creates a pool on a mock Ark server with a funded treasury
+func newTestPool(t *testing.T) *pool.Manager {
+	t.Helper()
+
+	keystore, err := keys.Open(filepath.Join(t.TempDir(), "keystore.json"), "test")
+	if err != nil {
+		t.Fatalf("open keystore: %v", err)
+	}
+	serverKey, err := keystore.ServerKey()
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+	wallet := treasury.NewRegtestWallet("bcrt1qoperator")
+	if _, err := wallet.Fund(treasury.DefaultRegtestFunding, pool.DefaultStartHeight); err != nil {
+		t.Fatalf("fund wallet: %v", err)
+	}
+
+	asp := ark.NewMockASP(serverKey)
+	pm, err := pool.NewManager(pool.Config{OperatorAddress: "bcrt1qoperator"}, keystore, asp, wallet)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(pm.Close)
+	asp.SetHeight(pm.BlockHeight())
+	pm.OnChainEvent(asp.HandleChainEvent)
+	return pm
+}
+
+// joinMiner joins a miner with a fresh key to the pool and returns its channel ID
+func joinMiner(t *testing.T, pm *pool.Manager) string {
+	t.Helper()
+
+	minerKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate miner key: %v", err)
+	}
+	challenge, err := pm.NewJoinChallenge()
+	if err != nil {
+		t.Fatalf("join challenge: %v", err)
+	}
+	challengeBytes, _ := hex.DecodeString(challenge.Challenge)
+	digest := pool.JoinDigest(challengeBytes, minerKey.PubKey())
+	sig, err := schnorr.Sign(minerKey, digest[:])
+	if err != nil {
+		t.Fatalf("sign join challenge: %v", err)
+	}
+
+	miner, err := pm.AddMiner(context.Background(), types.JoinPoolRequest{
+		MinerName: "miner",
+		Address:   "bcrt1qminer",
+		HashRate:  1e12,
+		PubKey:    hex.EncodeToString(minerKey.PubKey().SerializeCompressed()),
+		Challenge: challenge.Challenge,
+		Signature: hex.EncodeToString(sig),
+	})
+	if err != nil {
+		t.Fatalf("AddMiner: %v", err)
+	}
+	return miner.ChannelID
+}
+
+// registrar registers every state the pool signs with a tower, as an online miner
+// does before going offline
+type registrar struct {
+	tower  *Tower
+	latest map[string]*types.PaymentUpdate
+	states int
+	mu     sync.Mutex
+}
+
+func newRegistrar(t *testing.T, pm *pool.Manager, tower *Tower) *registrar {
+	r := &registrar{tower: tower, latest: make(map[string]*types.PaymentUpdate)}
+	pm.OnPaymentUpdate(func(minerID string, update *types.PaymentUpdate) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		reg, err := NewRegistration(update.ChannelID, r.latest[update.ChannelID], update)
+		if err != nil {
+			t.Errorf("NewRegistration: %v", err)
+			return
+		}
+		if err := tower.Register(reg); err != nil {
+			t.Errorf("Register: %v", err)
+			return
+		}
+		r.latest[update.ChannelID] = update
+		r.states++
+	})
+	return r
+}
+
+// waitForStates waits until n states have been registered
+func (r *registrar) waitForStates(t *testing.T, n int) {
+	t.Helper()
+	waitFor(t, "state registrations", func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.states >= n
+	})
+}
+
+// waitFor polls cond until it holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// payBlocks pays n blocks to the pool's miners and returns a channel's states
+func payBlocks(t *testing.T, pm *pool.Manager, channelID string, n int) []*types.PaymentUpdate {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if _, err := pm.ProcessBlockReward(context.Background(), types.ProcessBlockRequest{}); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+	}
+	ch, exists := pm.GetChannel(channelID)
+	if !exists {
+		t.Fatalf("channel %s not found", channelID)
+	}
+	return ch.PaymentHistory
+}
+
+func TestTowerCatchesRevokedOperatorExit(t *testing.T) {
+	pm := newTestPool(t)
+	tower := New(pm)
+	registrar := newRegistrar(t, pm, tower)
+
+	channelID := joinMiner(t, pm)
+	states := payBlocks(t, pm, channelID, 3)
+	registrar.waitForStates(t, len(states))
+	revoked, latest := states[0], states[len(states)-1]
+
+	// The operator exits with a state the miner has since been paid past
+	ch, err := pm.StartUnilateralExit(channelID, channel.PartyOperator, revoked)
+	if err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+
+	waitFor(t, "the tower's response", func() bool { return len(tower.Responses()) == 1 })
+	response := tower.Responses()[0]
+	if response.Error != "" || response.Dispute == nil {
+		t.Fatalf("tower did not challenge the exit: %+v", response)
+	}
+	if response.BreachSequence != revoked.SequenceNum || response.JusticeSequence != latest.SequenceNum {
+		t.Errorf("tower answered state %d with %d, want %d with %d",
+			response.BreachSequence, response.JusticeSequence, revoked.SequenceNum, latest.SequenceNum)
+	}
+	if !response.Dispute.Fraud || response.Dispute.ReportedBy != Party {
+		t.Errorf("dispute %+v is not a fraud reported by the tower", response.Dispute)
+	}
+
+	disputes, _ := pm.GetChannelDisputes(channelID)
+	if len(disputes) != 1 {
+		t.Fatalf("%d disputes on the channel, want 1", len(disputes))
+	}
+	if ch.Exit.State.SequenceNum != latest.SequenceNum || ch.Exit.Justice == nil {
+		t.Errorf("exit settles on state %d, want %d with a justice settlement", ch.Exit.State.SequenceNum, latest.SequenceNum)
+	}
+}
+
+func TestTowerSkipsForgedStatesAgainstThePool(t *testing.T) {
+	pm := newTestPool(t)
+	tower := New(pm)
+	registrar := newRegistrar(t, pm, tower)
+
+	channelID := joinMiner(t, pm)
+	states := payBlocks(t, pm, channelID, 2)
+	registrar.waitForStates(t, len(states))
+	revoked, latest := states[0], states[len(states)-1]
+
+	// Anyone who saw the revoked state can register a forgery against it, with a
+	// higher sequence number than any real state
+	forged, err := NewRegistration(channelID, revoked, &types.PaymentUpdate{
+		ChannelID:    channelID,
+		SequenceNum:  latest.SequenceNum + 100,
+		MinerBalance: 1,
+		Signature:    hex.EncodeToString(make([]byte, 64)),
+	})
+	if err != nil {
+		t.Fatalf("NewRegistration: %v", err)
+	}
+	if err := tower.Register(forged); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if _, err := pm.StartUnilateralExit(channelID, channel.PartyOperator, revoked); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+
+	// The pool refuses the forgery, so the tower falls back to the miner's state
+	waitFor(t, "the tower's response", func() bool { return len(tower.Responses()) == 1 })
+	response := tower.Responses()[0]
+	if response.Error != "" || response.JusticeSequence != latest.SequenceNum {
+		t.Errorf("tower answered with state %d (%s), want %d", response.JusticeSequence, response.Error, latest.SequenceNum)
+	}
+}
+
+func TestTowerIgnoresCurrentExits(t *testing.T) {
+	pm := newTestPool(t)
+	tower := New(pm)
+	registrar := newRegistrar(t, pm, tower)
+
+	channelID := joinMiner(t, pm)
+	states := payBlocks(t, pm, channelID, 2)
+	registrar.waitForStates(t, len(states))
+
+	// The operator exits on the latest state, which no registration revokes
+	if _, err := pm.StartUnilateralExit(channelID, channel.PartyOperator, nil); err != nil {
+		t.Fatalf("StartUnilateralExit: %v", err)
+	}
+	if err := pm.AdvanceChain(pm.BlockHeight() + 1); err != nil {
+		t.Fatalf("AdvanceChain: %v", err)
+	}
+
+	// The tower has seen the exit once it has seen the block after it
+	height := pm.BlockHeight()
+	waitFor(t, "the tower to follow the chain", func() bool { return tower.Stats().Height == height })
+	if stats := tower.Stats(); stats.Breaches != 0 || stats.Registrations != len(states) {
+		t.Errorf("tower saw %d breaches and holds %d registrations, want 0 and %d", stats.Breaches, stats.Registrations, len(states))
+	}
+	if disputes := pm.GetDisputes(); len(disputes) != 0 {
+		t.Errorf("%d disputes raised against a current exit", len(disputes))
+	}
+}
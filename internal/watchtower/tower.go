@@ -0,0 +1,226 @@
+package watchtower
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// Party is the name a tower presents its challenges under
+const Party = "watchtower"
+
+// Chain is the part of the pool a tower watches and answers exits on
+type Chain interface {
+	OnChainEvent(observer func(event *types.ChainEvent))
+	ChallengeExit(channelID, party string, state *types.PaymentUpdate) (*types.DisputeEvent, error)
+}
+
+// breach is a stale exit the tower holds later states for. States are tried newest
+// first until the pool accepts one.
+type breach struct {
+	states   []*types.PaymentUpdate
+	response *types.WatchtowerResponse
+}
+
+// Tower watches the pool's chain on behalf of miners that may be offline. Miners
+// register every state they revoke with the state that revoked it; when a revoked
+// state is broadcast in an exit, the tower recovers the newest state it holds for
+// the channel and challenges the exit with it, retrying on every block until the
+// exit's CSV delay runs out.
+//
+// Registration is unauthenticated and the hint of a channel's first state only
+// depends on its public ID, so a hint may hold blobs from anyone. The tower keeps
+// every blob registered under a hint and lets the pool's signature checks pick
+// the genuine state.
+type Tower struct {
+	chain     Chain
+	blobs     map[string][][]byte
+	pending   map[string]*breach
+	responses []*types.WatchtowerResponse
+	height    uint64
+	mu        sync.Mutex
+}
+
+// New creates a tower watching chain
+func New(chain Chain) *Tower {
+	t := &Tower{
+		chain:     chain,
+		blobs:     make(map[string][][]byte),
+		pending:   make(map[string]*breach),
+		responses: make([]*types.WatchtowerResponse, 0),
+	}
+	chain.OnChainEvent(t.HandleChainEvent)
+	return t
+}
+
+// Register stores a revoked state's hint with the encrypted state revoking it,
+// next to any blob already registered under the hint
+func (t *Tower) Register(reg *types.WatchtowerRegistration) error {
+	hint, err := hex.DecodeString(reg.Hint)
+	if err != nil || len(hint) != HintSize {
+		return fmt.Errorf("hint must be %d hex-encoded bytes", HintSize)
+	}
+	blob, err := hex.DecodeString(reg.Blob)
+	if err != nil || len(blob) == 0 {
+		return fmt.Errorf("%w: blob must be hex-encoded", ErrInvalidBlob)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, registered := range t.blobs[reg.Hint] {
+		if bytes.Equal(registered, blob) {
+			return nil
+		}
+	}
+	t.blobs[reg.Hint] = append(t.blobs[reg.Hint], blob)
+
+	return nil
+}
+
+// HandleChainEvent looks for breaches in exits and answers the pending ones
+func (t *Tower) HandleChainEvent(event *types.ChainEvent) {
+	t.mu.Lock()
+	if event.Height > t.height {
+		t.height = event.Height
+	}
+	if event.Type == pool.ChainEventExit && event.Exit != nil {
+		t.checkExit(event.ChannelID, event.Exit)
+	}
+	t.mu.Unlock()
+
+	t.respond()
+}
+
+// checkExit opens every blob registered against an exit's state and follows each
+// chain of blobs to the newest states the tower holds for the channel
+func (t *Tower) checkExit(channelID string, exit *types.ChannelExit) {
+	var states []*types.PaymentUpdate
+	visited := make(map[string]bool)
+	revoked := []*types.PaymentUpdate{exit.State}
+	for len(revoked) > 0 {
+		state := revoked[len(revoked)-1]
+		revoked = revoked[:len(revoked)-1]
+
+		hint, err := Hint(channelID, state)
+		if err != nil || visited[hint] {
+			continue
+		}
+		visited[hint] = true
+
+		for _, blob := range t.blobs[hint] {
+			next, err := openBlob(channelID, state, blob)
+			if err != nil {
+				continue
+			}
+			if state != nil && next.SequenceNum <= state.SequenceNum {
+				continue
+			}
+			states = append(states, next)
+			revoked = append(revoked, next)
+		}
+		delete(t.blobs, hint)
+	}
+	if len(states) == 0 {
+		return
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].SequenceNum > states[j].SequenceNum
+	})
+
+	response := &types.WatchtowerResponse{
+		ChannelID:       channelID,
+		JusticeSequence: states[0].SequenceNum,
+		MaturityHeight:  exit.MaturityHeight,
+	}
+	if exit.State != nil {
+		response.BreachSequence = exit.State.SequenceNum
+	}
+	t.pending[channelID] = &breach{states: states, response: response}
+}
+
+// respond challenges every pending breach. The pool is called without the tower
+// lock held, since it may be registering a miner's state at the same time.
+func (t *Tower) respond() {
+	t.mu.Lock()
+	height := t.height
+	breaches := make(map[string]*breach, len(t.pending))
+	for channelID, b := range t.pending {
+		breaches[channelID] = b
+	}
+	t.mu.Unlock()
+
+	for channelID, b := range breaches {
+		response := b.response
+		response.Height = height
+		response.Timestamp = time.Now()
+
+		if height < response.MaturityHeight {
+			event, state, err := t.challenge(channelID, b.states)
+			if err != nil {
+				// Try again on the next block
+				response.Error = err.Error()
+				continue
+			}
+			response.JusticeSequence = state.SequenceNum
+			response.Dispute = event
+			response.Error = ""
+		} else if response.Error == "" {
+			response.Error = "exit matured before the tower could answer"
+		}
+
+		t.mu.Lock()
+		delete(t.pending, channelID)
+		t.responses = append(t.responses, response)
+		t.mu.Unlock()
+	}
+}
+
+// challenge challenges an exit with the newest state the pool accepts. Forged
+// states fail the pool's signature checks and are skipped.
+func (t *Tower) challenge(channelID string, states []*types.PaymentUpdate) (*types.DisputeEvent, *types.PaymentUpdate, error) {
+	var firstErr error
+	for _, state := range states {
+		event, err := t.chain.ChallengeExit(channelID, Party, state)
+		if err == nil {
+			return event, state, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, nil, firstErr
+}
+
+// Responses returns the breaches the tower has answered or given up on, oldest first
+func (t *Tower) Responses() []*types.WatchtowerResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	responses := make([]*types.WatchtowerResponse, len(t.responses))
+	copy(responses, t.responses)
+	return responses
+}
+
+// Stats returns what the tower holds and has done
+func (t *Tower) Stats() *types.WatchtowerStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	registrations := 0
+	for _, blobs := range t.blobs {
+		registrations += len(blobs)
+	}
+
+	return &types.WatchtowerStats{
+		Registrations: registrations,
+		Breaches:      len(t.responses) + len(t.pending),
+		Pending:       len(t.pending),
+		Height:        t.height,
+	}
+}
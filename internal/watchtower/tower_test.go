@@ -0,0 +1,87 @@
+package watchtower
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/pkg/types"
+)
+
+// fakeChain accepts challenges with the states it signed and records them
+type fakeChain struct {
+	signed     map[string]bool
+	challenged []*types.PaymentUpdate
+}
+
+func (c *fakeChain) OnChainEvent(observer func(event *types.ChainEvent)) {}
+
+func (c *fakeChain) ChallengeExit(channelID, party string, state *types.PaymentUpdate) (*types.DisputeEvent, error) {
+	if !c.signed[state.Signature] {
+		return nil, fmt.Errorf("invalid state signature")
+	}
+	c.challenged = append(c.challenged, state)
+	return &types.DisputeEvent{ChannelID: channelID}, nil
+}
+
+func register(t *testing.T, tower *Tower, channelID string, revoked, next *types.PaymentUpdate) {
+	t.Helper()
+
+	reg, err := NewRegistration(channelID, revoked, next)
+	if err != nil {
+		t.Fatalf("NewRegistration: %v", err)
+	}
+	if err := tower.Register(reg); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+}
+
+func TestTowerKeepsEveryBlobUnderAHint(t *testing.T) {
+	state1 := testState("chan-1", 1, 0x01)
+	state2 := testState("chan-1", 2, 0x02)
+	chain := &fakeChain{signed: map[string]bool{state1.Signature: true, state2.Signature: true}}
+	tower := New(chain)
+
+	// The miner's chain of states, then a forgery under the public first hint
+	register(t, tower, "chan-1", nil, state1)
+	register(t, tower, "chan-1", state1, state2)
+	register(t, tower, "chan-1", nil, testState("chan-1", 9, 0x09))
+	if got := tower.Stats().Registrations; got != 3 {
+		t.Fatalf("tower holds %d registrations, want 3", got)
+	}
+
+	// The operator exits on the channel before its first payment
+	tower.HandleChainEvent(&types.ChainEvent{
+		Type:      pool.ChainEventExit,
+		Height:    100,
+		ChannelID: "chan-1",
+		Exit:      &types.ChannelExit{BroadcastHeight: 100, MaturityHeight: 244},
+	})
+
+	if len(chain.challenged) != 1 || chain.challenged[0].SequenceNum != 2 {
+		t.Fatalf("challenged with %v, want the miner's state 2", chain.challenged)
+	}
+	responses := tower.Responses()
+	if len(responses) != 1 || responses[0].JusticeSequence != 2 || responses[0].Error != "" {
+		t.Fatalf("responses %+v", responses)
+	}
+	if got := tower.Stats().Registrations; got != 0 {
+		t.Errorf("tower still holds %d registrations after the breach", got)
+	}
+}
+
+func TestTowerIgnoresDuplicateRegistrations(t *testing.T) {
+	tower := New(&fakeChain{})
+	reg, err := NewRegistration("chan-1", nil, testState("chan-1", 1, 0x01))
+	if err != nil {
+		t.Fatalf("NewRegistration: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := tower.Register(reg); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if got := tower.Stats().Registrations; got != 1 {
+		t.Errorf("tower holds %d registrations, want 1", got)
+	}
+}
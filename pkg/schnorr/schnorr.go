@@ -0,0 +1,170 @@
+// Package schnorr implements BIP-340 Schnorr signatures over secp256k1.
+package schnorr
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Sizes of BIP-340 keys, messages and signatures
+const (
+	PubKeySize    = 32
+	MessageSize   = 32
+	SignatureSize = 64
+)
+
+// ErrInvalidSignature is returned when a signature does not verify
+var ErrInvalidSignature = errors.New("invalid schnorr signature")
+
+// TaggedHash computes the BIP-340 tagged hash SHA256(SHA256(tag) || SHA256(tag) || msgs...)
+func TaggedHash(tag string, msgs ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+
+	var result [32]byte
+	copy(result[:], h.Sum(nil))
+	return result
+}
+
+// SerializePubKey returns the 32-byte x-only encoding of a public key
+func SerializePubKey(key *secp256k1.PublicKey) []byte {
+	return key.SerializeCompressed()[1:]
+}
+
+// ParsePubKey parses a 32-byte x-only public key, lifting it to the point with even Y
+func ParsePubKey(pubKey []byte) (*secp256k1.PublicKey, error) {
+	if len(pubKey) != PubKeySize {
+		return nil, fmt.Errorf("x-only public key must be %d bytes, got %d", PubKeySize, len(pubKey))
+	}
+	return secp256k1.ParsePubKey(append([]byte{0x02}, pubKey...))
+}
+
+// Sign produces a BIP-340 signature of a 32-byte message
+func Sign(privKey *secp256k1.PrivateKey, msg []byte) ([]byte, error) {
+	var auxRand [32]byte
+	if _, err := rand.Read(auxRand[:]); err != nil {
+		return nil, fmt.Errorf("failed to read auxiliary randomness: %w", err)
+	}
+	return sign(privKey, msg, auxRand)
+}
+
+// sign produces a BIP-340 signature of a 32-byte message with the given
+// auxiliary randomness
+func sign(privKey *secp256k1.PrivateKey, msg []byte, auxRand [32]byte) ([]byte, error) {
+	if len(msg) != MessageSize {
+		return nil, fmt.Errorf("message must be %d bytes, got %d", MessageSize, len(msg))
+	}
+
+	// Use the secret key whose public key has an even Y coordinate
+	d := privKey.Key
+	pubKey := privKey.PubKey()
+	if pubKey.SerializeCompressed()[0] == secp256k1.PubKeyFormatCompressedOdd {
+		d.Negate()
+	}
+	pubKeyBytes := SerializePubKey(pubKey)
+
+	// t = bytes(d) xor hash_aux(a)
+	dBytes := d.Bytes()
+	auxHash := TaggedHash("BIP0340/aux", auxRand[:])
+	var t [32]byte
+	for i := range t {
+		t[i] = dBytes[i] ^ auxHash[i]
+	}
+
+	nonceHash := TaggedHash("BIP0340/nonce", t[:], pubKeyBytes, msg)
+	var k secp256k1.ModNScalar
+	k.SetBytes(&nonceHash)
+	if k.IsZero() {
+		return nil, fmt.Errorf("generated nonce is zero")
+	}
+
+	var r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k, &r)
+	r.ToAffine()
+	if r.Y.IsOdd() {
+		k.Negate()
+	}
+	rBytes := r.X.Bytes()
+
+	e := challenge(rBytes[:], pubKeyBytes, msg)
+
+	// s = k + e*d mod n
+	s := new(secp256k1.ModNScalar).Mul2(&e, &d).Add(&k)
+	sBytes := s.Bytes()
+
+	sig := make([]byte, 0, SignatureSize)
+	sig = append(sig, rBytes[:]...)
+	sig = append(sig, sBytes[:]...)
+
+	// Never hand out a signature that would not verify
+	if err := Verify(pubKeyBytes, msg, sig); err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// Verify checks a BIP-340 signature of a 32-byte message against an x-only public key
+func Verify(pubKey, msg, sig []byte) error {
+	if len(msg) != MessageSize {
+		return fmt.Errorf("message must be %d bytes, got %d", MessageSize, len(msg))
+	}
+	if len(sig) != SignatureSize {
+		return fmt.Errorf("signature must be %d bytes, got %d", SignatureSize, len(sig))
+	}
+
+	key, err := ParsePubKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	var r secp256k1.FieldVal
+	if overflow := r.SetByteSlice(sig[:32]); overflow {
+		return ErrInvalidSignature
+	}
+
+	var s secp256k1.ModNScalar
+	if overflow := s.SetByteSlice(sig[32:]); overflow {
+		return ErrInvalidSignature
+	}
+
+	e := challenge(sig[:32], pubKey, msg)
+
+	// R = s*G - e*P
+	var p, sG, eP, result secp256k1.JacobianPoint
+	key.AsJacobian(&p)
+	secp256k1.ScalarBaseMultNonConst(&s, &sG)
+	e.Negate()
+	secp256k1.ScalarMultNonConst(&e, &p, &eP)
+	secp256k1.AddNonConst(&sG, &eP, &result)
+
+	if (result.X.IsZero() && result.Y.IsZero()) || result.Z.IsZero() {
+		return ErrInvalidSignature
+	}
+
+	result.ToAffine()
+	if result.Y.IsOdd() || !result.X.Equals(&r) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// challenge computes e = int(hash_challenge(r || P || m)) mod n
+func challenge(r, pubKey, msg []byte) secp256k1.ModNScalar {
+	hash := TaggedHash("BIP0340/challenge", r, pubKey, msg)
+
+	var e secp256k1.ModNScalar
+	e.SetBytes(&hash)
+	return e
+}
@@ -0,0 +1,201 @@
+package schnorr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ToLower(s))
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+// BIP-340 test vectors with a secret key: signing must reproduce the signature
+func TestSignVectors(t *testing.T) {
+	tests := []struct {
+		secretKey string
+		pubKey    string
+		auxRand   string
+		msg       string
+		sig       string
+	}{
+		{
+			secretKey: "0000000000000000000000000000000000000000000000000000000000000003",
+			pubKey:    "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			auxRand:   "0000000000000000000000000000000000000000000000000000000000000000",
+			msg:       "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:       "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			secretKey: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			pubKey:    "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			auxRand:   "0000000000000000000000000000000000000000000000000000000000000001",
+			msg:       "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:       "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+		{
+			secretKey: "C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9",
+			pubKey:    "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+			auxRand:   "C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906",
+			msg:       "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+			sig:       "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		},
+		{
+			secretKey: "0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710",
+			pubKey:    "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+			auxRand:   "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			msg:       "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			sig:       "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3",
+		},
+	}
+
+	for i, tt := range tests {
+		privKey := secp256k1.PrivKeyFromBytes(mustHex(t, tt.secretKey))
+		if got := SerializePubKey(privKey.PubKey()); !bytes.Equal(got, mustHex(t, tt.pubKey)) {
+			t.Errorf("vector %d: public key %x", i, got)
+		}
+
+		var auxRand [32]byte
+		copy(auxRand[:], mustHex(t, tt.auxRand))
+		sig, err := sign(privKey, mustHex(t, tt.msg), auxRand)
+		if err != nil {
+			t.Fatalf("vector %d: sign: %v", i, err)
+		}
+		if !bytes.Equal(sig, mustHex(t, tt.sig)) {
+			t.Errorf("vector %d: signature %X", i, sig)
+		}
+
+		if err := Verify(mustHex(t, tt.pubKey), mustHex(t, tt.msg), mustHex(t, tt.sig)); err != nil {
+			t.Errorf("vector %d: verify: %v", i, err)
+		}
+	}
+}
+
+// BIP-340 verification-only test vectors
+func TestVerifyVectors(t *testing.T) {
+	const (
+		pubKey = "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659"
+		msg    = "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89"
+	)
+
+	tests := []struct {
+		name   string
+		pubKey string
+		msg    string
+		sig    string
+		valid  bool
+	}{
+		{
+			name:   "vector 4",
+			pubKey: "D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9",
+			msg:    "4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703",
+			sig:    "00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4",
+			valid:  true,
+		},
+		{
+			name:   "vector 5: public key not on the curve",
+			pubKey: "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+			msg:    msg,
+			sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		},
+		{
+			name:   "vector 6: R has an odd Y",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2",
+		},
+		{
+			name:   "vector 7: negated message",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD",
+		},
+		{
+			name:   "vector 8: negated s",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769961764B3AA9B2FFCB6EF947B6887A226E8D7C93E00C5ED0C1834FF0D0C2E6DA6",
+		},
+		{
+			name:   "vector 9: R is the point at infinity",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "0000000000000000000000000000000000000000000000000000000000000000123DDA8328AF9C23A94C1FEECFD123BA4FB73476F0D594DCB65C6425BD186051",
+		},
+		{
+			name:   "vector 10: R is the point at infinity",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "00000000000000000000000000000000000000000000000000000000000000017615FBAF5AE28864013C099742DEADB4DBA87F11AC6754F93780D5A1837CF197",
+		},
+		{
+			name:   "vector 11: r is not an x coordinate on the curve",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		},
+		{
+			name:   "vector 12: r equals the field size",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		},
+		{
+			name:   "vector 13: s equals the curve order",
+			pubKey: pubKey,
+			msg:    msg,
+			sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		},
+		{
+			name:   "vector 14: public key exceeds the field size",
+			pubKey: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC30",
+			msg:    msg,
+			sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(mustHex(t, tt.pubKey), mustHex(t, tt.msg), mustHex(t, tt.sig))
+			if tt.valid && err != nil {
+				t.Errorf("valid signature rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("invalid signature accepted")
+			}
+		})
+	}
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	msg := TaggedHash("SparkPool/Test", []byte("state"))
+
+	sig, err := Sign(privKey, msg[:])
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	pubKey := SerializePubKey(privKey.PubKey())
+	if err := Verify(pubKey, msg[:], sig); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	msg[0] ^= 1
+	if err := Verify(pubKey, msg[:], sig); err == nil {
+		t.Error("signature verified for a different message")
+	}
+	if _, err := Sign(privKey, []byte("short")); err == nil {
+		t.Error("signed a message that is not 32 bytes")
+	}
+}
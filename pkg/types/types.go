@@ -16,9 +16,19 @@ type MiningPool struct {
 	Miners          map[string]*Miner         `json:"miners"`
 	ActiveChannels  map[string]*Channel       `json:"active_channels"`
 	CreatedAt       time.Time                 `json:"created_at"`
+
+	PayoutScheme        string              `json:"payout_scheme"`
+	FeeBasisPoints      uint32              `json:"fee_basis_points"`
+	DonationAddress     string              `json:"donation_address,omitempty"`
+	DonationBasisPoints uint32              `json:"donation_basis_points"`
+	OperatorFeesEarned  uint64              `json:"operator_fees_earned"`
+	DonationsPaid       uint64              `json:"donations_paid"`
+	ClosedChannels      map[string]*Channel `json:"closed_channels"`
 }
 
-// Miner represents a miner in the pool
+// Miner represents a miner in the pool. CurrentBalance is what the pool has paid
+// the miner through its channel, and PendingBalance what it has earned but not yet
+// been paid.
 type Miner struct {
 	ID             string    `json:"id"`
 	Address        string    `json:"address"`
@@ -30,21 +40,111 @@ type Miner struct {
 	LastActivity   time.Time `json:"last_activity"`
 	IsActive       bool      `json:"is_active"`
 	ChannelID      string    `json:"channel_id"`
+
+	AcceptedShares  uint64    `json:"accepted_shares"`
+	LastShareAt     time.Time `json:"last_share_at"`
+	FeeBasisPoints  *uint32   `json:"fee_basis_points,omitempty"`
+	PendingBalance  uint64    `json:"pending_balance"`
+	PayoutThreshold uint64    `json:"payout_threshold,omitempty"`
+	LastPayoutAt    time.Time `json:"last_payout_at"`
+	Withdrawn       uint64    `json:"withdrawn"`
+}
+
+// Share represents an accepted share submitted by a miner
+type Share struct {
+	MinerID    string    `json:"miner_id"`
+	Difficulty float64   `json:"difficulty"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
-// Channel represents a Virtual Channel between pool operator and miner
+// Channel represents a Virtual Channel between pool operator and miner.
+// InitialFunding is the channel's capacity, top-ups included, and CurrentBalance
+// the operator's side of its latest signed state: what the channel can still pay
+// the miner. The miner's side is the difference.
 type Channel struct {
 	ID              string                    `json:"id"`
 	PoolOperatorKey *secp256k1.PublicKey     `json:"pool_operator_key"`
 	MinerKey        *secp256k1.PublicKey     `json:"miner_key"`
 	InitialFunding  uint64                   `json:"initial_funding"`
 	CurrentBalance  uint64                   `json:"current_balance"`
-	Status          string                   `json:"status"`
+	Status          ChannelStatus            `json:"status"`
 	CreatedAt       time.Time                `json:"created_at"`
 	LastUpdated     time.Time                `json:"last_updated"`
 	PaymentHistory  []*PaymentUpdate         `json:"payment_history"`
 	MinerID         string                   `json:"miner_id"`
 	MinerAddress    string                   `json:"miner_address"`
+
+	OperatorKeyIndex uint32               `json:"operator_key_index"`
+	History          []*ChannelTransition `json:"history"`
+	TopUps           []*ChannelTopUp      `json:"top_ups"`
+	ScriptTree       *ChannelScriptTree   `json:"script_tree"`
+	TaprootAddress   string               `json:"taproot_address"`
+	FundingOutpoint  string               `json:"funding_outpoint"`
+	VTXO             *VTXO                `json:"vtxo"`
+	VTXOHistory      []*VTXO              `json:"vtxo_history"`
+	Settlement       *Settlement          `json:"settlement,omitempty"`
+	Exit             *ChannelExit         `json:"exit,omitempty"`
+	Disputes         []*DisputeEvent      `json:"disputes"`
+}
+
+// ChannelStatus is a state in a channel's lifecycle
+type ChannelStatus string
+
+// Channel lifecycle states
+const (
+	ChannelPendingFunding     ChannelStatus = "pending_funding"
+	ChannelOpen               ChannelStatus = "open"
+	ChannelClosingCooperative ChannelStatus = "closing_cooperative"
+	ChannelClosingUnilateral  ChannelStatus = "closing_unilateral"
+	ChannelDisputed           ChannelStatus = "disputed"
+	ChannelClosed             ChannelStatus = "closed"
+	ChannelExpired            ChannelStatus = "expired"
+)
+
+// ChannelTransition records a change of a channel's lifecycle state
+type ChannelTransition struct {
+	From      ChannelStatus `json:"from"`
+	To        ChannelStatus `json:"to"`
+	Reason    string        `json:"reason"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// ChannelScriptTree represents the taproot script tree locking a channel's funds
+type ChannelScriptTree struct {
+	InternalKey string     `json:"internal_key"`
+	MerkleRoot  string     `json:"merkle_root"`
+	OutputKey   string     `json:"output_key"`
+	Leaves      []*TapLeaf `json:"leaves"`
+}
+
+// TapLeaf represents a tapscript spending path of a channel
+type TapLeaf struct {
+	Name         string   `json:"name"`
+	Script       string   `json:"script"`
+	LeafHash     string   `json:"leaf_hash"`
+	ControlBlock string   `json:"control_block"`
+	Locktime     uint32   `json:"locktime,omitempty"`
+	PubKeys      []string `json:"pub_keys"`
+}
+
+// VTXO is the virtual output a channel's funds live in. It belongs to the batch
+// that created it and must be refreshed into a new batch before ExpiryHeight, when
+// the Ark server can sweep it.
+type VTXO struct {
+	Outpoint      string    `json:"outpoint"`
+	Amount        uint64    `json:"amount"`
+	BatchID       string    `json:"batch_id"`
+	CreatedHeight uint64    `json:"created_height"`
+	ExpiryHeight  uint64    `json:"expiry_height"`
+	RefreshFee    uint64    `json:"refresh_fee,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ChannelTopUp represents additional operator funding added to a channel
+type ChannelTopUp struct {
+	Amount    uint64    `json:"amount"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // PaymentUpdate represents a payment update in a Virtual Channel
@@ -57,8 +157,254 @@ type PaymentUpdate struct {
 	Timestamp   time.Time `json:"timestamp"`
 	Status      string    `json:"status"`
 	SequenceNum uint64    `json:"sequence_num"`
+
+	MinerBalance uint64 `json:"miner_balance"`
+	State        string `json:"state"`
+	Signature    string `json:"signature"`
+	OperatorKey  string `json:"operator_key"`
+}
+
+// ChannelExit represents a unilateral close waiting out its CSV delay. State is the
+// signed state the exit settles on; it is nil if the channel never paid the miner.
+type ChannelExit struct {
+	Party           string           `json:"party"`
+	Leaf            string           `json:"leaf"`
+	State           *PaymentUpdate   `json:"state,omitempty"`
+	BroadcastHeight uint64           `json:"broadcast_height"`
+	MaturityHeight  uint64           `json:"maturity_height"`
+	Challenges      []*ExitChallenge `json:"challenges"`
+	Justice         *Settlement      `json:"justice,omitempty"`
+}
+
+// ExitChallenge represents a later signed state presented during an exit window
+type ExitChallenge struct {
+	Party        string    `json:"party"`
+	SequenceNum  uint64    `json:"sequence_num"`
+	MinerBalance uint64    `json:"miner_balance"`
+	Height       uint64    `json:"height"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// DisputeEvent records a stale state found in a channel's exit. The claim is
+// fraud when it would have paid the claimant more than the latest signed state,
+// and the claimant forfeits that amount as Penalty.
+type DisputeEvent struct {
+	ID                  string      `json:"id"`
+	ChannelID           string      `json:"channel_id"`
+	MinerID             string      `json:"miner_id"`
+	ClaimedBy           string      `json:"claimed_by"`
+	ReportedBy          string      `json:"reported_by"`
+	ClaimedSequence     uint64      `json:"claimed_sequence"`
+	ClaimedMinerBalance uint64      `json:"claimed_miner_balance"`
+	LatestSequence      uint64      `json:"latest_sequence"`
+	LatestMinerBalance  uint64      `json:"latest_miner_balance"`
+	Fraud               bool        `json:"fraud"`
+	Penalty             uint64      `json:"penalty"`
+	Justice             *Settlement `json:"justice,omitempty"`
+	Height              uint64      `json:"height"`
+	Timestamp           time.Time   `json:"timestamp"`
+}
+
+// Settlement represents the final split of a channel's funds when it closes
+type Settlement struct {
+	ID             string                 `json:"id"`
+	ChannelID      string                 `json:"channel_id"`
+	MinerID        string                 `json:"miner_id"`
+	Type           string                 `json:"type"`
+	ChannelAmount  uint64                 `json:"channel_amount"`
+	MinerAmount    uint64                 `json:"miner_amount"`
+	OperatorChange uint64                 `json:"operator_change"`
+	Fee            uint64                 `json:"fee"`
+	FinalState     *PaymentUpdate         `json:"final_state,omitempty"`
+	Transaction    *SettlementTx          `json:"transaction"`
+	Signatures     []*SettlementSignature `json:"signatures"`
+	RoundID        string                 `json:"round_id,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// SettlementTx is the template of the transaction spending a channel output
+type SettlementTx struct {
+	Input   *SettlementInput    `json:"input"`
+	Outputs []*SettlementOutput `json:"outputs"`
+	Digest  string              `json:"digest"`
+}
+
+// SettlementInput is the channel output a settlement spends and the leaf it spends it through
+type SettlementInput struct {
+	Outpoint     string `json:"outpoint"`
+	Amount       uint64 `json:"amount"`
+	Leaf         string `json:"leaf"`
+	LeafHash     string `json:"leaf_hash"`
+	Script       string `json:"script"`
+	ControlBlock string `json:"control_block"`
+	Sequence     uint32 `json:"sequence"`
 }
 
+// SettlementOutput is a payment made by a settlement transaction
+type SettlementOutput struct {
+	Party   string `json:"party"`
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// SettlementSignature is one party's signature of a settlement
+type SettlementSignature struct {
+	Party     string `json:"party"`
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+}
+
+// Round is a simulated Ark round: one commitment transaction settling every intent
+// collected during a window. The outputs are committed to in a shared tree whose
+// root is CommitmentRoot; CommitmentTree lists its levels from the leaves up.
+type Round struct {
+	ID             string         `json:"id"`
+	Height         uint64         `json:"height"`
+	Intents        []*RoundIntent `json:"intents"`
+	Outputs        []*RoundOutput `json:"outputs"`
+	CommitmentRoot string         `json:"commitment_root"`
+	CommitmentTree [][]string     `json:"commitment_tree"`
+	ArkRoundID     string         `json:"ark_round_id"`
+	CommitmentTxID string         `json:"commitment_txid"`
+	VSize          uint64         `json:"vsize"`
+	FeeRate        uint64         `json:"fee_rate"`
+	Fee            uint64         `json:"fee"`
+	StandaloneFee  uint64         `json:"standalone_fee"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// RoundIntent is a request to settle in the next round, such as a channel's
+// cooperative close. Fee is the intent's share of its round's fee.
+type RoundIntent struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	ChannelID  string      `json:"channel_id"`
+	MinerID    string      `json:"miner_id"`
+	Inputs     []string    `json:"inputs"`
+	Status     string      `json:"status"`
+	RoundID    string      `json:"round_id,omitempty"`
+	Fee        uint64      `json:"fee"`
+	Settlement *Settlement `json:"settlement,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	SettledAt  time.Time   `json:"settled_at,omitempty"`
+}
+
+// RoundOutput is a payment a round makes to one participant of an intent. VTXO
+// is the output the Ark server issued for it.
+type RoundOutput struct {
+	IntentID string `json:"intent_id"`
+	Party    string `json:"party"`
+	Address  string `json:"address"`
+	Amount   uint64 `json:"amount"`
+	VTXO     *VTXO  `json:"vtxo,omitempty"`
+}
+
+// ArkInfo describes an Ark service provider
+type ArkInfo struct {
+	PubKey       string `json:"pubkey"`
+	VTXOLifetime uint64 `json:"vtxo_lifetime"`
+}
+
+// ArkIntent asks an Ark service provider to spend VTXOs into new outputs in its
+// next round
+type ArkIntent struct {
+	ID      string         `json:"id"`
+	Inputs  []string       `json:"inputs"`
+	Outputs []*RoundOutput `json:"outputs"`
+}
+
+// ArkRound is a round run by an Ark service provider. VTXOs lists the outputs it
+// issues, in the order its intents listed them.
+type ArkRound struct {
+	ID             string    `json:"id"`
+	CommitmentTxID string    `json:"commitment_txid"`
+	Height         uint64    `json:"height"`
+	Intents        []string  `json:"intents"`
+	Inputs         []string  `json:"inputs"`
+	VTXOs          []*VTXO   `json:"vtxos"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ArkForfeit gives up a VTXO spent in a round: if it is ever broadcast, the Ark
+// service provider can claim it
+type ArkForfeit struct {
+	Outpoint  string `json:"outpoint"`
+	PubKey    string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+// UTXO is an unspent on-chain output held by the pool operator
+type UTXO struct {
+	Outpoint string `json:"outpoint"`
+	Amount   uint64 `json:"amount"`
+	Address  string `json:"address"`
+	Height   uint64 `json:"height"`
+}
+
+// Boarding moves on-chain operator UTXOs into a VTXO held by the operator on the
+// Ark server
+type Boarding struct {
+	Inputs    []string  `json:"inputs"`
+	Amount    uint64    `json:"amount"`
+	VTXO      *VTXO     `json:"vtxo"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TreasuryStats represents the operator funds backing the pool's channels.
+// Available is boarded but not yet allocated, Allocated is held by open channels,
+// and Utilisation is the share of the two that is allocated.
+type TreasuryStats struct {
+	Address        string      `json:"address"`
+	OnchainBalance uint64      `json:"onchain_balance"`
+	OnchainUTXOs   int         `json:"onchain_utxos"`
+	Boarded        uint64      `json:"boarded"`
+	Available      uint64      `json:"available"`
+	Allocated      uint64      `json:"allocated"`
+	Returned       uint64      `json:"returned"`
+	Utilisation    float64     `json:"utilisation"`
+	Channels       int         `json:"channels"`
+	Boardings      []*Boarding `json:"boardings"`
+}
+
+// PendingRound represents the intents waiting for the next round
+type PendingRound struct {
+	Intents     []*RoundIntent `json:"intents"`
+	FeeRate     uint64         `json:"fee_rate"`
+	NextRoundAt time.Time      `json:"next_round_at,omitempty"`
+}
+
+// Withdrawal is a miner's off-boarding of channel earnings to its on-chain address
+// through an Ark round. Amount leaves the channel; the miner receives NetAmount
+// after paying Fee, its share of the round.
+type Withdrawal struct {
+	ID             string    `json:"id"`
+	MinerID        string    `json:"miner_id"`
+	ChannelID      string    `json:"channel_id"`
+	Address        string    `json:"address"`
+	Amount         uint64    `json:"amount"`
+	Fee            uint64    `json:"fee"`
+	NetAmount      uint64    `json:"net_amount"`
+	IntentID       string    `json:"intent_id"`
+	RoundID        string    `json:"round_id,omitempty"`
+	CommitmentTxID string    `json:"commitment_txid,omitempty"`
+	Height         uint64    `json:"height,omitempty"`
+	Confirmations  uint64    `json:"confirmations"`
+	SequenceNum    uint64    `json:"sequence_num,omitempty"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Withdrawal statuses
+const (
+	WithdrawalQueued      = "queued"
+	WithdrawalUnconfirmed = "unconfirmed"
+	WithdrawalConfirmed   = "confirmed"
+	WithdrawalFailed      = "failed"
+)
+
 // BlockReward represents a block reward distribution
 type BlockReward struct {
 	ID            string            `json:"id"`
@@ -66,6 +412,116 @@ type BlockReward struct {
 	TotalReward   uint64            `json:"total_reward"`
 	Distributions map[string]uint64 `json:"distributions"`
 	CreatedAt     time.Time         `json:"created_at"`
+
+	Subsidy         uint64 `json:"subsidy"`
+	TxFees          uint64 `json:"tx_fees"`
+	PayoutScheme    string `json:"payout_scheme"`
+	PaidOut         uint64 `json:"paid_out"`
+	FinderID        string `json:"finder_id,omitempty"`
+	OperatorFee     uint64 `json:"operator_fee"`
+	OperatorAddress string `json:"operator_address"`
+	Donation        uint64 `json:"donation"`
+	DonationAddress string `json:"donation_address,omitempty"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+}
+
+// BlockTemplate is a bitcoind block template to mine the next block on
+type BlockTemplate struct {
+	Version           int32                  `json:"version"`
+	PreviousBlockHash string                 `json:"previousblockhash"`
+	Height            uint64                 `json:"height"`
+	CoinbaseValue     uint64                 `json:"coinbasevalue"`
+	Bits              string                 `json:"bits"`
+	Target            string                 `json:"target"`
+	CurTime           int64                  `json:"curtime"`
+	Transactions      []*TemplateTransaction `json:"transactions"`
+}
+
+// TemplateTransaction is a transaction a block template includes
+type TemplateTransaction struct {
+	Data   string `json:"data"`
+	TxID   string `json:"txid"`
+	Hash   string `json:"hash"`
+	Fee    uint64 `json:"fee"`
+	Weight uint64 `json:"weight"`
+}
+
+// BlockHeader is a block header as bitcoind reports it
+type BlockHeader struct {
+	Hash              string `json:"hash"`
+	Confirmations     int64  `json:"confirmations"`
+	Height            uint64 `json:"height"`
+	Version           int32  `json:"version"`
+	MerkleRoot        string `json:"merkleroot"`
+	Time              int64  `json:"time"`
+	Nonce             uint32 `json:"nonce"`
+	Bits              string `json:"bits"`
+	PreviousBlockHash string `json:"previousblockhash,omitempty"`
+}
+
+// Block is a block with its transactions decoded, as bitcoind reports it with
+// verbosity 2. The coinbase transaction comes first.
+type Block struct {
+	BlockHeader
+	Tx []*Transaction `json:"tx"`
+}
+
+// Transaction is a decoded transaction
+type Transaction struct {
+	TxID string      `json:"txid"`
+	Vin  []*TxInput  `json:"vin"`
+	Vout []*TxOutput `json:"vout"`
+}
+
+// TxInput is a transaction input. Coinbase holds the script of a coinbase input.
+type TxInput struct {
+	Coinbase string `json:"coinbase,omitempty"`
+	TxID     string `json:"txid,omitempty"`
+	Vout     uint32 `json:"vout,omitempty"`
+}
+
+// TxOutput is a transaction output. Value is in BTC, as bitcoind reports it.
+type TxOutput struct {
+	Value        float64      `json:"value"`
+	N            uint32       `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+}
+
+// ScriptPubKey is an output script and the address it pays, if it has one
+type ScriptPubKey struct {
+	Hex     string `json:"hex"`
+	Address string `json:"address,omitempty"`
+}
+
+// ChainEvent represents something that happened on the pool's simulated chain: a
+// new block, or a channel exit broadcast at Height
+type ChainEvent struct {
+	Type      string       `json:"type"`
+	Height    uint64       `json:"height"`
+	ChannelID string       `json:"channel_id,omitempty"`
+	Exit      *ChannelExit `json:"exit,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// WatchtowerResponse represents a watchtower's answer to a stale channel exit
+type WatchtowerResponse struct {
+	ChannelID       string        `json:"channel_id"`
+	BreachSequence  uint64        `json:"breach_sequence"`
+	JusticeSequence uint64        `json:"justice_sequence"`
+	MaturityHeight  uint64        `json:"maturity_height"`
+	Height          uint64        `json:"height"`
+	Dispute         *DisputeEvent `json:"dispute,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	Timestamp       time.Time     `json:"timestamp"`
+}
+
+// WatchtowerStats represents what a watchtower holds and has done
+type WatchtowerStats struct {
+	Registrations int    `json:"registrations"`
+	Breaches      int    `json:"breaches"`
+	Pending       int    `json:"pending"`
+	Height        uint64 `json:"height"`
 }
 
 // MiningStats represents pool statistics
@@ -76,9 +532,23 @@ type MiningStats struct {
 	TotalEarned     uint64  `json:"total_earned"`
 	ActiveChannels  int     `json:"active_channels"`
 	LastBlockReward uint64  `json:"last_block_reward"`
+
+	CurrentSubsidy uint64  `json:"current_subsidy"`
+	TotalSubsidy   uint64  `json:"total_subsidy"`
+	TotalTxFees    uint64  `json:"total_tx_fees"`
+	PayoutScheme   string  `json:"payout_scheme"`
+	WindowShares   int     `json:"window_shares"`
+	WindowWork     float64 `json:"window_work"`
+	FeeBasisPoints uint32  `json:"fee_basis_points"`
+	OperatorFees   uint64  `json:"operator_fees"`
+	DonationsPaid  uint64  `json:"donations_paid"`
+	TotalPending   uint64  `json:"total_pending"`
 }
 
-// MinerStats represents individual miner statistics
+// MinerStats represents individual miner statistics. CurrentBalance is what the
+// pool has paid the miner through its channel, and ChannelBalance the miner's side
+// of the channel's balance sheet, which also reflects a pending exit or the
+// channel's settlement.
 type MinerStats struct {
 	MinerID         string  `json:"miner_id"`
 	Name            string  `json:"name"`
@@ -88,19 +558,39 @@ type MinerStats struct {
 	ChannelBalance  uint64  `json:"channel_balance"`
 	IsActive        bool    `json:"is_active"`
 	LastActivity    string  `json:"last_activity"`
+
+	PendingBalance uint64 `json:"pending_balance"`
 }
 
-// ChannelStats represents channel statistics
+// ChannelStats represents channel statistics. The balances are those of the
+// channel's ChannelBalance.
 type ChannelStats struct {
 	ChannelID       string `json:"channel_id"`
 	MinerID         string `json:"miner_id"`
 	MinerName       string `json:"miner_name"`
 	InitialFunding  uint64 `json:"initial_funding"`
 	CurrentBalance  uint64 `json:"current_balance"`
-	Status          string `json:"status"`
+	Status          ChannelStatus `json:"status"`
 	PaymentCount    int    `json:"payment_count"`
 	CreatedAt       string `json:"created_at"`
 	LastUpdated     string `json:"last_updated"`
+
+	OperatorBalance uint64 `json:"operator_balance"`
+	MinerBalance    uint64 `json:"miner_balance"`
+	InFlight        uint64 `json:"in_flight"`
+}
+
+// ChannelBalance is a channel's balance sheet. OperatorBalance, MinerBalance and
+// InFlight always add up to Capacity; InFlight is the amount a pending exit and
+// the latest signed state disagree on. SequenceNum is the latest state's.
+type ChannelBalance struct {
+	ChannelID       string        `json:"channel_id"`
+	Status          ChannelStatus `json:"status"`
+	Capacity        uint64        `json:"capacity"`
+	OperatorBalance uint64        `json:"operator_balance"`
+	MinerBalance    uint64        `json:"miner_balance"`
+	InFlight        uint64        `json:"in_flight"`
+	SequenceNum     uint64        `json:"sequence_num"`
 }
 
 // APIResponse represents a generic API response
@@ -118,21 +608,100 @@ type AddMinerRequest struct {
 	HashRate float64 `json:"hash_rate"`
 }
 
-// JoinPoolRequest represents a request to join the mining pool
+// JoinPoolRequest represents a request to join the mining pool. PubKey is the
+// miner's hex compressed secp256k1 channel key and Signature its hex BIP-340
+// signature over Challenge.
 type JoinPoolRequest struct {
 	MinerName string  `json:"miner_name"`
 	Address   string  `json:"address"`
 	HashRate  float64 `json:"hash_rate"`
+
+	PubKey    string `json:"pub_key"`
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+}
+
+// JoinChallenge represents a single-use challenge a miner signs to join the pool
+type JoinChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SimulateMinerRequest represents a request to start a simulated miner that
+// generates its own channel key
+type SimulateMinerRequest struct {
+	MinerName string  `json:"miner_name"`
+	Address   string  `json:"address"`
+	HashRate  float64 `json:"hash_rate"`
+}
+
+// SetMinerFeeRequest represents a request to override a miner's pool fee.
+// A null fee removes the override so the pool-wide fee applies again.
+type SetMinerFeeRequest struct {
+	FeeBasisPoints *uint32 `json:"fee_basis_points"`
+}
+
+// PayoutSettings represents a miner's deferred balance and payout configuration
+type PayoutSettings struct {
+	MinerID            string    `json:"miner_id"`
+	PendingBalance     uint64    `json:"pending_balance"`
+	PayoutThreshold    uint64    `json:"payout_threshold"`
+	EffectiveThreshold uint64    `json:"effective_threshold"`
+	LastPayoutAt       time.Time `json:"last_payout_at"`
+	NextScheduledAt    time.Time `json:"next_scheduled_at"`
+}
+
+// SetPayoutThresholdRequest represents a request to change a miner's payout threshold.
+// A zero threshold falls back to the pool default.
+type SetPayoutThresholdRequest struct {
+	PayoutThreshold uint64 `json:"payout_threshold"`
+}
+
+// WithdrawRequest represents a miner's request to off-board channel earnings to its
+// on-chain address. A zero amount withdraws the miner's whole side of the channel.
+type WithdrawRequest struct {
+	Amount uint64 `json:"amount"`
+}
+
+// SubmitBlockRequest carries a block the pool's miners found, serialized and hex
+// encoded, to submit to the node
+type SubmitBlockRequest struct {
+	Hex string `json:"hex" binding:"required"`
 }
 
-// ProcessBlockRequest represents a request to process a block reward
+// UnilateralExitRequest represents a request to close a channel unilaterally. State
+// is the signed state to exit with; without one the party's latest state is used.
+type UnilateralExitRequest struct {
+	Party string         `json:"party"`
+	State *PaymentUpdate `json:"state,omitempty"`
+}
+
+// ExitChallengeRequest represents a later signed state presented against an exit
+type ExitChallengeRequest struct {
+	Party string         `json:"party"`
+	State *PaymentUpdate `json:"state"`
+}
+
+// WatchtowerRegistration hands a watchtower the state that revokes an older one.
+// Hint identifies the older state and Blob is the newer state encrypted under a key
+// derived from the older one, so the tower learns nothing until it is broadcast.
+type WatchtowerRegistration struct {
+	Hint string `json:"hint"`
+	Blob string `json:"blob"`
+}
+
+// ProcessBlockRequest represents a request to process a block reward. Replay marks
+// a block at or below the current tip, such as one reorganised back in, as meant.
 type ProcessBlockRequest struct {
 	BlockHeight uint64 `json:"block_height,omitempty"`
 	Reward      uint64 `json:"reward,omitempty"`
+
+	TxFees uint64 `json:"tx_fees,omitempty"`
+	Replay bool   `json:"replay,omitempty"`
 }
 
 // WebSocketMessage represents a WebSocket message
 type WebSocketMessage struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
-} 
\ No newline at end of file
+}
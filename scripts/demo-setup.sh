@@ -97,7 +97,7 @@ start_app() {
     print_status "Starting mining pool demo..."
     
     # Run in background
-    ./bin/pool-operator --block-interval 15s &
+    ./bin/pool-operator --block-interval 15s --insecure-keystore &
     SERVER_PID=$!
     
     # Save PID for cleanup
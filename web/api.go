@@ -1,10 +1,15 @@
 package web
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/chdwlch/spark-pool/internal/chain"
+	"github.com/chdwlch/spark-pool/internal/channel"
 	"github.com/chdwlch/spark-pool/internal/miner"
 	"github.com/chdwlch/spark-pool/internal/pool"
+	"github.com/chdwlch/spark-pool/internal/round"
+	"github.com/chdwlch/spark-pool/internal/treasury"
 	"github.com/chdwlch/spark-pool/pkg/types"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +21,7 @@ import (
 type API struct {
 	poolManager    *pool.Manager
 	minerManager   *miner.Manager
+	node           *chain.Follower
 	upgrader       websocket.Upgrader
 	clients        map[*websocket.Conn]bool
 	broadcast      chan types.WebSocketMessage
@@ -24,7 +30,7 @@ type API struct {
 
 // NewAPI creates a new API server
 func NewAPI(poolManager *pool.Manager, minerManager *miner.Manager) *API {
-	return &API{
+	api := &API{
 		poolManager:  poolManager,
 		minerManager: minerManager,
 		upgrader: websocket.Upgrader{
@@ -36,6 +42,46 @@ func NewAPI(poolManager *pool.Manager, minerManager *miner.Manager) *API {
 		broadcast: make(chan types.WebSocketMessage, 100),
 		logger:    logrus.New(),
 	}
+
+	// Broadcast disputes however they were raised
+	poolManager.OnDispute(func(event *types.DisputeEvent) {
+		api.broadcast <- types.WebSocketMessage{
+			Type:    "dispute",
+			Payload: event,
+		}
+	})
+
+	// Broadcast each round, and the channels it closed
+	poolManager.OnRound(func(sealed *types.Round) {
+		api.broadcast <- types.WebSocketMessage{
+			Type:    "round_sealed",
+			Payload: sealed,
+		}
+		for _, intent := range sealed.Intents {
+			if intent.Kind == round.IntentClose && intent.Settlement != nil {
+				api.broadcast <- types.WebSocketMessage{
+					Type:    "channel_closed",
+					Payload: intent.Settlement,
+				}
+			}
+		}
+	})
+
+	// Stream the simulated chain so remote watchtowers can follow it
+	poolManager.OnChainEvent(func(event *types.ChainEvent) {
+		api.broadcast <- types.WebSocketMessage{
+			Type:    "chain_event",
+			Payload: event,
+		}
+	})
+
+	return api
+}
+
+// SetNode serves block templates from, and submits found blocks to, the node the
+// pool follows. Without one the chain routes answer 503.
+func (api *API) SetNode(node *chain.Follower) {
+	api.node = node
 }
 
 // SetupRoutes sets up the API routes
@@ -48,17 +94,45 @@ func (api *API) SetupRoutes(r *gin.Engine) {
 		apiGroup.GET("/pool/miners", api.GetAllMiners)
 		apiGroup.GET("/pool/channels", api.GetAllChannels)
 		apiGroup.POST("/pool/block-reward", api.ProcessBlockReward)
+		apiGroup.GET("/pool/block-rewards", api.GetBlockRewards)
+		apiGroup.GET("/pool/treasury", api.GetTreasury)
+		apiGroup.GET("/disputes", api.GetDisputes)
+		apiGroup.GET("/rounds", api.GetRounds)
+		apiGroup.GET("/rounds/pending", api.GetPendingRound)
+		apiGroup.GET("/rounds/:id", api.GetRound)
+		apiGroup.GET("/intents/:id", api.GetRoundIntent)
+
+		// Chain routes
+		apiGroup.GET("/chain/template", api.GetBlockTemplate)
+		apiGroup.POST("/chain/blocks", api.SubmitBlock)
 
 		// Miner routes
+		apiGroup.POST("/miners/challenge", api.NewJoinChallenge)
 		apiGroup.POST("/miners", api.AddMiner)
+		apiGroup.POST("/simulators", api.SimulateMiner)
 		apiGroup.GET("/miners/:id", api.GetMiner)
 		apiGroup.PUT("/miners/:id/start", api.StartMiner)
 		apiGroup.PUT("/miners/:id/stop", api.StopMiner)
 		apiGroup.GET("/miners/:id/stats", api.GetMinerStats)
+		apiGroup.GET("/miners/:id/balance", api.GetMinerBalance)
+		apiGroup.PUT("/miners/:id/fee", api.SetMinerFee)
+		apiGroup.GET("/miners/:id/payout", api.GetPayoutSettings)
+		apiGroup.PUT("/miners/:id/payout", api.SetPayoutThreshold)
+		apiGroup.POST("/miners/:id/withdraw", api.Withdraw)
+		apiGroup.GET("/miners/:id/withdrawals", api.GetWithdrawals)
+		apiGroup.GET("/withdrawals/:id", api.GetWithdrawal)
 
 		// Channel routes
 		apiGroup.GET("/channels/:id", api.GetChannel)
+		apiGroup.GET("/channels/:id/balance", api.GetChannelBalance)
+		apiGroup.GET("/channels/:id/stats", api.GetChannelStats)
+		apiGroup.GET("/channels/:id/vtxos", api.GetChannelVTXOs)
 		apiGroup.POST("/channels/:id/close", api.CloseChannel)
+		apiGroup.GET("/channels/:id/settlement", api.GetChannelSettlement)
+		apiGroup.POST("/channels/:id/exit", api.StartUnilateralExit)
+		apiGroup.POST("/channels/:id/exit/challenge", api.ChallengeExit)
+		apiGroup.POST("/channels/:id/exit/finalize", api.FinalizeUnilateralExit)
+		apiGroup.GET("/channels/:id/disputes", api.GetChannelDisputes)
 	}
 
 	// WebSocket route
@@ -82,6 +156,23 @@ func (api *API) GetPoolStats(c *gin.Context) {
 	})
 }
 
+// GetTreasury returns the treasury's balances and utilisation
+func (api *API) GetTreasury(c *gin.Context) {
+	stats, err := api.poolManager.GetTreasury(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
 // GetAllMiners returns all miners
 func (api *API) GetAllMiners(c *gin.Context) {
 	miners := api.poolManager.GetAllMiners()
@@ -102,9 +193,25 @@ func (api *API) GetAllChannels(c *gin.Context) {
 
 // ProcessBlockReward processes a block reward
 func (api *API) ProcessBlockReward(c *gin.Context) {
-	blockReward, err := api.poolManager.ProcessBlockReward(c.Request.Context())
+	// The body is optional; without one the next block at the default reward is processed
+	var req types.ProcessBlockRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	blockReward, err := api.poolManager.ProcessBlockReward(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+		status := http.StatusInternalServerError
+		if errors.Is(err, pool.ErrBlockAlreadyProcessed) || errors.Is(err, pool.ErrStaleBlockHeight) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, types.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -123,7 +230,96 @@ func (api *API) ProcessBlockReward(c *gin.Context) {
 	})
 }
 
-// AddMiner adds a new miner
+// GetBlockRewards returns the history of processed block rewards
+func (api *API) GetBlockRewards(c *gin.Context) {
+	blockRewards := api.poolManager.GetBlockRewards()
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    blockRewards,
+	})
+}
+
+// GetBlockTemplate returns a template from the node for the pool's next block
+func (api *API) GetBlockTemplate(c *gin.Context) {
+	if api.node == nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   "pool is not following a node",
+		})
+		return
+	}
+
+	template, err := api.node.GetBlockTemplate(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// SubmitBlock submits a block the pool found to the node. The block is paid out
+// once the node has it on its best chain and the pool follows it there.
+func (api *API) SubmitBlock(c *gin.Context) {
+	if api.node == nil {
+		c.JSON(http.StatusServiceUnavailable, types.APIResponse{
+			Success: false,
+			Error:   "pool is not following a node",
+		})
+		return
+	}
+
+	var req types.SubmitBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := api.node.SubmitBlock(c.Request.Context(), req.Hex); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, chain.ErrBlockRejected) || errors.Is(err, chain.ErrInvalidBlock) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "block submitted",
+	})
+}
+
+// NewJoinChallenge issues a challenge a miner signs with its channel key to join
+func (api *API) NewJoinChallenge(c *gin.Context) {
+	challenge, err := api.poolManager.NewJoinChallenge()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    challenge,
+	})
+}
+
+// AddMiner adds a new miner that proves possession of its own channel key
 func (api *API) AddMiner(c *gin.Context) {
 	var req types.JoinPoolRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -134,15 +330,55 @@ func (api *API) AddMiner(c *gin.Context) {
 		return
 	}
 
-	simulator, err := api.minerManager.AddSimulator(req.MinerName, req.Address, req.HashRate)
+	simulator, err := api.minerManager.AddSimulator(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+		c.JSON(joinErrorStatus(err), types.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
 
+	api.minerAdded(c, simulator)
+}
+
+// SimulateMiner adds a simulated miner that generates its own channel key
+func (api *API) SimulateMiner(c *gin.Context) {
+	var req types.SimulateMinerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	simulator, err := api.minerManager.SimulateMiner(req.MinerName, req.Address, req.HashRate)
+	if err != nil {
+		c.JSON(joinErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	api.minerAdded(c, simulator)
+}
+
+// joinErrorStatus maps an error joining the pool to an HTTP status
+func joinErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pool.ErrInvalidJoinProof):
+		return http.StatusUnauthorized
+	case errors.Is(err, treasury.ErrInsufficientFunds):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// minerAdded announces a new miner and returns its simulator
+func (api *API) minerAdded(c *gin.Context, simulator *miner.Simulator) {
 	// Broadcast new miner to WebSocket clients
 	api.broadcast <- types.WebSocketMessage{
 		Type: "miner_added",
@@ -260,6 +496,173 @@ func (api *API) GetMinerStats(c *gin.Context) {
 	})
 }
 
+// GetMinerBalance returns a miner's earnings and its side of the channel
+func (api *API) GetMinerBalance(c *gin.Context) {
+	stats, err := api.poolManager.GetMinerStats(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// SetMinerFee overrides the operator fee charged to a miner
+func (api *API) SetMinerFee(c *gin.Context) {
+	minerID := c.Param("id")
+
+	var req types.SetMinerFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	miner, err := api.poolManager.SetMinerFee(minerID, req.FeeBasisPoints)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    miner,
+	})
+}
+
+// GetPayoutSettings returns a miner's pending balance and payout threshold
+func (api *API) GetPayoutSettings(c *gin.Context) {
+	minerID := c.Param("id")
+
+	settings, err := api.poolManager.GetPayoutSettings(minerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// SetPayoutThreshold changes the pending balance at which a miner gets paid
+func (api *API) SetPayoutThreshold(c *gin.Context) {
+	minerID := c.Param("id")
+
+	var req types.SetPayoutThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	settings, err := api.poolManager.SetPayoutThreshold(minerID, req.PayoutThreshold)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// Withdraw off-boards a miner's channel earnings to its on-chain address in the
+// next round
+func (api *API) Withdraw(c *gin.Context) {
+	minerID := c.Param("id")
+
+	var req types.WithdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if _, exists := api.poolManager.GetMiner(minerID); !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "miner not found",
+		})
+		return
+	}
+
+	withdrawal, err := api.minerManager.Withdraw(c.Request.Context(), minerID, req.Amount)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, channel.ErrChannelNotOpen) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    withdrawal,
+	})
+}
+
+// GetWithdrawals returns a miner's withdrawals
+func (api *API) GetWithdrawals(c *gin.Context) {
+	minerID := c.Param("id")
+	if _, exists := api.poolManager.GetMiner(minerID); !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "miner not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    api.poolManager.GetWithdrawals(minerID),
+	})
+}
+
+// GetWithdrawal returns a withdrawal by ID
+func (api *API) GetWithdrawal(c *gin.Context) {
+	withdrawal, exists := api.poolManager.GetWithdrawal(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "withdrawal not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    withdrawal,
+	})
+}
+
 // GetChannel returns a channel by ID
 func (api *API) GetChannel(c *gin.Context) {
 	channelID := c.Param("id")
@@ -279,7 +682,68 @@ func (api *API) GetChannel(c *gin.Context) {
 	})
 }
 
-// CloseChannel closes a channel
+// GetChannelBalance returns the balance sheet of a channel
+func (api *API) GetChannelBalance(c *gin.Context) {
+	balance, err := api.poolManager.GetChannelBalance(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    balance,
+	})
+}
+
+// GetChannelVTXOs returns the VTXOs the Ark server holds at a channel's address
+func (api *API) GetChannelVTXOs(c *gin.Context) {
+	channelID := c.Param("id")
+	if _, exists := api.poolManager.GetChannel(channelID); !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "channel not found",
+		})
+		return
+	}
+
+	vtxos, err := api.poolManager.GetChannelVTXOs(c.Request.Context(), channelID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    vtxos,
+	})
+}
+
+// GetChannelStats returns a channel's statistics
+func (api *API) GetChannelStats(c *gin.Context) {
+	stats, err := api.poolManager.GetChannelStats(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// CloseChannel cooperatively closes a channel and returns its settlement, or the
+// queued intent when the settlement waits for the next round
 func (api *API) CloseChannel(c *gin.Context) {
 	channelID := c.Param("id")
 	
@@ -301,8 +765,121 @@ func (api *API) CloseChannel(c *gin.Context) {
 		return
 	}
 
-	if err := api.poolManager.CloseMinerChannel(minerID); err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
+	intent, err := api.poolManager.CloseMinerChannel(c.Request.Context(), minerID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, channel.ErrIllegalTransition) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Without a round interval the close settled in its own round; otherwise the
+	// intent is queued and the settlement follows once its round is sealed
+	if intent.Status != round.IntentSettled {
+		c.JSON(http.StatusAccepted, types.APIResponse{
+			Success: true,
+			Data:    intent,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    intent.Settlement,
+	})
+}
+
+// GetChannelSettlement returns the verified settlement of a closed channel
+func (api *API) GetChannelSettlement(c *gin.Context) {
+	settlement, err := api.poolManager.GetChannelSettlement(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    settlement,
+	})
+}
+
+// StartUnilateralExit closes a channel unilaterally for the operator or the miner.
+// A simulated miner exits with the latest state it has verified.
+func (api *API) StartUnilateralExit(c *gin.Context) {
+	channelID := c.Param("id")
+
+	var req types.UnilateralExitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.Party == channel.PartyMiner && req.State == nil {
+		if ch, exists := api.poolManager.GetChannel(channelID); exists {
+			if simulator, exists := api.minerManager.GetSimulator(ch.MinerID); exists {
+				req.State = simulator.LatestState()
+			}
+		}
+	}
+
+	ch, err := api.poolManager.StartUnilateralExit(channelID, req.Party, req.State)
+	if err != nil {
+		c.JSON(exitErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    ch,
+	})
+}
+
+// ChallengeExit presents a later signed state against a channel's pending exit and
+// returns the dispute it raises
+func (api *API) ChallengeExit(c *gin.Context) {
+	var req types.ExitChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	event, err := api.poolManager.ChallengeExit(c.Param("id"), req.Party, req.State)
+	if err != nil {
+		c.JSON(exitErrorStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    event,
+	})
+}
+
+// FinalizeUnilateralExit settles a channel's exit once its timelock has expired
+func (api *API) FinalizeUnilateralExit(c *gin.Context) {
+	settlement, err := api.poolManager.FinalizeUnilateralExit(c.Param("id"))
+	if err != nil {
+		c.JSON(exitErrorStatus(err), types.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -311,16 +888,101 @@ func (api *API) CloseChannel(c *gin.Context) {
 
 	// Broadcast channel closed
 	api.broadcast <- types.WebSocketMessage{
-		Type: "channel_closed",
-		Payload: map[string]string{"channel_id": channelID, "miner_id": minerID},
+		Type:    "channel_closed",
+		Payload: settlement,
 	}
 
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
-		Data:    map[string]string{"status": "closed"},
+		Data:    settlement,
 	})
 }
 
+// GetDisputes returns every dispute raised in the pool
+func (api *API) GetDisputes(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    api.poolManager.GetDisputes(),
+	})
+}
+
+// GetChannelDisputes returns the disputes raised against a channel
+func (api *API) GetChannelDisputes(c *gin.Context) {
+	disputes, exists := api.poolManager.GetChannelDisputes(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "channel not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    disputes,
+	})
+}
+
+// GetRounds returns every sealed round
+func (api *API) GetRounds(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    api.poolManager.GetRounds(),
+	})
+}
+
+// GetPendingRound returns the intents waiting for the next round
+func (api *API) GetPendingRound(c *gin.Context) {
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    api.poolManager.GetPendingRound(),
+	})
+}
+
+// GetRound returns a sealed round with its commitment tree
+func (api *API) GetRound(c *gin.Context) {
+	sealed, exists := api.poolManager.GetRound(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "round not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    sealed,
+	})
+}
+
+// GetRoundIntent returns an intent and whether it settled
+func (api *API) GetRoundIntent(c *gin.Context) {
+	intent, exists := api.poolManager.GetRoundIntent(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "intent not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    intent,
+	})
+}
+
+// exitErrorStatus maps a unilateral exit error to an HTTP status
+func exitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, channel.ErrIllegalTransition), errors.Is(err, channel.ErrTimelockActive):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 // HandleWebSocket handles WebSocket connections
 func (api *API) HandleWebSocket(c *gin.Context) {
 	conn, err := api.upgrader.Upgrade(c.Writer, c.Request, nil)